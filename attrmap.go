@@ -16,6 +16,7 @@ package svgdata
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/pkg/errors"
 )
@@ -58,3 +59,87 @@ func (am AttrMap) ExtractValueNoDefault(k string) (float64, error) {
 	}
 	return 0, nil
 }
+
+// extractRadii pulls the rx and ry attributes out of the AttrMap.  A radius
+// that is missing, auto or negative takes the value of the other one, and
+// both are zero if neither is set.
+func (am AttrMap) extractRadii() (float64, float64, error) {
+	var r [2]float64
+	var ok [2]bool
+	for i, k := range []string{"rx", "ry"} {
+		v, has := am[k]
+		delete(am, k)
+		if !has || v == "auto" {
+			continue
+		}
+		f, err := parseValue(v)
+		if err != nil {
+			return 0, 0, errors.WithStack(err)
+		}
+		r[i], ok[i] = f, f >= 0
+	}
+	switch {
+	case !ok[0] && !ok[1]:
+		return 0, 0, nil
+	case !ok[0]:
+		return r[1], r[1], nil
+	case !ok[1]:
+		return r[0], r[0], nil
+	}
+	return r[0], r[1], nil
+}
+
+// GetStyle returns the value of a presentation property for the node.  A
+// declaration in the style attribute takes precedence over an attribute of the
+// same name.
+func (am AttrMap) GetStyle(k string) (string, bool) {
+	if v, ok := parseStyle(am["style"])[k]; ok {
+		return v, true
+	}
+	v, ok := am[k]
+	return v, ok
+}
+
+// SetStyle sets a presentation property as an attribute, removing any
+// declaration for it in the style attribute so the new value is used.
+func (am AttrMap) SetStyle(k, v string) {
+	am.DeleteStyle(k)
+	am[k] = v
+}
+
+// DeleteStyle removes a presentation property from both the attributes and the
+// style attribute.
+func (am AttrMap) DeleteStyle(k string) {
+	delete(am, k)
+	s, ok := am["style"]
+	if !ok {
+		return
+	}
+	var decls []string
+	for _, d := range strings.Split(s, ";") {
+		if kv := strings.SplitN(d, ":", 2); len(kv) == 2 && strings.TrimSpace(kv[0]) == k {
+			continue
+		}
+		if strings.TrimSpace(d) != "" {
+			decls = append(decls, strings.TrimSpace(d))
+		}
+	}
+	if len(decls) == 0 {
+		delete(am, "style")
+		return
+	}
+	am["style"] = strings.Join(decls, ";")
+}
+
+// parseStyle splits a style attribute into its declarations.
+func parseStyle(s string) map[string]string {
+	r := map[string]string{}
+	for _, d := range strings.Split(s, ";") {
+		kv := strings.SplitN(d, ":", 2)
+		if len(kv) != 2 {
+			continue
+		}
+		r[strings.TrimSpace(kv[0])] = strings.TrimSpace(kv[1])
+	}
+	return r
+}
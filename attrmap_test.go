@@ -0,0 +1,42 @@
+// Copyright 2018 Joe Beda
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package svgdata
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAttrMapStyle(t *testing.T) {
+	assert := assert.New(t)
+
+	am := AttrMap{"fill": "red", "stroke": "blue", "style": "fill: green; stroke-width:2"}
+
+	v, ok := am.GetStyle("fill")
+	assert.True(ok)
+	assert.Equal("green", v)
+	v, ok = am.GetStyle("stroke")
+	assert.True(ok)
+	assert.Equal("blue", v)
+	_, ok = am.GetStyle("opacity")
+	assert.False(ok)
+
+	am.SetStyle("fill", "black")
+	assert.Equal(AttrMap{"fill": "black", "stroke": "blue", "style": "stroke-width:2"}, am)
+
+	am.DeleteStyle("stroke-width")
+	assert.Equal(AttrMap{"fill": "black", "stroke": "blue"}, am)
+}
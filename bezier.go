@@ -0,0 +1,386 @@
+// Copyright 2018 Joe Beda
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package svgdata
+
+import (
+	"math"
+	"sort"
+
+	"github.com/jbeda/geom"
+)
+
+// QuadBezier is a quadratic Bézier curve from P0 to P2 with control point P1.
+type QuadBezier struct {
+	P0, P1, P2 geom.Coord
+}
+
+// CubicBezier is a cubic Bézier curve from P0 to P3 with control points P1
+// and P2.
+type CubicBezier struct {
+	P0, P1, P2, P3 geom.Coord
+}
+
+// QuadBezierFromCommand returns the curve drawn by a Q or T command.  prev is
+// the command before c and is needed to find the implied control point of a
+// T.  Both commands must come from a parsed path so that their positions are
+// known.  false is returned if c does not draw a quadratic curve, or if c is
+// a T following another T, whose control point is implied by the commands
+// before prev.  SubPath.QuadBezier handles every case.
+func QuadBezierFromCommand(prev, c PathCommand) (QuadBezier, bool) {
+	if !isQuadCommand(c) || (isSmoothQuadCommand(c) && isSmoothQuadCommand(prev)) {
+		return QuadBezier{}, false
+	}
+	return commandSegment([]PathCommand{prev, c}).quad(), true
+}
+
+// CubicBezierFromCommand returns the curve drawn by a C, S, Q or T command.
+// Quadratic curves are elevated to cubics.  prev is the command before c as
+// for QuadBezierFromCommand.  false is returned if c does not draw a curve or
+// if c is a T following another T.
+func CubicBezierFromCommand(prev, c PathCommand) (CubicBezier, bool) {
+	if isQuadCommand(c) {
+		q, ok := QuadBezierFromCommand(prev, c)
+		return q.Elevate(), ok
+	}
+	switch c.Command {
+	case 'C', 'c', 'S', 's':
+		return commandSegment([]PathCommand{prev, c}).cubic(), true
+	}
+	return CubicBezier{}, false
+}
+
+// QuadBezier returns the curve drawn by the command at index i, which must be
+// a Q or T.  Implied control points are found from the commands before it.
+func (sp SubPath) QuadBezier(i int) (QuadBezier, bool) {
+	if i < 0 || i >= len(sp.Commands) || !isQuadCommand(sp.Commands[i]) {
+		return QuadBezier{}, false
+	}
+	return commandSegment(sp.Commands[:i+1]).quad(), true
+}
+
+// CubicBezier returns the curve drawn by the command at index i, which must
+// be a C, S, Q or T.  Quadratic curves are elevated to cubics.
+func (sp SubPath) CubicBezier(i int) (CubicBezier, bool) {
+	if i < 0 || i >= len(sp.Commands) {
+		return CubicBezier{}, false
+	}
+	switch sp.Commands[i].Command {
+	case 'C', 'c', 'S', 's':
+		return commandSegment(sp.Commands[:i+1]).cubic(), true
+	case 'Q', 'q', 'T', 't':
+		return commandSegment(sp.Commands[:i+1]).quad().Elevate(), true
+	}
+	return CubicBezier{}, false
+}
+
+func isQuadCommand(c PathCommand) bool {
+	return c.Command == 'Q' || c.Command == 'q' || isSmoothQuadCommand(c)
+}
+
+func isSmoothQuadCommand(c PathCommand) bool {
+	return c.Command == 'T' || c.Command == 't'
+}
+
+// commandSegment returns the segment drawn by the last of cmds, taking any
+// implied control point from the commands before it.
+func commandSegment(cmds []PathCommand) segment {
+	segs := subPathSegments(SubPath{Commands: cmds})
+	return segs[len(segs)-1]
+}
+
+// Point returns the position on the curve at t.
+func (q QuadBezier) Point(t float64) geom.Coord {
+	mt := 1 - t
+	return geom.Coord{
+		X: mt*mt*q.P0.X + 2*mt*t*q.P1.X + t*t*q.P2.X,
+		Y: mt*mt*q.P0.Y + 2*mt*t*q.P1.Y + t*t*q.P2.Y,
+	}
+}
+
+// Derivative returns the first derivative of the curve at t.
+func (q QuadBezier) Derivative(t float64) geom.Coord {
+	a := coordScale(coordSub(q.P1, q.P0), 2*(1-t))
+	b := coordScale(coordSub(q.P2, q.P1), 2*t)
+	return coordAdd(a, b)
+}
+
+// Split divides the curve at t using de Casteljau's algorithm.
+func (q QuadBezier) Split(t float64) (QuadBezier, QuadBezier) {
+	p01, p12 := coordLerp(q.P0, q.P1, t), coordLerp(q.P1, q.P2, t)
+	m := coordLerp(p01, p12, t)
+	return QuadBezier{q.P0, p01, m}, QuadBezier{m, p12, q.P2}
+}
+
+// SubCurve returns the part of the curve between t0 and t1.  The part runs
+// backwards if t0 is greater than t1 and is a single point if they are equal.
+func (q QuadBezier) SubCurve(t0, t1 float64) QuadBezier {
+	if t0 > t1 {
+		r := q.SubCurve(t1, t0)
+		return QuadBezier{r.P2, r.P1, r.P0}
+	}
+	if t0 == t1 {
+		p := q.Point(t0)
+		return QuadBezier{p, p, p}
+	}
+	if t1 < 1 {
+		q, _ = q.Split(t1)
+	}
+	if t0 > 0 {
+		_, q = q.Split(t0 / t1)
+	}
+	return q
+}
+
+// Subdivide splits the curve into n pieces of equal parameter range.  The
+// curve is returned unchanged if n is less than 2.
+func (q QuadBezier) Subdivide(n int) []QuadBezier {
+	if n < 1 {
+		n = 1
+	}
+	r := make([]QuadBezier, 0, n)
+	for i := n; i > 1; i-- {
+		a, b := q.Split(1 / float64(i))
+		r = append(r, a)
+		q = b
+	}
+	return append(r, q)
+}
+
+// Extrema returns, in increasing order, the parameters in (0, 1) where the
+// curve is horizontal or vertical.
+func (q QuadBezier) Extrema() []float64 {
+	var r []float64
+	for _, c := range [][3]float64{{q.P0.X, q.P1.X, q.P2.X}, {q.P0.Y, q.P1.Y, q.P2.Y}} {
+		// The derivative is linear: 2(p1-p0) + 2t(p0-2p1+p2).
+		r = appendRoots(r, solveQuadratic(0, 2*(c[0]-2*c[1]+c[2]), 2*(c[1]-c[0])))
+	}
+	sort.Float64s(r)
+	return r
+}
+
+// Bounds returns the tight bounding box of the curve.
+func (q QuadBezier) Bounds() geom.Rect {
+	return curveBounds(q.P0, q.P2, q.Extrema(), q.Point)
+}
+
+// Elevate returns the cubic that draws exactly the same curve.
+func (q QuadBezier) Elevate() CubicBezier {
+	return CubicBezier{
+		P0: q.P0,
+		P1: coordLerp(q.P0, q.P1, 2.0/3),
+		P2: coordLerp(q.P2, q.P1, 2.0/3),
+		P3: q.P2,
+	}
+}
+
+// Command returns the curve as an absolute Q command.
+func (q QuadBezier) Command() PathCommand {
+	return PathCommand{Command: 'Q', Params: []float64{q.P1.X, q.P1.Y, q.P2.X, q.P2.Y}}
+}
+
+// Point returns the position on the curve at t.
+func (c CubicBezier) Point(t float64) geom.Coord {
+	mt := 1 - t
+	a, b, cc, d := mt*mt*mt, 3*mt*mt*t, 3*mt*t*t, t*t*t
+	return geom.Coord{
+		X: a*c.P0.X + b*c.P1.X + cc*c.P2.X + d*c.P3.X,
+		Y: a*c.P0.Y + b*c.P1.Y + cc*c.P2.Y + d*c.P3.Y,
+	}
+}
+
+// Derivative returns the first derivative of the curve at t.
+func (c CubicBezier) Derivative(t float64) geom.Coord {
+	mt := 1 - t
+	a := coordScale(coordSub(c.P1, c.P0), 3*mt*mt)
+	b := coordScale(coordSub(c.P2, c.P1), 6*mt*t)
+	d := coordScale(coordSub(c.P3, c.P2), 3*t*t)
+	return coordAdd(coordAdd(a, b), d)
+}
+
+// SecondDerivative returns the second derivative of the curve at t.
+func (c CubicBezier) SecondDerivative(t float64) geom.Coord {
+	a := coordAdd(coordSub(c.P2, coordScale(c.P1, 2)), c.P0)
+	b := coordAdd(coordSub(c.P3, coordScale(c.P2, 2)), c.P1)
+	return coordAdd(coordScale(a, 6*(1-t)), coordScale(b, 6*t))
+}
+
+// Split divides the curve at t using de Casteljau's algorithm.
+func (c CubicBezier) Split(t float64) (CubicBezier, CubicBezier) {
+	p01, p12, p23 := coordLerp(c.P0, c.P1, t), coordLerp(c.P1, c.P2, t), coordLerp(c.P2, c.P3, t)
+	p012, p123 := coordLerp(p01, p12, t), coordLerp(p12, p23, t)
+	m := coordLerp(p012, p123, t)
+	return CubicBezier{c.P0, p01, p012, m}, CubicBezier{m, p123, p23, c.P3}
+}
+
+// SubCurve returns the part of the curve between t0 and t1.  The part runs
+// backwards if t0 is greater than t1 and is a single point if they are equal.
+func (c CubicBezier) SubCurve(t0, t1 float64) CubicBezier {
+	if t0 > t1 {
+		r := c.SubCurve(t1, t0)
+		return CubicBezier{r.P3, r.P2, r.P1, r.P0}
+	}
+	if t0 == t1 {
+		p := c.Point(t0)
+		return CubicBezier{p, p, p, p}
+	}
+	if t1 < 1 {
+		c, _ = c.Split(t1)
+	}
+	if t0 > 0 {
+		_, c = c.Split(t0 / t1)
+	}
+	return c
+}
+
+// Subdivide splits the curve into n pieces of equal parameter range.  The
+// curve is returned unchanged if n is less than 2.
+func (c CubicBezier) Subdivide(n int) []CubicBezier {
+	if n < 1 {
+		n = 1
+	}
+	r := make([]CubicBezier, 0, n)
+	for i := n; i > 1; i-- {
+		a, b := c.Split(1 / float64(i))
+		r = append(r, a)
+		c = b
+	}
+	return append(r, c)
+}
+
+// Extrema returns, in increasing order, the parameters in (0, 1) where the
+// curve is horizontal or vertical.
+func (c CubicBezier) Extrema() []float64 {
+	var r []float64
+	for _, p := range [][4]float64{{c.P0.X, c.P1.X, c.P2.X, c.P3.X}, {c.P0.Y, c.P1.Y, c.P2.Y, c.P3.Y}} {
+		// The derivative divided by 3 is a quadratic in t.
+		a := -p[0] + 3*p[1] - 3*p[2] + p[3]
+		b := 2 * (p[0] - 2*p[1] + p[2])
+		cc := p[1] - p[0]
+		r = appendRoots(r, solveQuadratic(a, b, cc))
+	}
+	sort.Float64s(r)
+	return r
+}
+
+// Inflections returns, in increasing order, the parameters in (0, 1) where
+// the curvature of the curve changes sign.
+func (c CubicBezier) Inflections() []float64 {
+	// The cross product of the first and second derivatives is quadratic in t.
+	a := coordSub(c.P1, c.P0)
+	b := coordSub(coordSub(c.P2, c.P1), a)
+	d := coordSub(coordSub(c.P3, c.P0), coordScale(coordSub(c.P2, c.P1), 3))
+	r := appendRoots(nil, solveQuadratic(coordCross(b, d), coordCross(a, d), coordCross(a, b)))
+	sort.Float64s(r)
+	return r
+}
+
+// Bounds returns the tight bounding box of the curve.
+func (c CubicBezier) Bounds() geom.Rect {
+	return curveBounds(c.P0, c.P3, c.Extrema(), c.Point)
+}
+
+// ToQuads approximates the curve with quadratic Béziers that stay within
+// tol of it.  The curve is halved until the quadratic sharing its end points
+// and midpoint tangent is close enough.
+func (c CubicBezier) ToQuads(tol float64) []QuadBezier {
+	tol = math.Max(tol, minTolerance)
+	var r []QuadBezier
+	var approx func(c CubicBezier, depth int)
+	approx = func(c CubicBezier, depth int) {
+		// The distance between a cubic and the quadratic with control point
+		// (3(P1+P2) - P0 - P3) / 4 is at most sqrt(3)/36 times the size of
+		// the cubic's third difference.
+		d := coordAdd(coordSub(c.P3, c.P0), coordScale(coordSub(c.P1, c.P2), 3))
+		if depth >= maxSubdivisions || math.Sqrt(3)/36*coordLen(d) <= tol {
+			ctrl := coordScale(coordSub(coordScale(coordAdd(c.P1, c.P2), 3), coordAdd(c.P0, c.P3)), 0.25)
+			r = append(r, QuadBezier{c.P0, ctrl, c.P3})
+			return
+		}
+		a, b := c.Split(0.5)
+		approx(a, depth+1)
+		approx(b, depth+1)
+	}
+	approx(c, 0)
+	return r
+}
+
+// Command returns the curve as an absolute C command.
+func (c CubicBezier) Command() PathCommand {
+	return PathCommand{Command: 'C', Params: []float64{c.P1.X, c.P1.Y, c.P2.X, c.P2.Y, c.P3.X, c.P3.Y}}
+}
+
+// solveQuadratic returns the real roots of at^2 + bt + c, handling the
+// degenerate linear and constant cases.
+func solveQuadratic(a, b, c float64) []float64 {
+	scale := math.Max(math.Abs(a), math.Max(math.Abs(b), math.Abs(c)))
+	if scale == 0 {
+		return nil
+	}
+	if math.Abs(a) <= geomEpsilon*scale {
+		if math.Abs(b) <= geomEpsilon*scale {
+			return nil
+		}
+		return []float64{-c / b}
+	}
+	disc := b*b - 4*a*c
+	if disc < 0 {
+		return nil
+	}
+	// Avoid cancellation by computing the larger root first.
+	sq := math.Sqrt(disc)
+	qq := -(b + math.Copysign(sq, b)) / 2
+	r := []float64{qq / a}
+	if qq != 0 {
+		r = append(r, c/qq)
+	}
+	return r
+}
+
+// appendRoots appends the roots that are strictly inside (0, 1).
+func appendRoots(r []float64, roots []float64) []float64 {
+	for _, t := range roots {
+		if t > 0 && t < 1 {
+			r = append(r, t)
+		}
+	}
+	return r
+}
+
+func curveBounds(p0, p1 geom.Coord, ts []float64, point func(float64) geom.Coord) geom.Rect {
+	r := geom.Rect{Min: p0, Max: p0}
+	r.ExpandToContainCoord(p1)
+	for _, t := range ts {
+		r.ExpandToContainCoord(point(t))
+	}
+	return r
+}
+
+// quad returns a 'Q' segment as a QuadBezier.
+func (s segment) quad() QuadBezier {
+	return QuadBezier{s.start, s.ctrl1, s.end}
+}
+
+// cubic returns a 'C' segment as a CubicBezier.
+func (s segment) cubic() CubicBezier {
+	return CubicBezier{s.start, s.ctrl1, s.ctrl2, s.end}
+}
+
+func quadSegment(q QuadBezier) segment {
+	return segment{kind: 'Q', start: q.P0, ctrl1: q.P1, end: q.P2}
+}
+
+func cubicSegment(c CubicBezier) segment {
+	return segment{kind: 'C', start: c.P0, ctrl1: c.P1, ctrl2: c.P2, end: c.P3}
+}
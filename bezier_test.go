@@ -0,0 +1,226 @@
+// Copyright 2018 Joe Beda
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package svgdata
+
+import (
+	"testing"
+
+	"github.com/jbeda/geom"
+	"github.com/stretchr/testify/assert"
+)
+
+func assertCoordNear(assert *assert.Assertions, expected, actual geom.Coord, delta float64) {
+	assert.InDelta(expected.X, actual.X, delta)
+	assert.InDelta(expected.Y, actual.Y, delta)
+}
+
+func TestBezierFromCommand(t *testing.T) {
+	assert := assert.New(t)
+
+	cmds := mustParsePath(t, "M0,0 C0,10 10,10 10,0 S20,-10 20,0 T30,0 Q35,5 40,0")[0].Commands
+
+	c, ok := CubicBezierFromCommand(cmds[0], cmds[1])
+	assert.True(ok)
+	assert.Equal(CubicBezier{geom.Coord{X: 0, Y: 0}, geom.Coord{X: 0, Y: 10}, geom.Coord{X: 10, Y: 10}, geom.Coord{X: 10, Y: 0}}, c)
+
+	// The first control point of S reflects the last one of the C.
+	c, ok = CubicBezierFromCommand(cmds[1], cmds[2])
+	assert.True(ok)
+	assert.Equal(geom.Coord{X: 10, Y: -10}, c.P1)
+
+	// T after a non-quadratic has its control point at its start.
+	q, ok := QuadBezierFromCommand(cmds[2], cmds[3])
+	assert.True(ok)
+	assert.Equal(geom.Coord{X: 20, Y: 0}, q.P1)
+
+	q, ok = QuadBezierFromCommand(cmds[3], cmds[4])
+	assert.True(ok)
+	assert.Equal(QuadBezier{geom.Coord{X: 30, Y: 0}, geom.Coord{X: 35, Y: 5}, geom.Coord{X: 40, Y: 0}}, q)
+	c, ok = CubicBezierFromCommand(cmds[3], cmds[4])
+	assert.True(ok)
+	assert.Equal(q.Elevate(), c)
+
+	_, ok = QuadBezierFromCommand(cmds[0], cmds[1])
+	assert.False(ok)
+	_, ok = CubicBezierFromCommand(PathCommand{}, cmds[0])
+	assert.False(ok)
+
+	// The control point of a T after another T depends on the commands
+	// before them, so only the subpath can find it.
+	sp := mustParsePath(t, "M0 0Q10 10 20 0T40 0T60 0")[0]
+	_, ok = QuadBezierFromCommand(sp.Commands[2], sp.Commands[3])
+	assert.False(ok)
+	_, ok = CubicBezierFromCommand(sp.Commands[2], sp.Commands[3])
+	assert.False(ok)
+	q, ok = sp.QuadBezier(3)
+	assert.True(ok)
+	assert.Equal(QuadBezier{geom.Coord{X: 40, Y: 0}, geom.Coord{X: 50, Y: 10}, geom.Coord{X: 60, Y: 0}}, q)
+	c, ok = sp.CubicBezier(3)
+	assert.True(ok)
+	assert.Equal(q.Elevate(), c)
+	q, ok = sp.QuadBezier(2)
+	assert.True(ok)
+	assert.Equal(geom.Coord{X: 30, Y: -10}, q.P1)
+	_, ok = sp.QuadBezier(0)
+	assert.False(ok)
+	_, ok = sp.CubicBezier(4)
+	assert.False(ok)
+}
+
+func TestBezierEvaluate(t *testing.T) {
+	assert := assert.New(t)
+
+	q := QuadBezier{geom.Coord{X: 0, Y: 0}, geom.Coord{X: 5, Y: 10}, geom.Coord{X: 10, Y: 0}}
+	assertCoordNear(assert, geom.Coord{X: 5, Y: 5}, q.Point(0.5), 1e-12)
+	assertCoordNear(assert, geom.Coord{X: 10, Y: 0}, q.Derivative(0.5), 1e-12)
+
+	c := CubicBezier{geom.Coord{X: 0, Y: 0}, geom.Coord{X: 0, Y: 10}, geom.Coord{X: 10, Y: 10}, geom.Coord{X: 10, Y: 0}}
+	assertCoordNear(assert, geom.Coord{X: 5, Y: 7.5}, c.Point(0.5), 1e-12)
+	assertCoordNear(assert, geom.Coord{X: 15, Y: 0}, c.Derivative(0.5), 1e-12)
+
+	// Compare derivatives with finite differences.
+	const h = 1e-6
+	for _, u := range []float64{0.1, 0.4, 0.9} {
+		fd := coordScale(coordSub(c.Point(u+h), c.Point(u-h)), 1/(2*h))
+		assertCoordNear(assert, fd, c.Derivative(u), 1e-5)
+		fd = coordScale(coordSub(c.Derivative(u+h), c.Derivative(u-h)), 1/(2*h))
+		assertCoordNear(assert, fd, c.SecondDerivative(u), 1e-5)
+		fd = coordScale(coordSub(q.Point(u+h), q.Point(u-h)), 1/(2*h))
+		assertCoordNear(assert, fd, q.Derivative(u), 1e-5)
+	}
+}
+
+func TestBezierSplit(t *testing.T) {
+	assert := assert.New(t)
+
+	c := CubicBezier{geom.Coord{X: 0, Y: 0}, geom.Coord{X: 3, Y: 12}, geom.Coord{X: 11, Y: -4}, geom.Coord{X: 10, Y: 5}}
+	a, b := c.Split(0.3)
+	for _, u := range []float64{0, 0.25, 0.5, 1} {
+		assertCoordNear(assert, c.Point(0.3*u), a.Point(u), 1e-12)
+		assertCoordNear(assert, c.Point(0.3+0.7*u), b.Point(u), 1e-12)
+	}
+
+	sub := c.SubCurve(0.2, 0.6)
+	assertCoordNear(assert, c.Point(0.4), sub.Point(0.5), 1e-12)
+
+	// Reversed bounds give the same part running backwards and equal bounds
+	// a single point, also at the start of the curve.
+	back := c.SubCurve(0.6, 0.2)
+	assertCoordNear(assert, c.Point(0.6), back.P0, 1e-12)
+	assertCoordNear(assert, c.Point(0.2), back.P3, 1e-12)
+	assertCoordNear(assert, c.Point(0.3), back.Point(0.75), 1e-12)
+	back = c.SubCurve(0.5, 0)
+	assertCoordNear(assert, c.Point(0.5), back.P0, 1e-12)
+	assert.Equal(c.P0, back.P3)
+	assert.Equal(CubicBezier{c.P0, c.P0, c.P0, c.P0}, c.SubCurve(0, 0))
+	for _, p := range []geom.Coord{c.SubCurve(0.4, 0.4).P0, c.SubCurve(0.4, 0.4).P3} {
+		assertCoordNear(assert, c.Point(0.4), p, 1e-12)
+	}
+
+	pieces := c.Subdivide(4)
+	if assert.Len(pieces, 4) {
+		for i, p := range pieces {
+			assertCoordNear(assert, c.Point(float64(i)/4), p.P0, 1e-12)
+			assertCoordNear(assert, c.Point(float64(i)/4+0.125), p.Point(0.5), 1e-12)
+		}
+		assert.Equal(c.P3, pieces[3].P3)
+	}
+
+	q := QuadBezier{geom.Coord{X: 0, Y: 0}, geom.Coord{X: 5, Y: 10}, geom.Coord{X: 10, Y: 0}}
+	qa, qb := q.Split(0.5)
+	assertCoordNear(assert, q.Point(0.25), qa.Point(0.5), 1e-12)
+	assertCoordNear(assert, q.Point(0.75), qb.Point(0.5), 1e-12)
+	assert.Len(q.Subdivide(3), 3)
+	assertCoordNear(assert, q.Point(0.5), q.SubCurve(0.25, 0.75).Point(0.5), 1e-12)
+	qback := q.SubCurve(0.5, 0)
+	assertCoordNear(assert, q.Point(0.5), qback.P0, 1e-12)
+	assertCoordNear(assert, q.Point(0.25), qback.Point(0.5), 1e-12)
+	assert.Equal(q.P0, qback.P2)
+	assert.Equal(QuadBezier{q.P2, q.P2, q.P2}, q.SubCurve(1, 1))
+
+	// Fewer than one piece leaves the curve whole.
+	for _, n := range []int{0, -1} {
+		assert.Equal([]QuadBezier{q}, q.Subdivide(n))
+		assert.Equal([]CubicBezier{c}, c.Subdivide(n))
+	}
+}
+
+func TestBezierExtrema(t *testing.T) {
+	assert := assert.New(t)
+
+	q := QuadBezier{geom.Coord{X: 0, Y: 0}, geom.Coord{X: 5, Y: 10}, geom.Coord{X: 10, Y: 0}}
+	assert.Equal([]float64{0.5}, q.Extrema())
+	b := q.Bounds()
+	assert.InDelta(5, b.Max.Y, 1e-12)
+
+	// An S shaped curve with extrema in y and an inflection in the middle.
+	c := CubicBezier{geom.Coord{X: 0, Y: 0}, geom.Coord{X: 5, Y: 10}, geom.Coord{X: 5, Y: -10}, geom.Coord{X: 10, Y: 0}}
+	ext := c.Extrema()
+	if assert.Len(ext, 2) {
+		for _, u := range ext {
+			assert.InDelta(0, c.Derivative(u).Y, 1e-9)
+		}
+	}
+	infl := c.Inflections()
+	if assert.Len(infl, 1) {
+		assert.InDelta(0.5, infl[0], 1e-12)
+	}
+	b = c.Bounds()
+	assert.InDelta(c.Point(ext[0]).Y, b.Max.Y, 1e-12)
+	assert.InDelta(c.Point(ext[1]).Y, b.Min.Y, 1e-12)
+	assert.Equal(0.0, b.Min.X)
+	assert.Equal(10.0, b.Max.X)
+
+	// An arch has no inflection.
+	arch := CubicBezier{geom.Coord{X: 0, Y: 0}, geom.Coord{X: 0, Y: 10}, geom.Coord{X: 10, Y: 10}, geom.Coord{X: 10, Y: 0}}
+	assert.Empty(arch.Inflections())
+}
+
+func TestBezierDegree(t *testing.T) {
+	assert := assert.New(t)
+
+	q := QuadBezier{geom.Coord{X: 0, Y: 0}, geom.Coord{X: 5, Y: 10}, geom.Coord{X: 10, Y: 0}}
+	c := q.Elevate()
+	for i := 0; i <= 10; i++ {
+		u := float64(i) / 10
+		assertCoordNear(assert, q.Point(u), c.Point(u), 1e-12)
+	}
+
+	// An elevated quadratic converts back exactly.
+	quads := c.ToQuads(1e-6)
+	if assert.Len(quads, 1) {
+		assertCoordNear(assert, q.P1, quads[0].P1, 1e-12)
+	}
+
+	s := CubicBezier{geom.Coord{X: 0, Y: 0}, geom.Coord{X: 5, Y: 10}, geom.Coord{X: 5, Y: -10}, geom.Coord{X: 10, Y: 0}}
+	for _, tol := range []float64{0.1, 0.001} {
+		quads = s.ToQuads(tol)
+		assert.True(len(quads) > 1)
+		assert.Equal(s.P0, quads[0].P0)
+		assert.Equal(s.P3, quads[len(quads)-1].P2)
+		for i, qq := range quads {
+			if i > 0 {
+				assert.Equal(quads[i-1].P2, qq.P0)
+			}
+			for j := 0; j <= 10; j++ {
+				p := qq.Point(float64(j) / 10)
+				_, near := cubicSegment(s).nearest(p)
+				assert.True(coordDist(p, near) <= tol, "%v is %v from the cubic", p, coordDist(p, near))
+			}
+		}
+	}
+	assert.Equal(PathCommand{Command: 'Q', Params: []float64{5, 10, 10, 0}}, q.Command())
+	assert.Equal(PathCommand{Command: 'C', Params: []float64{5, 10, 5, -10, 10, 0}}, s.Command())
+}
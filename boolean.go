@@ -0,0 +1,447 @@
+// Copyright 2018 Joe Beda
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package svgdata
+
+import (
+	"math"
+	"sort"
+
+	"github.com/jbeda/geom"
+	"github.com/pkg/errors"
+)
+
+// BoolOp is a boolean operation between two areas.
+type BoolOp int
+
+const (
+	Union BoolOp = iota
+	Intersection
+	Difference
+	Xor
+)
+
+func (op BoolOp) apply(a, b bool) bool {
+	switch op {
+	case Union:
+		return a || b
+	case Intersection:
+		return a && b
+	case Difference:
+		return a && !b
+	case Xor:
+		return a != b
+	}
+	return false
+}
+
+// region is an area described by closed polylines and a fill rule.  When
+// positive is set only areas with a positive winding number are inside, which
+// is used to clean up raw offset curves.
+type region struct {
+	pls      []Polyline
+	evenOdd  bool
+	positive bool
+}
+
+func (r region) contains(p geom.Coord) bool {
+	wn := 0
+	for _, pl := range r.pls {
+		wn += pl.windingNumber(p)
+	}
+	switch {
+	case r.positive:
+		return wn > 0
+	case r.evenOdd:
+		return wn%2 != 0
+	}
+	return wn != 0
+}
+
+func regionsContain(rs []region, p geom.Coord) bool {
+	for _, r := range rs {
+		if r.contains(p) {
+			return true
+		}
+	}
+	return false
+}
+
+// BooleanSubPaths applies op to the areas filled by subject and clip, both
+// using the nonzero fill rule.  Curves are flattened to within tol.  The result
+// is a set of closed subpaths where holes wind the opposite way of the
+// contours that contain them, so it fills correctly with either fill rule.
+func BooleanSubPaths(op BoolOp, subject, clip []SubPath, tol float64) []SubPath {
+	return PolylinesToSubPaths(clipRegions(op,
+		[]region{{pls: FlattenSubPaths(subject, tol)}},
+		[]region{{pls: FlattenSubPaths(clip, tol)}}))
+}
+
+// BooleanShapes applies op between two shapes, honoring the fill-rule of each.
+// The result is a new path that carries over the attributes of subject other
+// than id, including its transform, so the clip is mapped into the user space of
+// subject.  Only the transforms set directly on the shapes are taken into
+// account; use Root.Combine for shapes in a document.
+func BooleanShapes(op BoolOp, subject, clip Shape, tol float64) (*Path, error) {
+	st, err := NodeTransform(subject)
+	if err != nil {
+		return nil, err
+	}
+	ct, err := NodeTransform(clip)
+	if err != nil {
+		return nil, err
+	}
+	inv, ok := st.Invert()
+	if !ok {
+		return nil, errors.Errorf("transform of %s is not invertible", subject.Name())
+	}
+
+	pls := clipRegions(op, []region{shapeRegion(subject, IdentityTransform, NodeStyle(subject), tol)},
+		[]region{shapeRegion(clip, inv.Multiply(ct), NodeStyle(clip), tol)})
+	p := newPathFromNode(subject, PolylinesToSubPaths(pls))
+	delete(p.attrs, "id")
+	return p, nil
+}
+
+// Combine applies op between the area covered by the subject nodes and the
+// area covered by the clip nodes.  Nodes must be part of r and groups
+// contribute every shape within them.  Transforms and fill rules are taken
+// from the document and the resulting path is in the user space of r.  It
+// carries over the attributes of the first subject shape but is not added to
+// the document.
+func (r *Root) Combine(op BoolOp, subject, clip []Node, tol float64) (*Path, error) {
+	ctxs, err := nodeContexts(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var first Shape
+	collect := func(ns []Node) ([]region, error) {
+		var rs []region
+		for _, n := range ns {
+			if _, ok := ctxs[n]; !ok {
+				return nil, errors.Errorf("node %s is not part of the document", n.Name())
+			}
+			Walk(n, func(c Node) bool {
+				if s, ok := c.(Shape); ok {
+					if first == nil {
+						first = s
+					}
+					ctx := ctxs[c]
+					rs = append(rs, shapeRegion(s, ctx.ctm, ctx.style, tol))
+				}
+				return true
+			})
+		}
+		return rs, nil
+	}
+
+	srs, err := collect(subject)
+	if err != nil {
+		return nil, err
+	}
+	firstSubject := first
+	crs, err := collect(clip)
+	if err != nil {
+		return nil, err
+	}
+
+	sps := PolylinesToSubPaths(clipRegions(op, srs, crs))
+	if firstSubject == nil {
+		p := NewPath()
+		p.SubPaths = sps
+		return p, nil
+	}
+	p := newPathFromNode(firstSubject, sps)
+	delete(p.attrs, "transform")
+	delete(p.attrs, "id")
+	return p, nil
+}
+
+func shapeRegion(s Shape, ctm Transform, st Style, tol float64) region {
+	r := region{evenOdd: st.FillRule() == "evenodd"}
+	for _, pl := range FlattenShape(s, tol/math.Max(ctm.Scale(), geomEpsilon)) {
+		r.pls = append(r.pls, pl.transform(ctm))
+	}
+	return r
+}
+
+// transform returns a copy of the polyline with t applied to every point.
+func (pl Polyline) transform(t Transform) Polyline {
+	r := Polyline{Closed: pl.Closed, Points: make([]geom.Coord, len(pl.Points))}
+	for i, p := range pl.Points {
+		r.Points[i] = t.Apply(p)
+	}
+	return r
+}
+
+// clipRegions computes op between the union of the subject regions and the
+// union of the clip regions.
+//
+// Every edge of the input is split wherever it meets another edge so that the
+// edges form a planar arrangement.  Each edge in the arrangement is then
+// classified by testing whether the result covers the area on either side of
+// it.  Edges with the result on exactly one side form the boundary and are
+// directed so the result is on their left.  Finally the boundary edges are
+// chained into closed loops.
+func clipRegions(op BoolOp, subject, clip []region) []Polyline {
+	var all []Polyline
+	for _, r := range append(append([]region{}, subject...), clip...) {
+		all = append(all, r.pls...)
+	}
+
+	arr := newArrangement(all)
+	inside := func(p geom.Coord) bool {
+		return op.apply(regionsContain(subject, p), regionsContain(clip, p))
+	}
+
+	var kept []arrEdge
+	for _, e := range arr.edges {
+		a, b := arr.verts[e.a], arr.verts[e.b]
+		d := coordSub(b, a)
+		l := coordLen(d)
+		off := math.Min(arr.snap*100, l/4)
+		m := coordLerp(a, b, 0.5)
+		n := coordScale(coordPerp(d), off/l)
+
+		left, right := inside(coordAdd(m, n)), inside(coordSub(m, n))
+		switch {
+		case left && !right:
+			kept = append(kept, e)
+		case right && !left:
+			kept = append(kept, arrEdge{a: e.b, b: e.a})
+		}
+	}
+
+	return removeCollinear(arr.chain(kept), arr.snap)
+}
+
+// arrangement is a set of edges that only meet at their end points.
+type arrangement struct {
+	verts []geom.Coord
+	edges []arrEdge
+	snap  float64
+
+	grid map[[2]int64][]int
+}
+
+type arrEdge struct {
+	a, b int // Indexes into verts
+}
+
+func newArrangement(pls []Polyline) *arrangement {
+	var segs [][2]geom.Coord
+	bounds := geom.Rect{Min: geom.Coord{X: math.Inf(1), Y: math.Inf(1)}, Max: geom.Coord{X: math.Inf(-1), Y: math.Inf(-1)}}
+	for _, pl := range pls {
+		n := len(pl.Points)
+		for i := 0; i < n; i++ {
+			a, b := pl.Points[i], pl.Points[(i+1)%n]
+			bounds.ExpandToContainCoord(a)
+			if a != b {
+				segs = append(segs, [2]geom.Coord{a, b})
+			}
+		}
+	}
+
+	extent := 1.0
+	if len(segs) > 0 {
+		extent = math.Max(extent, math.Max(bounds.Width(), bounds.Height()))
+	}
+	arr := &arrangement{snap: extent * 1e-9, grid: map[[2]int64][]int{}}
+
+	splits := arr.intersectAll(segs)
+
+	seen := map[arrEdge]bool{}
+	for i, s := range segs {
+		pts := append([]geom.Coord{s[0], s[1]}, splits[i]...)
+		d := coordSub(s[1], s[0])
+		sort.Slice(pts, func(x, y int) bool {
+			return coordDot(coordSub(pts[x], s[0]), d) < coordDot(coordSub(pts[y], s[0]), d)
+		})
+
+		prev := arr.vertex(pts[0])
+		for _, p := range pts[1:] {
+			v := arr.vertex(p)
+			if v == prev {
+				continue
+			}
+			key := arrEdge{a: prev, b: v}
+			if v < prev {
+				key = arrEdge{a: v, b: prev}
+			}
+			if !seen[key] {
+				seen[key] = true
+				arr.edges = append(arr.edges, key)
+			}
+			prev = v
+		}
+	}
+	return arr
+}
+
+// vertex returns the index of the vertex at p, merging it with any existing
+// vertex within the snap distance.
+func (arr *arrangement) vertex(p geom.Coord) int {
+	cx, cy := int64(math.Floor(p.X/arr.snap)), int64(math.Floor(p.Y/arr.snap))
+	for dx := int64(-1); dx <= 1; dx++ {
+		for dy := int64(-1); dy <= 1; dy++ {
+			for _, i := range arr.grid[[2]int64{cx + dx, cy + dy}] {
+				if coordDist(arr.verts[i], p) <= arr.snap {
+					return i
+				}
+			}
+		}
+	}
+	arr.verts = append(arr.verts, p)
+	i := len(arr.verts) - 1
+	arr.grid[[2]int64{cx, cy}] = append(arr.grid[[2]int64{cx, cy}], i)
+	return i
+}
+
+// intersectAll finds the points where each segment needs to be split.  A sweep
+// over x keeps this from comparing segments that are far apart.
+func (arr *arrangement) intersectAll(segs [][2]geom.Coord) [][]geom.Coord {
+	splits := make([][]geom.Coord, len(segs))
+
+	order := make([]int, len(segs))
+	for i := range order {
+		order[i] = i
+	}
+	minX := func(i int) float64 { return math.Min(segs[i][0].X, segs[i][1].X) }
+	maxX := func(i int) float64 { return math.Max(segs[i][0].X, segs[i][1].X) }
+	sort.Slice(order, func(a, b int) bool { return minX(order[a]) < minX(order[b]) })
+
+	for oi, i := range order {
+		for _, j := range order[oi+1:] {
+			if minX(j) > maxX(i)+arr.snap {
+				break
+			}
+			si, sj := segs[i], segs[j]
+			if math.Min(si[0].Y, si[1].Y) > math.Max(sj[0].Y, sj[1].Y)+arr.snap ||
+				math.Min(sj[0].Y, sj[1].Y) > math.Max(si[0].Y, si[1].Y)+arr.snap {
+				continue
+			}
+			pi, pj := intersectSegments(si[0], si[1], sj[0], sj[1], arr.snap)
+			splits[i] = append(splits[i], pi...)
+			splits[j] = append(splits[j], pj...)
+		}
+	}
+	return splits
+}
+
+// intersectSegments returns the points where a1-a2 must be split and where
+// b1-b2 must be split so that the two segments only meet at end points.
+func intersectSegments(a1, a2, b1, b2 geom.Coord, tol float64) ([]geom.Coord, []geom.Coord) {
+	r, s := coordSub(a2, a1), coordSub(b2, b1)
+	rl, sl := coordLen(r), coordLen(s)
+	den := coordCross(r, s)
+	qp := coordSub(b1, a1)
+
+	if math.Abs(den) > geomEpsilon*rl*sl {
+		t := coordCross(qp, s) / den
+		u := coordCross(qp, r) / den
+		et, eu := tol/rl, tol/sl
+		if t < -et || t > 1+et || u < -eu || u > 1+eu {
+			return nil, nil
+		}
+		p := coordAdd(a1, coordScale(r, math.Max(0, math.Min(1, t))))
+		return []geom.Coord{p}, []geom.Coord{p}
+	}
+
+	// Parallel.  Only collinear overlaps need splitting.
+	if math.Abs(coordCross(r, qp))/rl > tol {
+		return nil, nil
+	}
+	var pa, pb []geom.Coord
+	for _, p := range []geom.Coord{b1, b2} {
+		if t := coordDot(coordSub(p, a1), r) / (rl * rl); t > 0 && t < 1 {
+			pa = append(pa, p)
+		}
+	}
+	for _, p := range []geom.Coord{a1, a2} {
+		if u := coordDot(coordSub(p, b1), s) / (sl * sl); u > 0 && u < 1 {
+			pb = append(pb, p)
+		}
+	}
+	return pa, pb
+}
+
+// chain joins directed edges into closed loops.  Where several edges leave a
+// vertex the one that turns furthest left is taken, which keeps loops that
+// only touch at a vertex separate.
+func (arr *arrangement) chain(edges []arrEdge) []Polyline {
+	out := map[int][]int{}
+	for i, e := range edges {
+		out[e.a] = append(out[e.a], i)
+	}
+	used := make([]bool, len(edges))
+
+	var r []Polyline
+	for start := range edges {
+		if used[start] {
+			continue
+		}
+		var pts []geom.Coord
+		cur := start
+		for !used[cur] {
+			used[cur] = true
+			e := edges[cur]
+			pts = append(pts, arr.verts[e.a])
+
+			din := coordSub(arr.verts[e.b], arr.verts[e.a])
+			next, best := -1, math.Inf(-1)
+			for _, c := range out[e.b] {
+				if used[c] && c != start {
+					continue
+				}
+				dout := coordSub(arr.verts[edges[c].b], arr.verts[edges[c].a])
+				if a := math.Atan2(coordCross(din, dout), coordDot(din, dout)); a > best {
+					next, best = c, a
+				}
+			}
+			if next < 0 {
+				break
+			}
+			cur = next
+		}
+		if len(pts) >= 3 {
+			r = append(r, Polyline{Points: pts, Closed: true})
+		}
+	}
+	return r
+}
+
+// removeCollinear drops points of closed polylines that lie on the line
+// between their neighbors.
+func removeCollinear(pls []Polyline, tol float64) []Polyline {
+	var r []Polyline
+	for _, pl := range pls {
+		pts := pl.Points
+		for changed := true; changed && len(pts) >= 3; {
+			changed = false
+			for i := 0; i < len(pts) && len(pts) >= 3; i++ {
+				prev, next := pts[(i+len(pts)-1)%len(pts)], pts[(i+1)%len(pts)]
+				if pointLineDist(pts[i], prev, next) <= tol &&
+					coordDot(coordSub(pts[i], prev), coordSub(next, pts[i])) >= 0 {
+					pts = append(pts[:i:i], pts[i+1:]...)
+					changed = true
+				}
+			}
+		}
+		if len(pts) >= 3 {
+			r = append(r, Polyline{Points: pts, Closed: true})
+		}
+	}
+	return r
+}
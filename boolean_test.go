@@ -0,0 +1,158 @@
+// Copyright 2018 Joe Beda
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package svgdata
+
+import (
+	"math"
+	"testing"
+
+	"github.com/jbeda/geom"
+	"github.com/stretchr/testify/assert"
+)
+
+func mustParsePath(t *testing.T, d string) []SubPath {
+	sps, err := ParsePathString(d)
+	assert.NoError(t, err)
+	return sps
+}
+
+// subPathsArea returns the area covered by the subpaths with the nonzero fill
+// rule.
+func subPathsArea(sps []SubPath) float64 {
+	a := 0.0
+	for _, pl := range FlattenSubPaths(sps, 0.001) {
+		a += polylineArea(pl.Points)
+	}
+	return math.Abs(a)
+}
+
+func TestBooleanSquares(t *testing.T) {
+	assert := assert.New(t)
+
+	a := mustParsePath(t, "M0,0 h10 v10 h-10 z")
+	b := mustParsePath(t, "M5,5 h10 v10 h-10 z")
+
+	u := BooleanSubPaths(Union, a, b, 0.01)
+	assert.Len(u, 1)
+	assert.InDelta(175, subPathsArea(u), 1e-9)
+	assert.Len(u[0].Commands, 9) // 8 corners and a close
+
+	i := BooleanSubPaths(Intersection, a, b, 0.01)
+	assert.Len(i, 1)
+	assert.InDelta(25, subPathsArea(i), 1e-9)
+
+	d := BooleanSubPaths(Difference, a, b, 0.01)
+	assert.Len(d, 1)
+	assert.InDelta(75, subPathsArea(d), 1e-9)
+
+	x := BooleanSubPaths(Xor, a, b, 0.01)
+	assert.InDelta(150, subPathsArea(x), 1e-9)
+	assert.True(FillContains(x, geom.Coord{X: 2, Y: 2}, "nonzero"))
+	assert.False(FillContains(x, geom.Coord{X: 7, Y: 7}, "nonzero"))
+}
+
+func TestBooleanHoles(t *testing.T) {
+	assert := assert.New(t)
+
+	outer := mustParsePath(t, "M0,0 h10 v10 h-10 z")
+	inner := mustParsePath(t, "M3,3 h4 v4 h-4 z")
+
+	d := BooleanSubPaths(Difference, outer, inner, 0.01)
+	assert.Len(d, 2)
+	assert.InDelta(84, subPathsArea(d), 1e-9)
+	assert.False(FillContains(d, geom.Coord{X: 5, Y: 5}, "nonzero"))
+	assert.False(FillContains(d, geom.Coord{X: 5, Y: 5}, "evenodd"))
+	assert.True(FillContains(d, geom.Coord{X: 1, Y: 5}, "nonzero"))
+
+	// Shared edges and touching shapes.
+	right := mustParsePath(t, "M10,0 h10 v10 h-10 z")
+	u := BooleanSubPaths(Union, outer, right, 0.01)
+	assert.Len(u, 1)
+	assert.InDelta(200, subPathsArea(u), 1e-9)
+	assert.Len(u[0].Commands, 5)
+
+	i := BooleanSubPaths(Intersection, outer, right, 0.01)
+	assert.Len(i, 0)
+}
+
+func TestBooleanCurves(t *testing.T) {
+	assert := assert.New(t)
+
+	c1 := NewCircle(geom.Coord{X: 0, Y: 0}, 10)
+	c2 := NewCircle(geom.Coord{X: 10, Y: 0}, 10)
+
+	p, err := BooleanShapes(Intersection, c1, c2, 0.001)
+	assert.NoError(err)
+	// Area of the lens between two circles of radius r a distance r apart.
+	lens := 2*100*math.Acos(0.5) - 5*math.Sqrt(400-100)
+	assert.InDelta(lens, subPathsArea(p.SubPaths), 0.05)
+
+	// The clip is mapped into the user space of the subject, whose transform
+	// the result keeps.
+	c1.Attrs()["transform"] = "scale(2)"
+	c2 = NewCircle(geom.Coord{X: 0, Y: 0}, 20)
+	c2.Attrs()["transform"] = "translate(20,0)"
+	p, err = BooleanShapes(Intersection, c1, c2, 0.001)
+	assert.NoError(err)
+	assert.Equal("scale(2)", p.Attrs()["transform"])
+	assert.InDelta(lens, subPathsArea(p.SubPaths), 0.05)
+
+	c1.Attrs()["transform"] = "scale(0)"
+	_, err = BooleanShapes(Intersection, c1, c2, 0.001)
+	assert.Error(err)
+}
+
+func TestBooleanFillRule(t *testing.T) {
+	assert := assert.New(t)
+
+	// A square with a hole from evenodd and a square covering the hole.
+	p := NewPath()
+	p.SubPaths = mustParsePath(t, "M0,0 h10 v10 h-10 z M3,3 h4 v4 h-4 z")
+	p.Attrs()["fill-rule"] = "evenodd"
+	p.Attrs()["fill"] = "red"
+	p.Attrs()["id"] = "square"
+	clip := NewRectXYWH(4, 4, 2, 2)
+
+	u, err := BooleanShapes(Union, p, clip, 0.01)
+	assert.NoError(err)
+	assert.Equal("red", u.Attrs()["fill"])
+	// The result can be added next to the subject without a duplicate id.
+	assert.NotContains(u.Attrs(), "id")
+	assert.InDelta(88, subPathsArea(u.SubPaths), 1e-9)
+	assert.Len(u.SubPaths, 3)
+}
+
+func TestRootCombine(t *testing.T) {
+	assert := assert.New(t)
+
+	r, err := Unmarshal([]byte(`<svg xmlns="http://www.w3.org/2000/svg">
+<g id="parts" transform="translate(100,0)" fill="blue">
+  <rect id="a" x="0" y="0" width="10" height="10"></rect>
+  <rect id="b" x="10" y="0" width="10" height="10"></rect>
+</g>
+<circle id="c" cx="110" cy="5" r="2"></circle>
+</svg>`))
+	assert.NoError(err)
+
+	p, err := r.Combine(Difference, []Node{FindByID(r, "parts")}, []Node{FindByID(r, "c")}, 0.001)
+	assert.NoError(err)
+	assert.Equal("", p.Attrs()["id"])
+	assert.InDelta(200-4*math.Pi, subPathsArea(p.SubPaths), 0.01)
+	assert.True(FillContains(p.SubPaths, geom.Coord{X: 101, Y: 1}, "nonzero"))
+	assert.False(FillContains(p.SubPaths, geom.Coord{X: 110, Y: 5}, "nonzero"))
+
+	_, err = r.Combine(Union, []Node{NewCircle(geom.Coord{}, 1)}, nil, 0.01)
+	assert.Error(err)
+}
@@ -0,0 +1,194 @@
+// Copyright 2018 Joe Beda
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package svgdata
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/jbeda/geom"
+)
+
+// CanvasOptions control the JavaScript generated by MarshalCanvas.
+type CanvasOptions struct {
+	// FuncName is the name of the generated function.  Empty means "draw".
+	FuncName string
+}
+
+// MarshalCanvas generates a JavaScript function that draws the document with
+// the HTML5 Canvas 2D API.  The function takes a CanvasRenderingContext2D and
+// draws at the document's size in CSS pixels from the origin, so callers can
+// transform the context to place and scale it.  Fills, strokes, solid colors,
+// dashes and transforms are supported.  Canvas has no groups, so the opacity
+// of a group is applied to each of its children.  Paints and elements that
+// can't be drawn are noted in comments.
+func MarshalCanvas(r *Root, opts CanvasOptions) ([]byte, error) {
+	name := opts.FuncName
+	if name == "" {
+		name = "draw"
+	}
+	_, vt, err := viewportTransform(r)
+	if err != nil {
+		return nil, err
+	}
+
+	w := &canvasWriter{}
+	fmt.Fprintf(&w.b, "function %s(ctx) {\n", name)
+	w.line(1, "ctx.save();")
+	if !vt.IsIdentity() {
+		w.line(1, "ctx.transform(%s);", canvasTransform(vt))
+	}
+	if err := w.children(r, 1, NodeStyle(r), 1); err != nil {
+		return nil, err
+	}
+	w.line(1, "ctx.restore();")
+	w.b.WriteString("}\n")
+	return w.b.Bytes(), nil
+}
+
+type canvasWriter struct {
+	b bytes.Buffer
+}
+
+func (w *canvasWriter) line(depth int, format string, args ...interface{}) {
+	w.b.WriteString(strings.Repeat("  ", depth))
+	fmt.Fprintf(&w.b, format, args...)
+	w.b.WriteByte('\n')
+}
+
+func (w *canvasWriter) children(n Node, alpha float64, st Style, depth int) error {
+	return walkGroups(n, st, func(c Node, t Transform, cst Style, opacity float64) error {
+		s, shape := c.(Shape)
+		if !shape && !groupingElements[c.Name()] {
+			w.line(depth, "// <%s> not supported", c.Name())
+			return nil
+		}
+		calpha := alpha * opacity
+		d := depth
+		if !t.IsIdentity() {
+			w.line(depth, "ctx.save();")
+			w.line(depth+1, "ctx.transform(%s);", canvasTransform(t))
+			d++
+		}
+		if shape && cst.Get("visibility", "visible") == "visible" {
+			if err := w.shape(s, calpha, cst, d); err != nil {
+				return err
+			}
+		}
+		if err := w.children(c, calpha, cst, d); err != nil {
+			return err
+		}
+		if !t.IsIdentity() {
+			w.line(depth, "ctx.restore();")
+		}
+		return nil
+	})
+}
+
+// paint returns a paint as a JavaScript string, noting paints that can't be
+// converted.
+func (w *canvasWriter) paint(depth int, k, v string) (string, bool) {
+	if isNonePaint(v) {
+		return "", false
+	}
+	c, ok := parseColor(v)
+	if !ok {
+		w.line(depth, "// %s %s not supported", k, strings.TrimSpace(v))
+		return "", false
+	}
+	return strconv.Quote(fmt.Sprintf("#%02x%02x%02x", c.R, c.G, c.B)), true
+}
+
+func (w *canvasWriter) shape(s Shape, alpha float64, st Style, depth int) error {
+	fill, hasFill := w.paint(depth, "fill", st.Get("fill", "black"))
+	stroke, hasStroke := w.paint(depth, "stroke", st.Get("stroke", "none"))
+	hasStroke = hasStroke && st.StrokeWidth() > 0
+	if !hasFill && !hasStroke {
+		return nil
+	}
+
+	w.line(depth, "ctx.beginPath();")
+	for _, sp := range s.ToSubPaths() {
+		segs, closed := closePathSegments(sp)
+		if len(segs) == 0 {
+			continue
+		}
+		w.line(depth, "ctx.moveTo(%s);", canvasCoords(segs[0].start))
+		for _, seg := range segs {
+			switch seg.kind {
+			case 'L':
+				w.line(depth, "ctx.lineTo(%s);", canvasCoords(seg.end))
+			case 'Q':
+				w.line(depth, "ctx.quadraticCurveTo(%s);", canvasCoords(seg.ctrl1, seg.end))
+			case 'C':
+				w.line(depth, "ctx.bezierCurveTo(%s);", canvasCoords(seg.ctrl1, seg.ctrl2, seg.end))
+			case 'A':
+				// Positive angles are towards the positive y axis in both SVG
+				// and Canvas.
+				ea := seg.ellipse()
+				w.line(depth, "ctx.ellipse(%s, %s, %s, %s, %s, %s, %t);", canvasCoords(ea.center),
+					roundedNumber(ea.rx), roundedNumber(ea.ry), roundedNumber(ea.phi),
+					roundedNumber(ea.theta), roundedNumber(ea.theta+ea.delta), ea.delta < 0)
+			}
+		}
+		if closed {
+			w.line(depth, "ctx.closePath();")
+		}
+	}
+
+	if hasFill {
+		w.line(depth, "ctx.globalAlpha = %s;", roundedNumber(alpha*styleOpacity(st, "fill-opacity")))
+		w.line(depth, "ctx.fillStyle = %s;", fill)
+		if st.FillRule() == "evenodd" {
+			w.line(depth, `ctx.fill("evenodd");`)
+		} else {
+			w.line(depth, "ctx.fill();")
+		}
+	}
+	if hasStroke {
+		w.line(depth, "ctx.globalAlpha = %s;", roundedNumber(alpha*styleOpacity(st, "stroke-opacity")))
+		w.line(depth, "ctx.strokeStyle = %s;", stroke)
+		w.line(depth, "ctx.lineWidth = %s;", roundedNumber(st.StrokeWidth()))
+		w.line(depth, "ctx.lineCap = %q;", [...]string{"butt", "round", "square"}[pdfLineCaps[st.LineCap()]])
+		w.line(depth, "ctx.lineJoin = %q;", [...]string{"miter", "round", "bevel"}[pdfLineJoins[st.LineJoin()]])
+		w.line(depth, "ctx.miterLimit = %s;", roundedNumber(st.MiterLimit()))
+		dashes, offset := dashPattern(s, st)
+		ds := make([]string, len(dashes))
+		for i, d := range dashes {
+			ds[i] = roundedNumber(d)
+		}
+		w.line(depth, "ctx.setLineDash([%s]);", strings.Join(ds, ", "))
+		w.line(depth, "ctx.lineDashOffset = %s;", roundedNumber(offset))
+		w.line(depth, "ctx.stroke();")
+	}
+	return nil
+}
+
+func canvasCoords(cs ...geom.Coord) string {
+	var parts []string
+	for _, c := range cs {
+		parts = append(parts, roundedNumber(c.X), roundedNumber(c.Y))
+	}
+	return strings.Join(parts, ", ")
+}
+
+func canvasTransform(t Transform) string {
+	return strings.Join([]string{
+		roundedNumber(t.A), roundedNumber(t.B), roundedNumber(t.C),
+		roundedNumber(t.D), roundedNumber(t.E), roundedNumber(t.F),
+	}, ", ")
+}
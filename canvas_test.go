@@ -0,0 +1,79 @@
+// Copyright 2018 Joe Beda
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package svgdata
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMarshalCanvas(t *testing.T) {
+	assert := assert.New(t)
+
+	r := mustUnmarshal(t, `<svg xmlns="http://www.w3.org/2000/svg" width="20" height="20" viewBox="0 0 10 10">
+  <g transform="translate(1 1)" opacity="0.5">
+    <path d="M0 0H4V4Z M1 1Q2 0 3 1" fill="#f00" fill-rule="evenodd"/>
+  </g>
+  <path d="M0 5C1 6 2 6 3 5A2 2 0 0 0 7 5" fill="none" stroke="blue" stroke-width="2"
+      stroke-linecap="round" stroke-dasharray="1 2" stroke-opacity="0.25"/>
+  <rect width="1" height="1" fill="url(#grad)"/>
+  <rect width="1" height="1" display="none"/>
+  <image href="a.png"/>
+</svg>`)
+	js, err := MarshalCanvas(r, CanvasOptions{FuncName: "drawIcon"})
+	if !assert.NoError(err) {
+		return
+	}
+	assert.Equal(`function drawIcon(ctx) {
+  ctx.save();
+  ctx.transform(2, 0, 0, 2, 0, 0);
+  ctx.save();
+    ctx.transform(1, 0, 0, 1, 1, 1);
+    ctx.beginPath();
+    ctx.moveTo(0, 0);
+    ctx.lineTo(4, 0);
+    ctx.lineTo(4, 4);
+    ctx.closePath();
+    ctx.moveTo(1, 1);
+    ctx.quadraticCurveTo(2, 0, 3, 1);
+    ctx.globalAlpha = 0.5;
+    ctx.fillStyle = "#ff0000";
+    ctx.fill("evenodd");
+  ctx.restore();
+  ctx.beginPath();
+  ctx.moveTo(0, 5);
+  ctx.bezierCurveTo(1, 6, 2, 6, 3, 5);
+  ctx.ellipse(5, 5, 2, 2, 0, 3.141592654, 0, true);
+  ctx.globalAlpha = 0.25;
+  ctx.strokeStyle = "#0000ff";
+  ctx.lineWidth = 2;
+  ctx.lineCap = "round";
+  ctx.lineJoin = "miter";
+  ctx.miterLimit = 4;
+  ctx.setLineDash([1, 2]);
+  ctx.lineDashOffset = 0;
+  ctx.stroke();
+  // fill url(#grad) not supported
+  // <image> not supported
+  ctx.restore();
+}
+`, string(js))
+
+	js, err = MarshalCanvas(mustUnmarshal(t, `<svg xmlns="http://www.w3.org/2000/svg"/>`), CanvasOptions{})
+	if assert.NoError(err) {
+		assert.Equal("function draw(ctx) {\n  ctx.save();\n  ctx.restore();\n}\n", string(js))
+	}
+}
@@ -23,6 +23,8 @@ type Circle struct {
 	Radius float64
 }
 
+var _ Shape = (*Circle)(nil)
+
 func init() {
 	RegisterNodeCreator("circle", func() Node { return createCircle() })
 }
@@ -42,6 +44,19 @@ func NewCircle(c geom.Coord, r float64) *Circle {
 	return n
 }
 
+// ToSubPaths returns the circle as a closed subpath made of two half circle
+// arcs starting at the rightmost point.
+func (c *Circle) ToSubPaths() []SubPath {
+	r := c.Radius
+	cx, cy := c.Center.X, c.Center.Y
+	return BuildSubPaths([]PathCommand{
+		{Command: 'M', Params: []float64{cx + r, cy}},
+		{Command: 'A', Params: []float64{r, r, 0, 0, 1, cx - r, cy}},
+		{Command: 'A', Params: []float64{r, r, 0, 0, 1, cx + r, cy}},
+		{Command: 'Z'},
+	})
+}
+
 func (c *Circle) marshalAttrs(am AttrMap) {
 	am["cx"] = floatToString(c.Center.X)
 	am["cy"] = floatToString(c.Center.Y)
@@ -0,0 +1,43 @@
+// Copyright 2018 Joe Beda
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package svgdata
+
+import (
+	"image/color"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseColor(t *testing.T) {
+	assert := assert.New(t)
+
+	for s, expected := range map[string]color.RGBA{
+		"#ff8000":           {0xff, 0x80, 0x00, 0xff},
+		"#F80":              {0xff, 0x88, 0x00, 0xff},
+		"Red":               {0xff, 0x00, 0x00, 0xff},
+		" rgb(0, 128, 255)": {0x00, 0x80, 0xff, 0xff},
+		"rgb(100%,50%,0%)":  {0xff, 0x80, 0x00, 0xff},
+	} {
+		c, ok := parseColor(s)
+		assert.True(ok, s)
+		assert.Equal(expected, c, s)
+	}
+
+	for _, s := range []string{"none", "url(#grad)", "#12345", "#ggg", "rgb(1,2)", "chartreuse"} {
+		_, ok := parseColor(s)
+		assert.False(ok, s)
+	}
+}
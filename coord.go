@@ -0,0 +1,104 @@
+// Copyright 2018 Joe Beda
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package svgdata
+
+import (
+	"math"
+
+	"github.com/jbeda/geom"
+)
+
+// geomEpsilon is the tolerance used to decide that two computed values are the
+// same when no user tolerance applies.
+const geomEpsilon = 1e-9
+
+// Small vector helpers for geom.Coord.  These are kept local so that the
+// geometry code reads the same everywhere.
+
+func coordAdd(a, b geom.Coord) geom.Coord {
+	return geom.Coord{X: a.X + b.X, Y: a.Y + b.Y}
+}
+
+func coordSub(a, b geom.Coord) geom.Coord {
+	return geom.Coord{X: a.X - b.X, Y: a.Y - b.Y}
+}
+
+func coordScale(a geom.Coord, s float64) geom.Coord {
+	return geom.Coord{X: a.X * s, Y: a.Y * s}
+}
+
+func coordDot(a, b geom.Coord) float64 {
+	return a.X*b.X + a.Y*b.Y
+}
+
+// coordCross returns the z component of the cross product of a and b.
+func coordCross(a, b geom.Coord) float64 {
+	return a.X*b.Y - a.Y*b.X
+}
+
+func coordLen(a geom.Coord) float64 {
+	return math.Hypot(a.X, a.Y)
+}
+
+func coordDist(a, b geom.Coord) float64 {
+	return math.Hypot(a.X-b.X, a.Y-b.Y)
+}
+
+func coordLerp(a, b geom.Coord, t float64) geom.Coord {
+	return geom.Coord{X: a.X + (b.X-a.X)*t, Y: a.Y + (b.Y-a.Y)*t}
+}
+
+// coordUnit returns a normalized copy of a.  A zero vector is returned as is.
+func coordUnit(a geom.Coord) geom.Coord {
+	l := coordLen(a)
+	if l == 0 {
+		return a
+	}
+	return geom.Coord{X: a.X / l, Y: a.Y / l}
+}
+
+// coordPerp returns a rotated 90 degrees counter-clockwise (in a y-up system).
+func coordPerp(a geom.Coord) geom.Coord {
+	return geom.Coord{X: -a.Y, Y: a.X}
+}
+
+// coordNear returns true if a and b are within tol of each other.
+func coordNear(a, b geom.Coord, tol float64) bool {
+	return coordDist(a, b) <= tol
+}
+
+// pointSegmentDist returns the distance from p to the line segment a-b along
+// with the parameter of the closest point on the segment.
+func pointSegmentDist(p, a, b geom.Coord) (float64, float64) {
+	d := coordSub(b, a)
+	l2 := coordDot(d, d)
+	if l2 == 0 {
+		return coordDist(p, a), 0
+	}
+	t := coordDot(coordSub(p, a), d) / l2
+	t = math.Max(0, math.Min(1, t))
+	return coordDist(p, coordLerp(a, b, t)), t
+}
+
+// pointLineDist returns the distance from p to the infinite line through a
+// and b.  If a and b are the same point the distance to a is returned.
+func pointLineDist(p, a, b geom.Coord) float64 {
+	d := coordSub(b, a)
+	l := coordLen(d)
+	if l == 0 {
+		return coordDist(p, a)
+	}
+	return math.Abs(coordCross(d, coordSub(p, a))) / l
+}
@@ -0,0 +1,437 @@
+// Copyright 2018 Joe Beda
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package svgdata
+
+import (
+	"math"
+
+	"github.com/jbeda/geom"
+)
+
+// maxTwoOptPasses bounds the number of improvement passes over the order.
+const maxTwoOptPasses = 50
+
+// CutOrderOptions control how the order of cuts is optimized.
+type CutOrderOptions struct {
+	// Start is where the tool is before the first cut.
+	Start geom.Coord
+
+	// Tolerance is used to flatten curves when deciding which contours lie
+	// inside others.
+	Tolerance float64
+}
+
+// OrderSubPaths reorders sps to reduce the distance travelled between the end
+// of one subpath and the start of the next.  Open subpaths may be reversed and
+// closed subpaths may start at any of their vertices, but keep their
+// direction.  Anything inside a closed subpath is cut before it so that parts
+// are not cut free before the features within them.  The order is built by
+// repeatedly going to the nearest subpath that is ready to cut and is then
+// improved with 2-opt.
+func OrderSubPaths(sps []SubPath, opts CutOrderOptions) []SubPath {
+	items := make([]*cutItem, 0, len(sps))
+	for _, sp := range sps {
+		if it := newCutItem(sp, IdentityTransform); it != nil {
+			items = append(items, it)
+		}
+	}
+	var r []SubPath
+	for _, it := range orderCutItems(items, opts) {
+		r = append(r, it.subPath())
+	}
+	return r
+}
+
+// TravelDistance returns the total distance between the end of each subpath
+// and the start of the next, starting from start.
+func TravelDistance(sps []SubPath, start geom.Coord) float64 {
+	d := 0.0
+	p := start
+	for _, sp := range sps {
+		if len(sp.Commands) == 0 {
+			continue
+		}
+		d += coordDist(p, sp.Start())
+		p = sp.End()
+		if sp.IsClosed() {
+			p = sp.Start()
+		}
+	}
+	return d
+}
+
+// OptimizeCutOrder reorders the shapes that are children of n, which is
+// usually the root or a layer group, as described for OrderSubPaths.  The
+// subpaths of all of the shapes are ordered together, so a shape whose
+// subpaths end up apart is split into several paths that share its
+// attributes, with only the first keeping its id.  Shapes that are not
+// changed are kept as they are.  The shapes are placed where the first of
+// them was and other children keep their order.
+func (r *Root) OptimizeCutOrder(n Node, opts CutOrderOptions) error {
+	var shapes []Shape
+	var items []*cutItem
+	first := -1
+	for i, c := range *n.Children() {
+		s, ok := c.(Shape)
+		if !ok {
+			continue
+		}
+		if first < 0 {
+			first = i
+		}
+		t, err := NodeTransform(c)
+		if err != nil {
+			return err
+		}
+		for _, sp := range s.ToSubPaths() {
+			if it := newCutItem(sp, t); it != nil {
+				it.owner = len(shapes)
+				items = append(items, it)
+			}
+		}
+		shapes = append(shapes, s)
+	}
+	if first < 0 {
+		return nil
+	}
+
+	// Split the order into runs that belong to the same shape.
+	type run struct {
+		owner int
+		items []*cutItem
+	}
+	var runs []run
+	for _, it := range orderCutItems(items, opts) {
+		if l := len(runs); l > 0 && runs[l-1].owner == it.owner {
+			runs[l-1].items = append(runs[l-1].items, it)
+			continue
+		}
+		runs = append(runs, run{it.owner, []*cutItem{it}})
+	}
+
+	// A shape is kept if it is in one run with its subpaths unchanged.
+	runCount := make([]int, len(shapes))
+	for _, rn := range runs {
+		runCount[rn.owner]++
+	}
+	unchanged := func(rn run) bool {
+		if runCount[rn.owner] != 1 {
+			return false
+		}
+		for i, it := range rn.items {
+			if it.reversed || it.startVertex != 0 || (i > 0 && it.index < rn.items[i-1].index) {
+				return false
+			}
+		}
+		return true
+	}
+
+	var ordered []Node
+	used := make([]bool, len(shapes))
+	for _, rn := range runs {
+		s := shapes[rn.owner]
+		if unchanged(rn) {
+			ordered = append(ordered, s)
+			continue
+		}
+		var sps []SubPath
+		for _, it := range rn.items {
+			sps = append(sps, it.subPath())
+		}
+		p := newPathFromNode(s, sps)
+		if used[rn.owner] {
+			delete(p.attrs, "id")
+		}
+		used[rn.owner] = true
+		ordered = append(ordered, p)
+	}
+
+	// Shapes without any geometry are kept at the end of the run.
+	for i, s := range shapes {
+		if runCount[i] == 0 {
+			ordered = append(ordered, s)
+		}
+	}
+
+	children := n.Children()
+	var rest []Node
+	for i, c := range *children {
+		if i == first {
+			rest = append(rest, ordered...)
+		}
+		if _, ok := c.(Shape); !ok {
+			rest = append(rest, c)
+		}
+	}
+	*children = rest
+	return nil
+}
+
+// cutItem is a subpath being ordered.  Positions are in a common space while
+// the segments are kept in the subpath's own space for output.
+type cutItem struct {
+	segs   []segment // In the subpath's own space
+	tsegs  []segment // In the common space
+	verts  []geom.Coord
+	closed bool
+	owner  int // Index of the shape the subpath belongs to
+	index  int // Position in the input
+
+	flat   Polyline   // For containment tests
+	before []*cutItem // Items that must be cut first
+
+	reversed    bool
+	startVertex int
+}
+
+func newCutItem(sp SubPath, t Transform) *cutItem {
+	segs := subPathSegments(sp)
+	if len(segs) == 0 {
+		return nil
+	}
+	it := &cutItem{segs: segs, closed: sp.IsClosed()}
+	for _, s := range segs {
+		ts := s.transform(t)
+		it.tsegs = append(it.tsegs, ts)
+		it.verts = append(it.verts, ts.start)
+	}
+	it.verts = append(it.verts, it.tsegs[len(segs)-1].end)
+	return it
+}
+
+// entry returns where cutting the item starts.
+func (it *cutItem) entry() geom.Coord {
+	switch {
+	case it.closed:
+		return it.verts[it.startVertex]
+	case it.reversed:
+		return it.verts[len(it.verts)-1]
+	}
+	return it.verts[0]
+}
+
+// exit returns where cutting the item ends.
+func (it *cutItem) exit() geom.Coord {
+	switch {
+	case it.closed:
+		return it.verts[it.startVertex]
+	case it.reversed:
+		return it.verts[0]
+	}
+	return it.verts[len(it.verts)-1]
+}
+
+// enterFrom picks the start of the item nearest to p.
+func (it *cutItem) enterFrom(p geom.Coord) {
+	if !it.closed {
+		it.reversed = coordDist(p, it.verts[len(it.verts)-1]) < coordDist(p, it.verts[0])
+		return
+	}
+	best := math.Inf(1)
+	for i, v := range it.verts[:len(it.verts)-1] {
+		if d := coordDist(p, v); d < best {
+			best, it.startVertex = d, i
+		}
+	}
+}
+
+// subPath returns the item as a subpath in its own space, starting and
+// running as chosen.
+func (it *cutItem) subPath() SubPath {
+	segs := it.segs
+	switch {
+	case it.closed:
+		segs = append(append([]segment{}, segs[it.startVertex:]...), segs[:it.startVertex]...)
+	case it.reversed:
+		segs = reverseSegments(segs)
+	}
+	return BuildSubPaths(segmentsToCommands(segs, it.closed))[0]
+}
+
+// orderCutItems returns the items in the order they should be cut, with their
+// direction and start chosen.
+func orderCutItems(items []*cutItem, opts CutOrderOptions) []*cutItem {
+	if len(items) == 0 {
+		return nil
+	}
+	tol := math.Max(opts.Tolerance, 0.01)
+	for i, it := range items {
+		it.index = i
+		sp := BuildSubPaths(segmentsToCommands(it.tsegs, it.closed))[0]
+		it.flat = sp.Flatten(tol)
+	}
+	cutConstraints(items, tol)
+
+	// Nearest neighbour, only considering items whose contents are done.
+	done := make(map[*cutItem]bool, len(items))
+	order := make([]*cutItem, 0, len(items))
+	p := opts.Start
+	for len(order) < len(items) {
+		var best *cutItem
+		bestD := math.Inf(1)
+		for _, it := range items {
+			if done[it] || !cutReady(it, done) {
+				continue
+			}
+			it.enterFrom(p)
+			if d := coordDist(p, it.entry()); d < bestD {
+				best, bestD = it, d
+			}
+		}
+		best.enterFrom(p)
+		done[best] = true
+		order = append(order, best)
+		p = best.exit()
+	}
+
+	twoOpt(order, opts.Start)
+
+	// Now that the neighbours are settled pick the best start for each
+	// closed item.
+	p = opts.Start
+	for i, it := range order {
+		if it.closed {
+			next := geom.Coord{}
+			hasNext := i+1 < len(order)
+			if hasNext {
+				next = order[i+1].entry()
+			}
+			best := math.Inf(1)
+			for vi, v := range it.verts[:len(it.verts)-1] {
+				d := coordDist(p, v)
+				if hasNext {
+					d += coordDist(v, next)
+				}
+				if d < best {
+					best, it.startVertex = d, vi
+				}
+			}
+		}
+		p = it.exit()
+	}
+	return order
+}
+
+// cutConstraints records which items lie inside each closed item.  Only the
+// innermost closed item containing each item is recorded as the others follow
+// from it.
+func cutConstraints(items []*cutItem, tol float64) {
+	var closed []*cutItem
+	for _, it := range items {
+		if it.closed {
+			closed = append(closed, it)
+		}
+	}
+	for _, it := range items {
+		var container *cutItem
+		area := math.Inf(1)
+		for _, c := range closed {
+			if c == it {
+				continue
+			}
+			a := math.Abs(polylineArea(c.flat.Points))
+			if a < area && (!it.closed || math.Abs(polylineArea(it.flat.Points)) < a) &&
+				polylineInside(it.flat, c.flat, tol) {
+				container, area = c, a
+			}
+		}
+		if container != nil {
+			container.before = append(container.before, it)
+		}
+	}
+}
+
+// polylineArea returns the signed area enclosed by pts.
+func polylineArea(pts []geom.Coord) float64 {
+	a := 0.0
+	for i := range pts {
+		a += coordCross(pts[i], pts[(i+1)%len(pts)])
+	}
+	return a / 2
+}
+
+func cutReady(it *cutItem, done map[*cutItem]bool) bool {
+	for _, b := range it.before {
+		if !done[b] {
+			return false
+		}
+	}
+	return true
+}
+
+// twoOpt improves the order by reversing runs of items where that shortens
+// the travel and keeps every item after its contents.  Reversing a run
+// reverses the open items in it.  Closed items start and end at the same
+// point so they are unaffected.
+func twoOpt(order []*cutItem, start geom.Coord) {
+	n := len(order)
+	pos := make(map[*cutItem]int, n)
+	exitBefore := func(i int) geom.Coord {
+		if i == 0 {
+			return start
+		}
+		return order[i-1].exit()
+	}
+
+	for pass := 0; pass < maxTwoOptPasses; pass++ {
+		for i, it := range order {
+			pos[it] = i
+		}
+		improved := false
+		for i := 0; i < n-1; i++ {
+			for j := i + 1; j < n; j++ {
+				a := exitBefore(i)
+				// After reversing, the run is entered at the old exit of
+				// order[j] and left at the old entry of order[i].
+				oldD := coordDist(a, order[i].entry())
+				newD := coordDist(a, order[j].exit())
+				if j+1 < n {
+					b := order[j+1].entry()
+					oldD += coordDist(order[j].exit(), b)
+					newD += coordDist(order[i].entry(), b)
+				}
+				if newD >= oldD-geomEpsilon || !canReverse(order, pos, i, j) {
+					continue
+				}
+				for l, r := i, j; l < r; l, r = l+1, r-1 {
+					order[l], order[r] = order[r], order[l]
+				}
+				for k := i; k <= j; k++ {
+					if !order[k].closed {
+						order[k].reversed = !order[k].reversed
+					}
+					pos[order[k]] = k
+				}
+				improved = true
+			}
+		}
+		if !improved {
+			return
+		}
+	}
+}
+
+// canReverse returns true if reversing order[i:j+1] keeps every item after
+// its contents.
+func canReverse(order []*cutItem, pos map[*cutItem]int, i, j int) bool {
+	for k := i; k <= j; k++ {
+		for _, b := range order[k].before {
+			if p := pos[b]; p >= i && p <= j {
+				return false
+			}
+		}
+	}
+	return true
+}
@@ -0,0 +1,119 @@
+// Copyright 2018 Joe Beda
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package svgdata
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+
+	"github.com/jbeda/geom"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOrderSubPaths(t *testing.T) {
+	assert := assert.New(t)
+
+	sps := OrderSubPaths(mustParsePath(t, "M10,0 L11,0 M0,0 L1,0 M5,0 L6,0"), CutOrderOptions{})
+	assert.Equal("M0 0L1 0M5 0L6 0M10 0L11 0", SavePathString(sps))
+
+	// Open subpaths are reversed when that is shorter.
+	sps = OrderSubPaths(mustParsePath(t, "M0,0 L1,0 M6,0 L2,0"), CutOrderOptions{})
+	assert.Equal("M0 0L1 0M2 0L6 0", SavePathString(sps))
+
+	// Closed subpaths start at the closest vertex and keep their direction.
+	sps = OrderSubPaths(mustParsePath(t, "M10,10 h10 v10 h-10 z"), CutOrderOptions{Start: geom.Coord{X: 20, Y: 20}})
+	assert.Equal("M20 20L10 20L10 10L20 10Z", SavePathString(sps))
+}
+
+func TestOrderSubPathsContainment(t *testing.T) {
+	assert := assert.New(t)
+
+	d := "M0,0 h100 v100 h-100 z M40,40 h20 v20 h-20 z M45,50 L55,50 M110,0 L120,0"
+	sps := OrderSubPaths(mustParsePath(t, d), CutOrderOptions{})
+	if assert.Len(sps, 4) {
+		// The outer square is nearest the start but has to wait for the
+		// square and line inside it.  It starts at the corner nearest to
+		// the last line.
+		assert.Equal("M100 0L100 100L0 100L0 0Z", SavePathString(sps[2:3]))
+		assert.Equal("M110 0L120 0", SavePathString(sps[3:]))
+		assert.True(sps[2].IsClosed())
+	}
+
+	// Circles are handled through their flattened outline.
+	inner := NewCircle(geom.Coord{X: 50, Y: 50}, 5).ToSubPaths()
+	sps = OrderSubPaths(append(mustParsePath(t, "M0,0 h100 v100 h-100 z"), inner...), CutOrderOptions{})
+	if assert.Len(sps, 2) {
+		assert.Equal(2, countCommands(sps[:1], 'A'))
+	}
+}
+
+func TestOrderSubPathsRandom(t *testing.T) {
+	assert := assert.New(t)
+
+	rng := rand.New(rand.NewSource(1))
+	var d string
+	for i := 0; i < 60; i++ {
+		d += fmt.Sprintf("M%f,%f l%f,%f ", rng.Float64()*100, rng.Float64()*100, rng.Float64()*4-2, rng.Float64()*4-2)
+	}
+	sps := mustParsePath(t, d)
+	ordered := OrderSubPaths(sps, CutOrderOptions{})
+	assert.Len(ordered, len(sps))
+	assert.InDelta(subPathsLength(sps), subPathsLength(ordered), 1e-6)
+	assert.True(TravelDistance(ordered, geom.Coord{}) < TravelDistance(sps, geom.Coord{})/3)
+
+	// No single reversal improves the result.
+	improved := false
+	for i := 0; i < len(ordered); i++ {
+		for j := i + 1; j < len(ordered); j++ {
+			trial := append([]SubPath{}, ordered[:i]...)
+			for k := j; k >= i; k-- {
+				trial = append(trial, ordered[k].Reverse())
+			}
+			trial = append(trial, ordered[j+1:]...)
+			if TravelDistance(trial, geom.Coord{}) < TravelDistance(ordered, geom.Coord{})-1e-9 {
+				improved = true
+			}
+		}
+	}
+	assert.False(improved)
+}
+
+func TestRootOptimizeCutOrder(t *testing.T) {
+	assert := assert.New(t)
+
+	r, err := Unmarshal([]byte(`<svg xmlns="http://www.w3.org/2000/svg">
+  <g id="layer">
+    <rect id="outer" x="0" y="0" width="100" height="100"/>
+    <path id="lines" d="M200,0 L210,0 M1,1 L2,2" stroke="red"/>
+    <text>label</text>
+    <circle id="hole" cx="50" cy="50" r="10" transform="translate(-40,-40)"/>
+  </g>
+</svg>`))
+	assert.NoError(err)
+	layer := FindByID(r, "layer")
+	assert.NoError(r.OptimizeCutOrder(layer, CutOrderOptions{}))
+
+	var ids []string
+	for _, c := range *layer.Children() {
+		ids = append(ids, c.Name()+"#"+c.Attrs()["id"])
+	}
+	assert.Equal([]string{"path#lines", "path#hole", "path#outer", "path#", "text#"}, ids)
+
+	children := *layer.Children()
+	assert.Equal("M1 1L2 2", SavePathString(children[0].(*Path).SubPaths))
+	assert.Equal("M200 0L210 0", SavePathString(children[3].(*Path).SubPaths))
+	assert.Equal("red", children[3].Attrs()["stroke"])
+}
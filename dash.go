@@ -0,0 +1,205 @@
+// Copyright 2018 Joe Beda
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package svgdata
+
+import "math"
+
+// dashProperties are removed from the document once dashes are applied.
+var dashProperties = []string{"stroke-dasharray", "stroke-dashoffset"}
+
+// DashSubPaths splits each subpath into the dashes that a stroke with the
+// given dash array and offset would draw.  Lengths are measured along curves
+// and arcs.  As in SVG, an odd number of lengths is repeated to make an even
+// number and the pattern restarts with each subpath.  A dash that runs through
+// the start of a closed subpath is kept as one piece.  Dashes of zero length
+// are dropped.  sps is returned unchanged if the dash array is empty, has
+// negative lengths or sums to zero.
+func DashSubPaths(sps []SubPath, dashes []float64, offset float64) []SubPath {
+	period := 0.0
+	for _, d := range dashes {
+		if d < 0 {
+			return sps
+		}
+		period += d
+	}
+	if period <= 0 {
+		return sps
+	}
+	if len(dashes)%2 == 1 {
+		dashes = append(append([]float64(nil), dashes...), dashes...)
+		period *= 2
+	}
+
+	var cmds []PathCommand
+	for _, sp := range sps {
+		segs := subPathSegments(sp)
+		var lens []float64
+		total := 0.0
+		for _, s := range segs {
+			l := s.length()
+			lens = append(lens, l)
+			total += l
+		}
+		if total <= 0 {
+			continue
+		}
+
+		// Find where in the pattern the subpath starts.
+		pos := math.Mod(offset, period)
+		if pos < 0 {
+			pos += period
+		}
+		i := 0
+		for pos >= dashes[i] {
+			pos -= dashes[i]
+			i = (i + 1) % len(dashes)
+		}
+		rem := dashes[i] - pos
+
+		var ranges [][2]float64
+		for d := 0.0; d < total; {
+			next := math.Min(d+rem, total)
+			if i%2 == 0 && next > d {
+				ranges = append(ranges, [2]float64{d, next})
+			}
+			d = next
+			i = (i + 1) % len(dashes)
+			rem = dashes[i]
+		}
+
+		var dashSegs [][]segment
+		for _, r := range ranges {
+			dashSegs = append(dashSegs, segmentsInRange(segs, lens, r[0], r[1]))
+		}
+		if n := len(ranges); sp.IsClosed() && n > 1 && ranges[0][0] == 0 && ranges[n-1][1] == total {
+			dashSegs[0] = append(dashSegs[n-1], dashSegs[0]...)
+			dashSegs = dashSegs[:n-1]
+		}
+		for _, ds := range dashSegs {
+			cmds = append(cmds, segmentsToCommands(ds, false)...)
+		}
+	}
+	return BuildSubPaths(cmds)
+}
+
+// segmentsInRange returns the parts of segs between lengths d0 and d1 from
+// the start.  lens holds the length of each segment.
+func segmentsInRange(segs []segment, lens []float64, d0, d1 float64) []segment {
+	var r []segment
+	start := 0.0
+	for i, s := range segs {
+		end := start + lens[i]
+		a, b := math.Max(d0, start), math.Min(d1, end)
+		if b > a {
+			t0, t1 := 0.0, 1.0
+			if a > start {
+				t0 = s.paramAtLength(a - start)
+			}
+			if b < end {
+				t1 = s.paramAtLength(b - start)
+			}
+			if t1 > t0 {
+				r = append(r, s.sub(t0, t1))
+			}
+		}
+		start = end
+	}
+	return r
+}
+
+// ApplyDashes replaces every rendered, stroked shape in the document that has
+// a stroke-dasharray with a path made up of the individual dashes.  A
+// pathLength attribute scales the dash lengths as it does when rendering.
+// Afterwards stroke-dasharray and stroke-dashoffset are removed from every
+// node so nothing is dashed twice.  Fills are left alone and will no longer
+// match the original for shapes that were dashed.
+func (r *Root) ApplyDashes() error {
+	replacements := map[Node]Node{}
+	err := walkPaint(r, IdentityTransform, NodeStyle(r), func(n Node, ctm Transform, st Style) error {
+		s, ok := n.(Shape)
+		if !ok || !st.HasStroke() {
+			return nil
+		}
+		sps, dashed := dashShape(s, st)
+		if !dashed {
+			return nil
+		}
+
+		p := newPathFromNode(n, sps)
+		delete(p.attrs, "pathLength")
+		replacements[n] = p
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	replaceShapes(r, func(s Shape) Node {
+		if n, ok := replacements[s]; ok {
+			return n
+		}
+		return s
+	})
+	Walk(r, func(n Node) bool {
+		for _, k := range dashProperties {
+			n.Attrs().DeleteStyle(k)
+		}
+		return true
+	})
+	return nil
+}
+
+// dashShape returns the subpaths of s split into the dashes given by st.
+// false is returned if the stroke isn't dashed.
+func dashShape(s Shape, st Style) ([]SubPath, bool) {
+	dashes, offset := dashPattern(s, st)
+	if dashes == nil {
+		return nil, false
+	}
+	return DashSubPaths(s.ToSubPaths(), dashes, offset), true
+}
+
+// dashPattern returns the dash array and offset for stroking s with st.  A
+// pathLength attribute on s scales the lengths.  The dash array is nil if the
+// stroke is solid, including when the lengths are negative or sum to zero.
+// An invalid pathLength is ignored.
+func dashPattern(s Shape, st Style) ([]float64, float64) {
+	dashes := st.DashArray()
+	if len(dashes) == 0 {
+		return nil, 0
+	}
+	offset := st.DashOffset()
+	period := 0.0
+	for _, d := range dashes {
+		if d < 0 {
+			return nil, 0
+		}
+		period += d
+	}
+	if period <= 0 {
+		return nil, 0
+	}
+
+	if pl, ok := s.Attrs()["pathLength"]; ok {
+		if author, err := parseValue(pl); err == nil && author > 0 {
+			scale := subPathsLength(s.ToSubPaths()) / author
+			for i := range dashes {
+				dashes[i] *= scale
+			}
+			offset *= scale
+		}
+	}
+	return dashes, offset
+}
@@ -0,0 +1,152 @@
+// Copyright 2018 Joe Beda
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package svgdata
+
+import (
+	"math"
+	"testing"
+
+	"github.com/jbeda/geom"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDashLines(t *testing.T) {
+	assert := assert.New(t)
+
+	line := mustParsePath(t, "M0,0 L10,0")
+	assert.Equal("M0 0L2 0M3 0L5 0M6 0L8 0M9 0L10 0", SavePathString(DashSubPaths(line, []float64{2, 1}, 0)))
+	assert.Equal("M0 0L1 0M2 0L4 0M5 0L7 0M8 0L10 0", SavePathString(DashSubPaths(line, []float64{2, 1}, 1)))
+	assert.Equal("M1 0L3 0M4 0L6 0M7 0L9 0", SavePathString(DashSubPaths(line, []float64{2, 1}, -1)))
+
+	// An odd number of lengths is repeated.
+	assert.Equal(
+		SavePathString(DashSubPaths(line, []float64{3, 1, 2, 3, 1, 2}, 0)),
+		SavePathString(DashSubPaths(line, []float64{3, 1, 2}, 0)))
+
+	// Invalid patterns leave the path solid.
+	assert.Equal(line, DashSubPaths(line, []float64{2, -1}, 0))
+	assert.Equal(line, DashSubPaths(line, []float64{0, 0}, 0))
+	assert.Equal(line, DashSubPaths(line, nil, 0))
+
+	// Dashes continue around corners.
+	sps := DashSubPaths(mustParsePath(t, "M0,0 L4,0 L4,4"), []float64{3, 2}, 0)
+	assert.Equal("M0 0L3 0M4 1L4 4", SavePathString(sps))
+	sps = DashSubPaths(mustParsePath(t, "M0,0 L4,0 L4,4"), []float64{5, 1}, 0)
+	assert.Equal("M0 0L4 0L4 1M4 2L4 4", SavePathString(sps))
+}
+
+func TestDashClosed(t *testing.T) {
+	assert := assert.New(t)
+
+	square := mustParsePath(t, "M0,0 h10 v10 h-10 z")
+	sps := DashSubPaths(square, []float64{5, 5}, 0)
+	assert.Len(sps, 4)
+	for _, sp := range sps {
+		assert.False(sp.IsClosed())
+		assert.InDelta(5, sp.Length(), 1e-9)
+	}
+
+	// The dash through the start is joined up.
+	sps = DashSubPaths(square, []float64{5, 5}, 2)
+	if assert.Len(sps, 4) {
+		assert.Equal("M0 2L0 0L3 0", SavePathString(sps[:1]))
+	}
+}
+
+func TestDashCurves(t *testing.T) {
+	assert := assert.New(t)
+
+	circle := NewCircle(geom.Coord{X: 0, Y: 0}, 10).ToSubPaths()
+	circumference := 20 * math.Pi
+	dash := circumference / 12
+	sps := DashSubPaths(circle, []float64{dash}, 0)
+	assert.Len(sps, 6)
+	for _, sp := range sps {
+		assert.InDelta(dash, sp.Length(), 1e-6)
+		mid := sp.PointAtLength(dash / 2)
+		assert.InDelta(10, coordLen(mid), 1e-9)
+	}
+
+	cubic := mustParsePath(t, "M0,0 C0,10 10,10 10,0")
+	l := cubic[0].Length()
+	sps = DashSubPaths(cubic, []float64{l / 4}, 0)
+	if assert.Len(sps, 2) {
+		assert.InDelta(l/4, sps[0].Length(), 1e-6)
+		assertCoordNear(assert, cubic[0].PointAtLength(l/2), sps[1].Start(), 1e-6)
+	}
+}
+
+func TestApplyDashes(t *testing.T) {
+	assert := assert.New(t)
+
+	r, err := Unmarshal([]byte(`<svg xmlns="http://www.w3.org/2000/svg">
+  <path id="a" d="M0,0 L10,0" stroke="black" stroke-dasharray="2,1"/>
+  <g style="stroke: black; stroke-dasharray: 4 4">
+    <path id="b" d="M0,0 L10,0" style="stroke-dashoffset: 4"/>
+    <path id="c" d="M0,0 L10,0" pathLength="20"/>
+    <path id="d" d="M0,0 L10,0" stroke="none"/>
+  </g>
+  <path id="e" d="M0,0 L10,0" stroke="black" stroke-dasharray="none"/>
+</svg>`))
+	assert.NoError(err)
+	assert.NoError(r.ApplyDashes())
+
+	expected := map[string]string{
+		"a": "M0 0L2 0M3 0L5 0M6 0L8 0M9 0L10 0",
+		"b": "M4 0L8 0",
+		"c": "M0 0L2 0M4 0L6 0M8 0L10 0",
+		"d": "M0 0L10 0",
+		"e": "M0 0L10 0",
+	}
+	for id, d := range expected {
+		p := FindByID(r, id).(*Path)
+		assert.Equal(d, SavePathString(p.SubPaths), id)
+		_, ok := p.Attrs().GetStyle("stroke-dasharray")
+		assert.False(ok, id)
+		_, ok = p.Attrs().GetStyle("stroke-dashoffset")
+		assert.False(ok, id)
+	}
+	_, ok := FindByID(r, "c").Attrs()["pathLength"]
+	assert.False(ok)
+	g := (*r.Children())[1]
+	assert.Equal("stroke: black", g.Attrs()["style"])
+
+	// Dash arrays that can't be parsed leave the stroke solid.
+	r, err = Unmarshal([]byte(`<svg xmlns="http://www.w3.org/2000/svg">
+  <path id="x" d="M0,0 L10,0" stroke="black" stroke-dasharray="2,x"/>
+  <path id="pct" d="M0,0 L10,0" stroke="black" stroke-dasharray="5%" stroke-dashoffset="1%"/>
+  <path id="inherit" d="M0,0 L10,0" stroke="black" stroke-dasharray="inherit"/>
+</svg>`))
+	assert.NoError(err)
+	assert.NoError(r.ApplyDashes())
+	for _, id := range []string{"x", "pct", "inherit"} {
+		assert.Equal("M0 0L10 0", SavePathString(FindByID(r, id).(*Path).SubPaths), id)
+	}
+
+	// Nor do they stop the document from being exported.
+	r = mustUnmarshal(t, `<svg xmlns="http://www.w3.org/2000/svg" width="10" height="10">
+  <path d="M0,0 L10,0" stroke="black" stroke-dasharray="calc(1px)"/>
+</svg>`)
+	_, err = Render(r, RenderOptions{})
+	assert.NoError(err)
+	_, err = MarshalPDF(r, PDFOptions{})
+	assert.NoError(err)
+	_, err = MarshalEPS(r)
+	assert.NoError(err)
+	_, err = MarshalCanvas(r, CanvasOptions{})
+	assert.NoError(err)
+	_, _, err = MarshalVectorDrawable(r)
+	assert.NoError(err)
+}
@@ -0,0 +1,385 @@
+// Copyright 2018 Joe Beda
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package svgdata
+
+import (
+	"math"
+
+	"github.com/jbeda/geom"
+)
+
+// DedupSubPaths removes geometry that is drawn more than once.  Segments that
+// repeat an earlier segment, in either direction, are dropped and the parts
+// of lines and circular arcs that overlap an earlier collinear line or
+// co-circular arc are trimmed away.  The remaining segments are chained end
+// to end into subpaths and runs of collinear lines and co-circular arcs are
+// merged.  Points within tol are treated as equal.
+func DedupSubPaths(sps []SubPath, tol float64) []SubPath {
+	tol = math.Max(tol, minTolerance)
+	var owned []ownedSegment
+	for i, sp := range sps {
+		for _, s := range subPathSegments(sp) {
+			owned = append(owned, ownedSegment{s: s, subPath: i})
+		}
+	}
+	return chainSegments(dedupSegments(owned, tol), tol)
+}
+
+// Dedup removes duplicate and overlapping geometry from the path.  See
+// DedupSubPaths.
+func (p *Path) Dedup(tol float64) {
+	p.SubPaths = DedupSubPaths(p.SubPaths, tol)
+}
+
+// Dedup removes geometry that is drawn more than once across all of the
+// rendered shapes in the document, such as an edge shared by two adjacent
+// parts.  Transforms are taken into account.  The first shape in document
+// order keeps shared geometry.  Shapes that lose geometry are replaced by
+// paths and shapes that lose all of it are removed.
+func (r *Root) Dedup(tol float64) error {
+	tol = math.Max(tol, minTolerance)
+
+	type shapeInfo struct {
+		ctm   Transform
+		count int
+	}
+	var shapes []Shape
+	var infos []shapeInfo
+	var owned []ownedSegment
+	err := walkPaint(r, IdentityTransform, NodeStyle(r), func(n Node, ctm Transform, st Style) error {
+		s, ok := n.(Shape)
+		if !ok {
+			return nil
+		}
+		info := shapeInfo{ctm: ctm}
+		for i, sp := range s.ToSubPaths() {
+			for _, seg := range subPathSegments(sp) {
+				owned = append(owned, ownedSegment{s: seg.transform(ctm), owner: len(shapes), subPath: i})
+				info.count++
+			}
+		}
+		shapes = append(shapes, s)
+		infos = append(infos, info)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	kept := dedupSegments(owned, tol)
+	byOwner := make([][]ownedSegment, len(shapes))
+	changed := make([]bool, len(shapes))
+	for _, o := range kept {
+		byOwner[o.owner] = append(byOwner[o.owner], o)
+		if o.trimmed {
+			changed[o.owner] = true
+		}
+	}
+
+	replacements := map[Node]Node{}
+	for i, s := range shapes {
+		if !changed[i] && len(byOwner[i]) == infos[i].count {
+			continue
+		}
+		inv, ok := infos[i].ctm.Invert()
+		if !ok {
+			continue
+		}
+		segs := byOwner[i]
+		for j := range segs {
+			segs[j].s = segs[j].s.transform(inv)
+		}
+		if len(segs) == 0 {
+			replacements[s] = nil
+			continue
+		}
+		replacements[s] = newPathFromNode(s, chainSegments(segs, tol/math.Max(infos[i].ctm.Scale(), geomEpsilon)))
+	}
+
+	replaceShapes(r, func(s Shape) Node {
+		if n, ok := replacements[s]; ok {
+			return n
+		}
+		return s
+	})
+	return nil
+}
+
+// ownedSegment is a segment along with where it came from.
+type ownedSegment struct {
+	s       segment
+	owner   int // Index of the shape the segment belongs to
+	subPath int // Index of the subpath within the shape
+	trimmed bool
+}
+
+// dedupSegments returns the segments with every part that repeats an earlier
+// segment removed.
+func dedupSegments(segs []ownedSegment, tol float64) []ownedSegment {
+	var kept []ownedSegment
+	var bounds []geom.Rect
+	for _, o := range segs {
+		s := o.s
+		if segmentBoundsSize(s) <= tol {
+			continue
+		}
+		b := segmentBounds(s)
+
+		// The parameter ranges of s that are not yet covered.
+		free := [][2]float64{{0, 1}}
+		for i, k := range kept {
+			if len(free) == 0 {
+				break
+			}
+			if !rectsOverlap(b, bounds[i], tol) {
+				continue
+			}
+			for _, c := range coveredRanges(s, k.s, tol) {
+				free = subtractRange(free, c)
+			}
+		}
+
+		for _, f := range free {
+			if f == [2]float64{0, 1} {
+				kept = append(kept, o)
+				bounds = append(bounds, b)
+				continue
+			}
+			p := s.sub(f[0], f[1])
+			if segmentBoundsSize(p) <= tol {
+				continue
+			}
+			kept = append(kept, ownedSegment{p, o.owner, o.subPath, true})
+			bounds = append(bounds, segmentBounds(p))
+		}
+	}
+	return kept
+}
+
+// segmentBoundsSize returns the largest dimension of the segment's bounds.
+func segmentBoundsSize(s segment) float64 {
+	b := segmentBounds(s)
+	return math.Max(b.Width(), b.Height())
+}
+
+// coveredRanges returns the parameter ranges of s that k also draws.
+func coveredRanges(s, k segment, tol float64) [][2]float64 {
+	switch {
+	case s.kind == 'L' && k.kind == 'L':
+		if pointLineDist(k.start, s.start, s.end) > tol || pointLineDist(k.end, s.start, s.end) > tol {
+			return nil
+		}
+		d := coordSub(s.end, s.start)
+		l2 := coordDot(d, d)
+		t0 := coordDot(coordSub(k.start, s.start), d) / l2
+		t1 := coordDot(coordSub(k.end, s.start), d) / l2
+		return [][2]float64{{math.Min(t0, t1), math.Max(t0, t1)}}
+
+	case s.kind == 'A' && k.kind == 'A':
+		sc, ok1 := circularArc(s)
+		kc, ok2 := circularArc(k)
+		if ok1 && ok2 {
+			return sc.covered(kc, tol)
+		}
+	}
+
+	if coincident, _ := coincidentPieces(piece{s, 0, 1}, piece{k, 0, 1}, tol); coincident {
+		return [][2]float64{{0, 1}}
+	}
+	return nil
+}
+
+// circArc is a circular arc described by the range of angles it covers.
+type circArc struct {
+	center geom.Coord
+	radius float64
+	start  float64 // Angle of the start point
+	delta  float64 // Signed sweep
+}
+
+func circularArc(s segment) (circArc, bool) {
+	ea := s.ellipse()
+	if !ea.isCircular() {
+		return circArc{}, false
+	}
+	d := coordSub(s.start, ea.center)
+	return circArc{ea.center, ea.rx, math.Atan2(d.Y, d.X), ea.delta}, true
+}
+
+// covered returns the parameter ranges of a that k also covers.
+func (a circArc) covered(k circArc, tol float64) [][2]float64 {
+	if !coordNear(a.center, k.center, tol) || math.Abs(a.radius-k.radius) > tol {
+		return nil
+	}
+
+	// Work with increasing angles from the low end of each arc.
+	lo := func(c circArc) float64 { return math.Min(c.start, c.start+c.delta) }
+	sweep := math.Abs(a.delta)
+	off := math.Mod(lo(k)-lo(a), 2*math.Pi)
+	if off < 0 {
+		off += 2 * math.Pi
+	}
+
+	var r [][2]float64
+	for _, o := range []float64{off, off - 2*math.Pi} {
+		x0, x1 := math.Max(0, o), math.Min(sweep, o+math.Abs(k.delta))
+		if x1 <= x0 {
+			continue
+		}
+		t0, t1 := x0/sweep, x1/sweep
+		if a.delta < 0 {
+			t0, t1 = 1-t1, 1-t0
+		}
+		r = append(r, [2]float64{t0, t1})
+	}
+	return r
+}
+
+// subtractRange removes c from each of the ranges in free.
+func subtractRange(free [][2]float64, c [2]float64) [][2]float64 {
+	var r [][2]float64
+	for _, f := range free {
+		if c[1] <= f[0] || c[0] >= f[1] {
+			r = append(r, f)
+			continue
+		}
+		if c[0] > f[0] {
+			r = append(r, [2]float64{f[0], c[0]})
+		}
+		if c[1] < f[1] {
+			r = append(r, [2]float64{c[1], f[1]})
+		}
+	}
+	return r
+}
+
+// chainSegments joins segments that meet end to end into subpaths.
+// Segments keep their order within each original subpath and are reversed
+// where that lets two chains join.  Chains that end where they start are
+// closed.
+func chainSegments(segs []ownedSegment, tol float64) []SubPath {
+	// Split into runs that were already connected.
+	var chains [][]segment
+	for i, o := range segs {
+		if i > 0 && segs[i-1].owner == o.owner && segs[i-1].subPath == o.subPath &&
+			coordNear(segs[i-1].s.end, o.s.start, tol) {
+			chains[len(chains)-1] = append(chains[len(chains)-1], o.s)
+			continue
+		}
+		chains = append(chains, []segment{o.s})
+	}
+
+	isClosed := func(c []segment) bool {
+		return len(c) > 1 && coordNear(c[len(c)-1].end, c[0].start, tol)
+	}
+	for joined := true; joined; {
+		joined = false
+		for i := 0; i < len(chains) && !joined; i++ {
+			if isClosed(chains[i]) {
+				continue
+			}
+			for j := i + 1; j < len(chains); j++ {
+				if isClosed(chains[j]) {
+					continue
+				}
+				if c, ok := joinChains(chains[i], chains[j], tol); ok {
+					chains[i] = c
+					chains = append(chains[:j], chains[j+1:]...)
+					joined = true
+					break
+				}
+			}
+		}
+	}
+
+	var cmds []PathCommand
+	for _, c := range chains {
+		closed := isClosed(c)
+		c = mergeRuns(c, tol)
+		if closed {
+			c[len(c)-1].end = c[0].start
+		}
+		cmds = append(cmds, segmentsToCommands(c, closed)...)
+	}
+	return BuildSubPaths(cmds)
+}
+
+// joinChains returns a and b joined end to end, reversing one of them if
+// needed.
+func joinChains(a, b []segment, tol float64) ([]segment, bool) {
+	switch {
+	case coordNear(a[len(a)-1].end, b[0].start, tol):
+		return append(a, b...), true
+	case coordNear(b[len(b)-1].end, a[0].start, tol):
+		return append(b, a...), true
+	case coordNear(a[len(a)-1].end, b[len(b)-1].end, tol):
+		return append(a, reverseSegments(b)...), true
+	case coordNear(a[0].start, b[0].start, tol):
+		return append(reverseSegments(b), a...), true
+	}
+	return nil, false
+}
+
+func reverseSegments(segs []segment) []segment {
+	r := make([]segment, len(segs))
+	for i, s := range segs {
+		r[len(segs)-1-i] = s.reverse()
+	}
+	return r
+}
+
+// mergeRuns combines consecutive collinear lines and co-circular arcs that
+// continue in the same direction.
+func mergeRuns(segs []segment, tol float64) []segment {
+	r := []segment{segs[0]}
+	for _, s := range segs[1:] {
+		last := &r[len(r)-1]
+		if m, ok := mergeSegments(*last, s, tol); ok {
+			*last = m
+			continue
+		}
+		r = append(r, s)
+	}
+	return r
+}
+
+func mergeSegments(a, b segment, tol float64) (segment, bool) {
+	switch {
+	case a.kind == 'L' && b.kind == 'L':
+		if pointLineDist(a.end, a.start, b.end) > tol ||
+			coordDot(coordSub(a.end, a.start), coordSub(b.end, b.start)) <= 0 {
+			return segment{}, false
+		}
+		return segment{kind: 'L', start: a.start, end: b.end}, true
+
+	case a.kind == 'A' && b.kind == 'A':
+		ca, ok1 := circularArc(a)
+		cb, ok2 := circularArc(b)
+		if !ok1 || !ok2 || !coordNear(ca.center, cb.center, tol) || math.Abs(ca.radius-cb.radius) > tol ||
+			a.sweep != b.sweep {
+			return segment{}, false
+		}
+		total := math.Abs(ca.delta) + math.Abs(cb.delta)
+		if total >= 2*math.Pi-geomEpsilon {
+			return segment{}, false
+		}
+		return segment{
+			kind: 'A', start: a.start, end: b.end,
+			rx: ca.radius, ry: ca.radius,
+			largeArc: total > math.Pi, sweep: a.sweep,
+		}, true
+	}
+	return segment{}, false
+}
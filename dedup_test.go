@@ -0,0 +1,106 @@
+// Copyright 2018 Joe Beda
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package svgdata
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDedupSharedEdge(t *testing.T) {
+	assert := assert.New(t)
+
+	sps := DedupSubPaths(mustParsePath(t, "M0,0 h10 v10 h-10 z M10,0 h10 v10 h-10 z"), 1e-6)
+	if assert.Len(sps, 2) {
+		assert.True(sps[0].IsClosed())
+		assert.False(sps[1].IsClosed())
+	}
+	assert.InDelta(70, subPathsLength(sps), 1e-9)
+}
+
+func TestDedupLines(t *testing.T) {
+	assert := assert.New(t)
+
+	sps := DedupSubPaths(mustParsePath(t, "M0,0 L10,0 M5,0 L15,0"), 1e-6)
+	assert.Equal("M0 0L15 0", SavePathString(sps))
+
+	sps = DedupSubPaths(mustParsePath(t, "M0,0 L10,0 M10,0 L0,0"), 1e-6)
+	assert.Equal("M0 0L10 0", SavePathString(sps))
+
+	// A line inside another disappears.
+	sps = DedupSubPaths(mustParsePath(t, "M0,0 L10,0 M8,0 L2,0"), 1e-6)
+	assert.Equal("M0 0L10 0", SavePathString(sps))
+
+	// Lines that are close but not within the tolerance stay.
+	sps = DedupSubPaths(mustParsePath(t, "M0,0 L10,0 M0,0.1 L10,0.1"), 1e-6)
+	assert.Len(sps, 2)
+}
+
+func TestDedupCurves(t *testing.T) {
+	assert := assert.New(t)
+
+	sps := DedupSubPaths(mustParsePath(t, "M0,0 C0,10 10,10 10,0 M10,0 C10,10 0,10 0,0"), 1e-6)
+	assert.Equal("M0 0C0 10 10 10 10 0", SavePathString(sps))
+
+	// Different curves between the same points are both kept.
+	sps = DedupSubPaths(mustParsePath(t, "M0,0 C0,10 10,10 10,0 M10,0 C10,-10 0,-10 0,0"), 1e-6)
+	if assert.Len(sps, 1) {
+		assert.True(sps[0].IsClosed())
+	}
+}
+
+func TestDedupArcs(t *testing.T) {
+	assert := assert.New(t)
+
+	// Half of a circle drawn again.
+	sps := DedupSubPaths(mustParsePath(t, "M5,0 A5,5 0 0 1 -5,0 A5,5 0 0 1 5,0 Z M5,0 A5,5 0 0 1 -5,0"), 1e-6)
+	if assert.Len(sps, 1) {
+		assert.True(sps[0].IsClosed())
+	}
+	assert.InDelta(10*math.Pi, subPathsLength(sps), 1e-6)
+
+	// Two quarter circles that overlap by an eighth merge into one arc.
+	sps = DedupSubPaths(mustParsePath(t, "M10,0 A10,10 0 0 1 0,10 M7.0710678118654755,7.0710678118654755 A10,10 0 0 1 -10,0"), 1e-6)
+	if assert.Len(sps, 1) {
+		assert.Len(sps[0].Commands, 2)
+		assert.InDelta(10*math.Pi, sps[0].Length(), 1e-6)
+	}
+}
+
+func TestRootDedup(t *testing.T) {
+	assert := assert.New(t)
+
+	r, err := Unmarshal([]byte(`<svg xmlns="http://www.w3.org/2000/svg">
+  <rect id="a" x="0" y="0" width="10" height="10"/>
+  <g transform="translate(20,0)">
+    <rect id="b" x="-10" y="0" width="10" height="10" fill="red"/>
+    <rect id="c" x="-20" y="0" width="10" height="10"/>
+  </g>
+  <circle id="d" cx="50" cy="50" r="5"/>
+</svg>`))
+	assert.NoError(err)
+	assert.NoError(r.Dedup(1e-6))
+
+	b, ok := FindByID(r, "b").(*Path)
+	if assert.True(ok) {
+		assert.Equal("red", b.Attrs()["fill"])
+		assert.Equal("M-10 0L0 0L0 10L-10 10", SavePathString(b.SubPaths))
+	}
+	assert.Nil(FindByID(r, "c"))
+	assert.IsType(&Rect{}, FindByID(r, "a"))
+	assert.IsType(&Circle{}, FindByID(r, "d"))
+}
@@ -0,0 +1,700 @@
+// Copyright 2018 Joe Beda
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package svgdata
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/jbeda/geom"
+	"github.com/pkg/errors"
+)
+
+// dxfPair is a group code and its value, the basic unit of a DXF file.
+type dxfPair struct {
+	code  int
+	value string
+}
+
+// dxfEntity is an entity from the ENTITIES section with all of its pairs.
+// Vertices of an old style POLYLINE are collected as nested entities.
+type dxfEntity struct {
+	kind     string
+	pairs    []dxfPair
+	vertices []*dxfEntity
+}
+
+func (e *dxfEntity) str(code int, def string) string {
+	for _, p := range e.pairs {
+		if p.code == code {
+			return p.value
+		}
+	}
+	return def
+}
+
+// float returns the first value for code.  Values are checked when the file
+// is read so the error is ignored here.
+func (e *dxfEntity) float(code int, def float64) float64 {
+	for _, p := range e.pairs {
+		if p.code == code {
+			v, _ := strconv.ParseFloat(p.value, 64)
+			return v
+		}
+	}
+	return def
+}
+
+func (e *dxfEntity) int(code int, def int) int {
+	return int(e.float(code, float64(def)))
+}
+
+func (e *dxfEntity) point(code int) geom.Coord {
+	return geom.Coord{X: e.float(code, 0), Y: e.float(code+10, 0)}
+}
+
+// dxfFloatCode returns true for group codes that hold numbers.
+func dxfFloatCode(c int) bool {
+	return (c >= 10 && c <= 99) || (c >= 140 && c <= 147) || (c >= 160 && c <= 179) ||
+		(c >= 210 && c <= 239) || (c >= 270 && c <= 289) || (c >= 370 && c <= 389) ||
+		(c >= 400 && c <= 409) || (c >= 420 && c <= 427) || (c >= 1010 && c <= 1071)
+}
+
+func readDXFPairs(data []byte) ([]dxfPair, error) {
+	if bytes.HasPrefix(data, []byte("AutoCAD Binary DXF")) {
+		return nil, errors.New("binary DXF files are not supported")
+	}
+	lines := strings.Split(string(data), "\n")
+	var pairs []dxfPair
+	for i := 0; i+1 < len(lines); i += 2 {
+		cs := strings.TrimSpace(lines[i])
+		if cs == "" && i+2 >= len(lines) {
+			break
+		}
+		code, err := strconv.Atoi(cs)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid group code on line %d", i+1)
+		}
+		v := strings.TrimRight(lines[i+1], "\r")
+		if dxfFloatCode(code) {
+			v = strings.TrimSpace(v)
+			if _, err := strconv.ParseFloat(v, 64); err != nil {
+				return nil, errors.Wrapf(err, "invalid value for group code %d on line %d", code, i+2)
+			}
+		}
+		pairs = append(pairs, dxfPair{code: code, value: v})
+	}
+	return pairs, nil
+}
+
+// dxfLayer is an entry from the LAYER table.
+type dxfLayer struct {
+	name  string
+	color int
+	nodes []Node
+}
+
+// dxfUnits maps $INSUNITS values to an SVG unit and the number of those units
+// in one drawing unit.
+var dxfUnits = map[int]struct {
+	unit  string
+	scale float64
+}{
+	1:  {"in", 1},
+	2:  {"in", 12},
+	3:  {"in", 63360},
+	4:  {"mm", 1},
+	5:  {"cm", 1},
+	6:  {"mm", 1e3},
+	7:  {"mm", 1e6},
+	8:  {"in", 1e-6},
+	9:  {"in", 1e-3},
+	10: {"in", 36},
+	11: {"mm", 1e-7},
+	12: {"mm", 1e-6},
+	13: {"mm", 1e-3},
+	14: {"cm", 10},
+	15: {"mm", 1e4},
+	16: {"mm", 1e5},
+	17: {"mm", 1e12},
+	18: {"mm", 1.495978707e14},
+	19: {"mm", 9.4607304725808e18},
+	20: {"mm", 3.0856775814913673e19},
+	21: {"mm", 1.2e6 / 3937},
+}
+
+// dxfColors are the standard AutoCAD color index values.  Colors 1 to 9 are
+// named colors, 10 to 249 are 24 hues in five shades with a paler variant of
+// each, and 250 to 255 are grays.  Color 7 is drawn black or white depending
+// on the background and is black here.
+var dxfColors = map[int]string{
+	1: "#ff0000", 2: "#ffff00", 3: "#00ff00", 4: "#00ffff", 5: "#0000ff", 6: "#ff00ff", 7: "#000000", 8: "#808080", 9: "#c0c0c0",
+	10: "#ff0000", 11: "#ffaaaa", 12: "#bd0000", 13: "#bd7e7e", 14: "#810000", 15: "#815656", 16: "#680000", 17: "#684545", 18: "#4f0000", 19: "#4f3535",
+	20: "#ff3f00", 21: "#ffbfaa", 22: "#bd2e00", 23: "#bd8d7e", 24: "#811f00", 25: "#816056", 26: "#681900", 27: "#684e45", 28: "#4f1300", 29: "#4f3b35",
+	30: "#ff7f00", 31: "#ffd4aa", 32: "#bd5e00", 33: "#bd9d7e", 34: "#814000", 35: "#816b56", 36: "#683400", 37: "#685645", 38: "#4f2700", 39: "#4f4235",
+	40: "#ffbf00", 41: "#ffeaaa", 42: "#bd8d00", 43: "#bdad7e", 44: "#816000", 45: "#817656", 46: "#684e00", 47: "#685f45", 48: "#4f3b00", 49: "#4f4935",
+	50: "#ffff00", 51: "#ffffaa", 52: "#bdbd00", 53: "#bdbd7e", 54: "#818100", 55: "#818156", 56: "#686800", 57: "#686845", 58: "#4f4f00", 59: "#4f4f35",
+	60: "#bfff00", 61: "#eaffaa", 62: "#8dbd00", 63: "#adbd7e", 64: "#608100", 65: "#768156", 66: "#4e6800", 67: "#5f6845", 68: "#3b4f00", 69: "#494f35",
+	70: "#7fff00", 71: "#d4ffaa", 72: "#5ebd00", 73: "#9dbd7e", 74: "#408100", 75: "#6b8156", 76: "#346800", 77: "#566845", 78: "#274f00", 79: "#424f35",
+	80: "#3fff00", 81: "#bfffaa", 82: "#2ebd00", 83: "#8dbd7e", 84: "#1f8100", 85: "#608156", 86: "#196800", 87: "#4e6845", 88: "#134f00", 89: "#3b4f35",
+	90: "#00ff00", 91: "#aaffaa", 92: "#00bd00", 93: "#7ebd7e", 94: "#008100", 95: "#568156", 96: "#006800", 97: "#456845", 98: "#004f00", 99: "#354f35",
+	100: "#00ff3f", 101: "#aaffbf", 102: "#00bd2e", 103: "#7ebd8d", 104: "#00811f", 105: "#568160", 106: "#006819", 107: "#45684e", 108: "#004f13", 109: "#354f3b",
+	110: "#00ff7f", 111: "#aaffd4", 112: "#00bd5e", 113: "#7ebd9d", 114: "#008140", 115: "#56816b", 116: "#006834", 117: "#456856", 118: "#004f27", 119: "#354f42",
+	120: "#00ffbf", 121: "#aaffea", 122: "#00bd8d", 123: "#7ebdad", 124: "#008160", 125: "#568176", 126: "#00684e", 127: "#45685f", 128: "#004f3b", 129: "#354f49",
+	130: "#00ffff", 131: "#aaffff", 132: "#00bdbd", 133: "#7ebdbd", 134: "#008181", 135: "#568181", 136: "#006868", 137: "#456868", 138: "#004f4f", 139: "#354f4f",
+	140: "#00bfff", 141: "#aaeaff", 142: "#008dbd", 143: "#7eadbd", 144: "#006081", 145: "#567681", 146: "#004e68", 147: "#455f68", 148: "#003b4f", 149: "#35494f",
+	150: "#007fff", 151: "#aad4ff", 152: "#005ebd", 153: "#7e9dbd", 154: "#004081", 155: "#566b81", 156: "#003468", 157: "#455668", 158: "#00274f", 159: "#35424f",
+	160: "#003fff", 161: "#aabfff", 162: "#002ebd", 163: "#7e8dbd", 164: "#001f81", 165: "#566081", 166: "#001968", 167: "#454e68", 168: "#00134f", 169: "#353b4f",
+	170: "#0000ff", 171: "#aaaaff", 172: "#0000bd", 173: "#7e7ebd", 174: "#000081", 175: "#565681", 176: "#000068", 177: "#454568", 178: "#00004f", 179: "#35354f",
+	180: "#3f00ff", 181: "#bfaaff", 182: "#2e00bd", 183: "#8d7ebd", 184: "#1f0081", 185: "#605681", 186: "#190068", 187: "#4e4568", 188: "#13004f", 189: "#3b354f",
+	190: "#7f00ff", 191: "#d4aaff", 192: "#5e00bd", 193: "#9d7ebd", 194: "#400081", 195: "#6b5681", 196: "#340068", 197: "#564568", 198: "#27004f", 199: "#42354f",
+	200: "#bf00ff", 201: "#eaaaff", 202: "#8d00bd", 203: "#ad7ebd", 204: "#600081", 205: "#765681", 206: "#4e0068", 207: "#5f4568", 208: "#3b004f", 209: "#49354f",
+	210: "#ff00ff", 211: "#ffaaff", 212: "#bd00bd", 213: "#bd7ebd", 214: "#810081", 215: "#815681", 216: "#680068", 217: "#684568", 218: "#4f004f", 219: "#4f354f",
+	220: "#ff00bf", 221: "#ffaaea", 222: "#bd008d", 223: "#bd7ead", 224: "#810060", 225: "#815676", 226: "#68004e", 227: "#68455f", 228: "#4f003b", 229: "#4f3549",
+	230: "#ff007f", 231: "#ffaad4", 232: "#bd005e", 233: "#bd7e9d", 234: "#810040", 235: "#81566b", 236: "#680034", 237: "#684556", 238: "#4f0027", 239: "#4f3542",
+	240: "#ff003f", 241: "#ffaabf", 242: "#bd002e", 243: "#bd7e8d", 244: "#81001f", 245: "#815660", 246: "#680019", 247: "#68454e", 248: "#4f0013", 249: "#4f353b",
+	250: "#333333", 251: "#505050", 252: "#696969", 253: "#828282", 254: "#bebebe", 255: "#ffffff",
+}
+
+func dxfColor(aci int) string {
+	if c, ok := dxfColors[aci]; ok {
+		return c
+	}
+	return "#000000"
+}
+
+// UnmarshalDXF reads an ASCII DXF drawing.  LINE, ARC, CIRCLE, LWPOLYLINE,
+// POLYLINE, SPLINE and ELLIPSE entities are converted to paths, circles and
+// polylines and other entities are ignored.  Every layer with entities becomes
+// a <g> with the layer name as its id, stroked with the layer color.  Layers
+// that are turned off are hidden with display none.
+//
+// The Y axis is flipped to match SVG so the drawing appears the right way up.
+// The viewBox covers the drawing in drawing units and $INSUNITS sets the
+// units of the width and height so the document has the right physical size.
+func UnmarshalDXF(data []byte) (*Root, error) {
+	pairs, err := readDXFPairs(data)
+	if err != nil {
+		return nil, err
+	}
+
+	insUnits := 0
+	var layers []*dxfLayer
+	layerIndex := map[string]*dxfLayer{}
+	layer := func(name string) *dxfLayer {
+		l, ok := layerIndex[name]
+		if !ok {
+			l = &dxfLayer{name: name, color: 7}
+			layerIndex[name] = l
+			layers = append(layers, l)
+		}
+		return l
+	}
+
+	// Split the file into sections and the sections into items that each
+	// start with a 0 group code.
+	var section string
+	var items []*dxfEntity
+	flush := func() error {
+		for i := 0; i < len(items); i++ {
+			it := items[i]
+			switch section {
+			case "HEADER":
+				if it.kind == "$INSUNITS" {
+					insUnits = it.int(70, 0)
+				}
+			case "TABLES":
+				if it.kind == "LAYER" && it.str(2, "") != "" {
+					l := layer(it.str(2, ""))
+					l.color = it.int(62, 7)
+				}
+			case "ENTITIES":
+				if it.kind == "POLYLINE" {
+					for i+1 < len(items) && items[i+1].kind == "VERTEX" {
+						it.vertices = append(it.vertices, items[i+1])
+						i++
+					}
+				}
+				n, err := dxfEntityNode(it)
+				if err != nil {
+					return err
+				}
+				if n != nil {
+					l := layer(it.str(8, "0"))
+					l.nodes = append(l.nodes, n)
+				}
+			}
+		}
+		items = nil
+		return nil
+	}
+
+	for i := 0; i < len(pairs); i++ {
+		p := pairs[i]
+		switch {
+		case p.code == 0 && p.value == "SECTION":
+			if i+1 < len(pairs) && pairs[i+1].code == 2 {
+				section = pairs[i+1].value
+				i++
+			}
+		case p.code == 0 && (p.value == "ENDSEC" || p.value == "EOF"):
+			if err := flush(); err != nil {
+				return nil, err
+			}
+			section = ""
+		case p.code == 0:
+			items = append(items, &dxfEntity{kind: p.value})
+		case p.code == 9 && section == "HEADER":
+			items = append(items, &dxfEntity{kind: p.value})
+		case len(items) > 0:
+			it := items[len(items)-1]
+			it.pairs = append(it.pairs, p)
+		}
+	}
+	if err := flush(); err != nil {
+		return nil, err
+	}
+
+	r := CreateRoot()
+	r.attrs = AttrMap{"xmlns": SvgNs}
+	var shapes []Shape
+	for _, l := range layers {
+		if len(l.nodes) == 0 {
+			continue
+		}
+		g := NewGroup()
+		g.Attrs()["id"] = l.name
+		g.Attrs()["fill"] = "none"
+		g.Attrs()["stroke"] = dxfColor(int(math.Abs(float64(l.color))))
+		if l.color < 0 {
+			g.Attrs()["display"] = "none"
+		}
+		for _, n := range l.nodes {
+			g.AddChild(n)
+			shapes = append(shapes, n.(Shape))
+		}
+		r.AddChild(g)
+	}
+
+	if b, ok := dxfBounds(shapes); ok {
+		r.attrs["viewBox"] = fmt.Sprintf("%s %s %s %s", floatToString(b.Min.X), floatToString(b.Min.Y),
+			floatToString(b.Width()), floatToString(b.Height()))
+		u, ok := dxfUnits[insUnits]
+		if !ok {
+			u.scale = 1
+		}
+		r.attrs["width"] = floatToString(b.Width()*u.scale) + u.unit
+		r.attrs["height"] = floatToString(b.Height()*u.scale) + u.unit
+	}
+	return r, nil
+}
+
+// dxfBounds returns the bounds of the shapes.
+func dxfBounds(shapes []Shape) (geom.Rect, bool) {
+	var segs []segment
+	for _, s := range shapes {
+		for _, sp := range s.ToSubPaths() {
+			segs = append(segs, subPathSegments(sp)...)
+		}
+	}
+	if len(segs) == 0 {
+		return geom.Rect{}, false
+	}
+	hull := unionRects(segmentsBounds(segs))
+	tol := math.Max(hull.Width(), hull.Height()) * 1e-9
+	var pts []geom.Coord
+	for _, s := range segs {
+		pts = s.flatten(tol, append(pts, s.start))
+	}
+	return pointsBounds(pts), true
+}
+
+// dxfMapping converts object coordinates of an entity to SVG coordinates.
+// The Y axis is flipped and entities with an extrusion direction along -Z are
+// mirrored in X, as given by the arbitrary axis algorithm.
+type dxfMapping struct {
+	mirror bool
+}
+
+func (m dxfMapping) vector(v geom.Coord) geom.Coord {
+	// Subtracting from zero avoids writing out negative zeros.
+	if m.mirror {
+		v.X = 0 - v.X
+	}
+	return geom.Coord{X: v.X, Y: 0 - v.Y}
+}
+
+func dxfEntityNode(e *dxfEntity) (Node, error) {
+	ocs := dxfMapping{mirror: e.float(230, 1) < 0}
+	wcs := dxfMapping{}
+
+	var n Node
+	switch e.kind {
+	case "LINE":
+		n = dxfPath([]PathCommand{
+			dxfCommand('M', wcs.vector(e.point(10))),
+			dxfCommand('L', wcs.vector(e.point(11))),
+		})
+	case "CIRCLE":
+		n = NewCircle(ocs.vector(e.point(10)), e.float(40, 0))
+	case "ARC":
+		r := e.float(40, 0)
+		t0, t1 := e.float(50, 0)*math.Pi/180, e.float(51, 0)*math.Pi/180
+		for t1 <= t0 {
+			t1 += 2 * math.Pi
+		}
+		n = dxfPath(dxfArc(nil, ocs.vector(e.point(10)), ocs.vector(geom.Coord{X: r}),
+			ocs.vector(geom.Coord{Y: r}), t0, t1, true))
+	case "ELLIPSE":
+		major := e.point(11)
+		minor := coordScale(coordPerp(major), e.float(40, 1))
+		if e.float(230, 1) < 0 {
+			minor = coordScale(minor, -1)
+		}
+		t0, t1 := e.float(41, 0), e.float(42, 2*math.Pi)
+		for t1 <= t0 {
+			t1 += 2 * math.Pi
+		}
+		cmds := dxfArc(nil, wcs.vector(e.point(10)), wcs.vector(major), wcs.vector(minor), t0, t1, true)
+		if t1-t0 >= 2*math.Pi-geomEpsilon {
+			cmds = append(cmds, PathCommand{Command: 'Z'})
+		}
+		n = dxfPath(cmds)
+	case "LWPOLYLINE":
+		var pts []geom.Coord
+		var bulges []float64
+		for _, p := range e.pairs {
+			v, _ := strconv.ParseFloat(p.value, 64)
+			switch p.code {
+			case 10:
+				pts = append(pts, geom.Coord{X: v})
+				bulges = append(bulges, 0)
+			case 20:
+				if len(pts) > 0 {
+					pts[len(pts)-1].Y = v
+				}
+			case 42:
+				if len(bulges) > 0 {
+					bulges[len(bulges)-1] = v
+				}
+			}
+		}
+		n = dxfPolyline(ocs, pts, bulges, e.int(70, 0)&1 != 0)
+	case "POLYLINE":
+		flags := e.int(70, 0)
+		if flags&(16|64) != 0 {
+			// Polygon and polyface meshes are surfaces.
+			return nil, nil
+		}
+		if flags&8 != 0 {
+			ocs = wcs
+		}
+		var pts []geom.Coord
+		var bulges []float64
+		for _, v := range e.vertices {
+			if v.int(70, 0)&16 != 0 {
+				continue
+			}
+			pts = append(pts, v.point(10))
+			bulges = append(bulges, v.float(42, 0))
+		}
+		n = dxfPolyline(ocs, pts, bulges, flags&1 != 0)
+	case "SPLINE":
+		cmds, err := dxfSpline(e)
+		if err != nil {
+			return nil, err
+		}
+		if cmds != nil {
+			n = dxfPath(cmds)
+		}
+	default:
+		return nil, nil
+	}
+
+	if n == nil {
+		return nil, nil
+	}
+	if c := e.int(420, -1); c >= 0 {
+		n.Attrs()["stroke"] = fmt.Sprintf("#%06x", c&0xffffff)
+	} else if c := e.int(62, 256); c != 0 && c != 256 {
+		n.Attrs()["stroke"] = dxfColor(int(math.Abs(float64(c))))
+	}
+	return n, nil
+}
+
+func dxfCommand(c byte, p geom.Coord) PathCommand {
+	return PathCommand{Command: c, Params: []float64{p.X, p.Y}}
+}
+
+func dxfPath(cmds []PathCommand) *Path {
+	p := NewPath()
+	p.SubPaths = BuildSubPaths(cmds)
+	return p
+}
+
+// dxfArc appends commands for the elliptical arc c + major*cos(t) +
+// minor*sin(t) from t0 to t1, where t1 may be less than t0 for an arc that
+// runs backwards.  All vectors are in SVG coordinates.  When move is set the
+// arc starts with a move to its start point.  Arcs of more than 270 degrees
+// are split in two so that a full turn does not end where it starts.
+func dxfArc(cmds []PathCommand, c, major, minor geom.Coord, t0, t1 float64, move bool) []PathCommand {
+	at := func(t float64) geom.Coord {
+		// Snap the rounding error at multiples of 90 degrees so that end
+		// points fall on the axes exactly.
+		sin, cos := math.Sincos(t)
+		if math.Abs(sin) < 1e-12 {
+			sin = 0
+		}
+		if math.Abs(cos) < 1e-12 {
+			cos = 0
+		}
+		return coordAdd(c, coordAdd(coordScale(major, cos), coordScale(minor, sin)))
+	}
+	if move {
+		cmds = append(cmds, dxfCommand('M', at(t0)))
+	}
+
+	rx, ry := coordLen(major), coordLen(minor)
+	rot := 0 + math.Atan2(major.Y, major.X)*180/math.Pi
+	positive := coordCross(major, minor) > 0
+	n := 1
+	if math.Abs(t1-t0) > 1.5*math.Pi {
+		n = 2
+	}
+	for i := 0; i < n; i++ {
+		ta := t0 + (t1-t0)*float64(i)/float64(n)
+		tb := t0 + (t1-t0)*float64(i+1)/float64(n)
+		p := at(tb)
+		cmds = append(cmds, PathCommand{Command: 'A', Params: []float64{
+			rx, ry, rot, boolToFlag(math.Abs(tb-ta) > math.Pi), boolToFlag((tb > ta) == positive), p.X, p.Y,
+		}})
+	}
+	return cmds
+}
+
+// dxfPolyline converts polyline vertices in object coordinates to a node.
+// The bulge of a vertex is the tangent of a quarter of the angle swept by the
+// arc to the next vertex, positive for counterclockwise.
+func dxfPolyline(m dxfMapping, pts []geom.Coord, bulges []float64, closed bool) Node {
+	if len(pts) < 2 {
+		return nil
+	}
+
+	hasBulge := false
+	for i, b := range bulges {
+		if b != 0 && (closed || i < len(pts)-1) {
+			hasBulge = true
+		}
+	}
+	if !hasBulge {
+		mapped := make([]geom.Coord, len(pts))
+		for i, p := range pts {
+			mapped[i] = m.vector(p)
+		}
+		if closed {
+			return NewPolygon(mapped)
+		}
+		return NewPolyline(mapped)
+	}
+
+	cmds := []PathCommand{dxfCommand('M', m.vector(pts[0]))}
+	n := len(pts) - 1
+	if closed {
+		n = len(pts)
+	}
+	for i := 0; i < n; i++ {
+		a, b := pts[i], pts[(i+1)%len(pts)]
+		bulge := bulges[i]
+		if bulge == 0 || coordNear(a, b, geomEpsilon) {
+			cmds = append(cmds, dxfCommand('L', m.vector(b)))
+			continue
+		}
+		d := coordSub(b, a)
+		c := coordAdd(coordLerp(a, b, 0.5), coordScale(coordPerp(d), (1-bulge*bulge)/(4*bulge)))
+		r := coordDist(a, c)
+		t0 := math.Atan2(a.Y-c.Y, a.X-c.X)
+		cmds = dxfArc(cmds, m.vector(c), m.vector(geom.Coord{X: r}), m.vector(geom.Coord{Y: r}),
+			t0, t0+4*math.Atan(bulge), false)
+		// Land exactly on the next vertex.
+		cmds[len(cmds)-1].Params[5], cmds[len(cmds)-1].Params[6] = m.vector(b).X, m.vector(b).Y
+	}
+	if closed {
+		cmds = append(cmds, PathCommand{Command: 'Z'})
+	}
+	return dxfPath(cmds)
+}
+
+// dxfSplineSamples is the number of lines used for each knot span of a spline
+// that can't be drawn with Bezier curves.
+const dxfSplineSamples = 16
+
+// dxfSpline converts a SPLINE entity to path commands.  Non-rational splines
+// of degree three or less are split into Bezier curves at their knots, which
+// is exact.  Rational and higher degree splines are approximated with lines.
+// Splines that only have fit points are drawn through them as a Catmull-Rom
+// curve.
+func dxfSpline(e *dxfEntity) ([]PathCommand, error) {
+	deg := e.int(71, 3)
+	closed := e.int(70, 0)&1 != 0
+	var knots, weights []float64
+	var ctrl, fit []geom.Coord
+	for _, p := range e.pairs {
+		v, _ := strconv.ParseFloat(p.value, 64)
+		switch p.code {
+		case 10:
+			ctrl = append(ctrl, geom.Coord{X: v})
+		case 20:
+			if len(ctrl) > 0 {
+				ctrl[len(ctrl)-1].Y = v
+			}
+		case 11:
+			fit = append(fit, geom.Coord{X: v})
+		case 21:
+			if len(fit) > 0 {
+				fit[len(fit)-1].Y = v
+			}
+		case 40:
+			knots = append(knots, v)
+		case 41:
+			weights = append(weights, v)
+		}
+	}
+
+	m := dxfMapping{}
+	var cmds []PathCommand
+	switch {
+	case len(ctrl) > 0:
+		if deg < 1 || len(ctrl) <= deg || len(knots) != len(ctrl)+deg+1 {
+			return nil, errors.Errorf("invalid spline with degree %d, %d control points and %d knots",
+				deg, len(ctrl), len(knots))
+		}
+		cmds = splineCommands(deg, knots, ctrl, weights, m)
+	case len(fit) > 1:
+		cmds = catmullRomCommands(fit, closed, m)
+	default:
+		return nil, nil
+	}
+	if closed {
+		cmds = append(cmds, PathCommand{Command: 'Z'})
+	}
+	return cmds, nil
+}
+
+// splineBlossom evaluates the polar form of the B-spline on knot span k at
+// ts.  Points are in homogeneous coordinates.
+func splineBlossom(deg, k int, knots []float64, ctrl [][3]float64, ts []float64) [3]float64 {
+	d := make([][3]float64, deg+1)
+	copy(d, ctrl[k-deg:k+1])
+	for r := 1; r <= deg; r++ {
+		t := ts[r-1]
+		for j := deg; j >= r; j-- {
+			i := j + k - deg
+			a := 0.0
+			if den := knots[i+deg+1-r] - knots[i]; den != 0 {
+				a = (t - knots[i]) / den
+			}
+			for c := range d[j] {
+				d[j][c] = (1-a)*d[j-1][c] + a*d[j][c]
+			}
+		}
+	}
+	return d[deg]
+}
+
+func splineCommands(deg int, knots []float64, ctrl []geom.Coord, weights []float64, m dxfMapping) []PathCommand {
+	rational := false
+	hc := make([][3]float64, len(ctrl))
+	for i, p := range ctrl {
+		w := 1.0
+		if i < len(weights) && weights[i] > 0 {
+			w = weights[i]
+		}
+		if w != 1 {
+			rational = true
+		}
+		hc[i] = [3]float64{p.X * w, p.Y * w, w}
+	}
+	point := func(h [3]float64) geom.Coord {
+		return m.vector(geom.Coord{X: h[0] / h[2], Y: h[1] / h[2]})
+	}
+	repeat := func(a, b float64, j int) []float64 {
+		ts := make([]float64, deg)
+		for i := range ts {
+			ts[i] = a
+			if i < j {
+				ts[i] = b
+			}
+		}
+		return ts
+	}
+
+	var cmds []PathCommand
+	for k := deg; k < len(ctrl); k++ {
+		a, b := knots[k], knots[k+1]
+		if b <= a {
+			continue
+		}
+		if cmds == nil {
+			cmds = append(cmds, dxfCommand('M', point(splineBlossom(deg, k, knots, hc, repeat(a, b, 0)))))
+		}
+		if rational || deg > 3 {
+			for i := 1; i <= dxfSplineSamples; i++ {
+				t := a + (b-a)*float64(i)/dxfSplineSamples
+				cmds = append(cmds, dxfCommand('L', point(splineBlossom(deg, k, knots, hc, repeat(t, t, 0)))))
+			}
+			continue
+		}
+		c := PathCommand{Command: " LQC"[deg]}
+		for j := 1; j <= deg; j++ {
+			p := point(splineBlossom(deg, k, knots, hc, repeat(a, b, j)))
+			c.Params = append(c.Params, p.X, p.Y)
+		}
+		cmds = append(cmds, c)
+	}
+	return cmds
+}
+
+// catmullRomCommands returns cubic Bezier curves passing through pts.
+func catmullRomCommands(pts []geom.Coord, closed bool, m dxfMapping) []PathCommand {
+	n := len(pts)
+	at := func(i int) geom.Coord {
+		switch {
+		case closed:
+			return pts[(i+n)%n]
+		case i < 0:
+			return pts[0]
+		case i >= n:
+			return pts[n-1]
+		}
+		return pts[i]
+	}
+
+	cmds := []PathCommand{dxfCommand('M', m.vector(pts[0]))}
+	segs := n - 1
+	if closed {
+		segs = n
+	}
+	for i := 0; i < segs; i++ {
+		p0, p1, p2, p3 := at(i-1), at(i), at(i+1), at(i+2)
+		c1 := m.vector(coordAdd(p1, coordScale(coordSub(p2, p0), 1.0/6)))
+		c2 := m.vector(coordSub(p2, coordScale(coordSub(p3, p1), 1.0/6)))
+		e := m.vector(p2)
+		cmds = append(cmds, PathCommand{Command: 'C', Params: []float64{c1.X, c1.Y, c2.X, c2.Y, e.X, e.Y}})
+	}
+	return cmds
+}
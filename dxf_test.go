@@ -0,0 +1,202 @@
+// Copyright 2018 Joe Beda
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package svgdata
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/jbeda/geom"
+	"github.com/stretchr/testify/assert"
+)
+
+// dxfText builds a DXF file from alternating group codes and values.
+func dxfText(pairs ...interface{}) []byte {
+	var b strings.Builder
+	for _, p := range pairs {
+		fmt.Fprintf(&b, "%v\n", p)
+	}
+	return []byte(b.String())
+}
+
+func dxfEntities(header []interface{}, entities ...interface{}) []byte {
+	var pairs []interface{}
+	pairs = append(pairs, 0, "SECTION", 2, "HEADER")
+	pairs = append(pairs, header...)
+	pairs = append(pairs, 0, "ENDSEC", 0, "SECTION", 2, "ENTITIES")
+	pairs = append(pairs, entities...)
+	pairs = append(pairs, 0, "ENDSEC", 0, "EOF")
+	return dxfText(pairs...)
+}
+
+func TestUnmarshalDXF(t *testing.T) {
+	assert := assert.New(t)
+
+	data := dxfText(
+		0, "SECTION", 2, "HEADER", 9, "$ACADVER", 1, "AC1015", 9, "$INSUNITS", 70, 4, 0, "ENDSEC",
+		0, "SECTION", 2, "TABLES",
+		0, "TABLE", 2, "LAYER", 70, 2,
+		0, "LAYER", 2, "Cut", 70, 0, 62, 1,
+		0, "LAYER", 2, "Hidden", 70, 0, 62, -3,
+		0, "LAYER", 2, "Unused", 70, 0, 62, 5,
+		0, "ENDTAB", 0, "ENDSEC",
+		0, "SECTION", 2, "ENTITIES",
+		0, "LINE", 8, "0", 10, 0, 20, 0, 30, 0, 11, 100, 21, 0, 31, 0,
+		0, "CIRCLE", 8, "Cut", 10, 50, 20, 25, 30, 0, 40, 10,
+		0, "ARC", 8, "Cut", 62, 3, 10, 0, 20, 0, 30, 0, 40, 50, 50, 0, 51, 90,
+		0, "TEXT", 8, "Cut", 10, 0, 20, 0, 1, "ignored",
+		0, "LINE", 8, "Hidden", 10, 0, 20, 0, 11, 1, 21, 1,
+		0, "ENDSEC", 0, "EOF")
+
+	r, err := UnmarshalDXF(data)
+	assert.NoError(err)
+
+	var ids []string
+	for _, c := range *r.Children() {
+		assert.Equal("g", c.Name())
+		ids = append(ids, c.Attrs()["id"])
+	}
+	assert.Equal([]string{"Cut", "Hidden", "0"}, ids)
+
+	cut := FindByID(r, "Cut")
+	assert.Equal("#ff0000", cut.Attrs()["stroke"])
+	assert.Equal("none", cut.Attrs()["fill"])
+	assert.Equal("none", FindByID(r, "Hidden").Attrs()["display"])
+	if assert.Len(*cut.Children(), 2) {
+		c := (*cut.Children())[0].(*Circle)
+		assert.Equal(geom.Coord{X: 50, Y: -25}, c.Center)
+		assert.Equal(10.0, c.Radius)
+
+		// Counterclockwise in the drawing is clockwise once Y is flipped.
+		arc := (*cut.Children())[1].(*Path)
+		assert.Equal("M50 0A50 50 0 0 0 0 -50", SavePathString(arc.SubPaths))
+		assert.Equal("#00ff00", arc.Attrs()["stroke"])
+	}
+	line := (*FindByID(r, "0").Children())[0].(*Path)
+	assert.Equal("M0 0L100 0", SavePathString(line.SubPaths))
+
+	assert.Equal("0 -50 100 50", r.Attrs()["viewBox"])
+	assert.Equal("100mm", r.Attrs()["width"])
+	assert.Equal("50mm", r.Attrs()["height"])
+
+	// The document can be written and read back.
+	b, err := Marshal(r, true)
+	assert.NoError(err)
+	_, err = Unmarshal(b)
+	assert.NoError(err)
+}
+
+func TestDXFColors(t *testing.T) {
+	assert := assert.New(t)
+
+	for aci, c := range map[int]string{1: "#ff0000", 30: "#ff7f00", 143: "#7eadbd", 254: "#bebebe", 0: "#000000", 256: "#000000"} {
+		assert.Equal(c, dxfColor(aci), "color %d", aci)
+	}
+
+	// Paints map to the nearest index, the lowest one when colors repeat.
+	for paint, aci := range map[string]int{"red": 1, "#fe8001": 30, "white": 255, "#6a6a6a": 252} {
+		got, _, ok := dxfColorCodes(paint)
+		assert.True(ok)
+		assert.Equal(aci, got, "paint %s", paint)
+	}
+}
+
+func TestUnmarshalDXFUnits(t *testing.T) {
+	assert := assert.New(t)
+
+	line := []interface{}{0, "LINE", 10, 0, 20, 0, 11, 2, 21, 1}
+	for units, width := range map[int]string{0: "2", 1: "2in", 2: "24in", 5: "2cm", 6: "2000mm"} {
+		r, err := UnmarshalDXF(dxfEntities([]interface{}{9, "$INSUNITS", 70, units}, line...))
+		assert.NoError(err)
+		assert.Equal(width, r.Attrs()["width"], "units %d", units)
+	}
+
+	_, err := UnmarshalDXF(dxfEntities(nil, 0, "LINE", 10, "x", 20, 0))
+	assert.Error(err)
+	_, err = UnmarshalDXF([]byte("AutoCAD Binary DXF\r\n\x1a\x00"))
+	assert.Error(err)
+}
+
+func TestUnmarshalDXFPolylines(t *testing.T) {
+	assert := assert.New(t)
+
+	r, err := UnmarshalDXF(dxfEntities(nil,
+		// A 10x10 slot with half circle ends.
+		0, "LWPOLYLINE", 90, 4, 70, 1,
+		10, 0, 20, 0, 10, 10, 20, 0, 42, 1, 10, 10, 20, 10, 10, 0, 20, 10, 42, 1,
+		0, "LWPOLYLINE", 90, 3, 70, 0, 10, 0, 20, 0, 10, 1, 20, 0, 10, 1, 20, 1,
+		0, "POLYLINE", 66, 1, 70, 1,
+		0, "VERTEX", 10, 0, 20, 0,
+		0, "VERTEX", 10, 5, 20, 0,
+		0, "VERTEX", 10, 5, 20, 5,
+		0, "SEQEND",
+		// Mirrored by its extrusion direction.
+		0, "LWPOLYLINE", 90, 2, 70, 0, 10, 1, 20, 2, 10, 3, 20, 4, 210, 0, 220, 0, 230, -1))
+	assert.NoError(err)
+
+	children := *FindByID(r, "0").Children()
+	if !assert.Len(children, 4) {
+		return
+	}
+	slot := children[0].(*Path)
+	assert.Equal("M0 0L10 0A5 5 0 0 0 10 -10L0 -10A5 5 0 0 0 0 0Z", SavePathString(slot.SubPaths))
+	assert.InDelta(100+25*math.Pi, subPathsArea(slot.SubPaths), 0.05)
+
+	assert.Equal("polyline", children[1].Name())
+	assert.Equal([]geom.Coord{{X: 0, Y: 0}, {X: 1, Y: 0}, {X: 1, Y: -1}}, children[1].(*Polyshape).Points)
+	assert.Equal("polygon", children[2].Name())
+	assert.Len(children[2].(*Polyshape).Points, 3)
+	assert.Equal([]geom.Coord{{X: -1, Y: -2}, {X: -3, Y: -4}}, children[3].(*Polyshape).Points)
+}
+
+func TestUnmarshalDXFCurves(t *testing.T) {
+	assert := assert.New(t)
+
+	r, err := UnmarshalDXF(dxfEntities(nil,
+		// A clamped cubic with one span is a single Bezier curve.
+		0, "SPLINE", 70, 8, 71, 3, 72, 8, 73, 4,
+		40, 0, 40, 0, 40, 0, 40, 0, 40, 1, 40, 1, 40, 1, 40, 1,
+		10, 0, 20, 0, 10, 1, 20, 2, 10, 3, 20, 2, 10, 4, 20, 0,
+		// A uniform quadratic B-spline whose spans join at the midpoints of
+		// the control polygon.
+		0, "SPLINE", 70, 8, 71, 2, 72, 7, 73, 4,
+		40, 0, 40, 1, 40, 2, 40, 3, 40, 4, 40, 5, 40, 6,
+		10, 0, 20, 0, 10, 2, 20, 2, 10, 4, 20, 0, 10, 6, 20, 2,
+		// A full ellipse with a major axis along Y.
+		0, "ELLIPSE", 10, 0, 20, 0, 11, 0, 21, 4, 40, 0.5, 41, 0, 42, 2*math.Pi,
+		0, "SPLINE", 70, 8, 71, 3, 74, 3, 11, 0, 21, 0, 11, 1, 21, 1, 11, 2, 21, 0))
+	assert.NoError(err)
+
+	children := *FindByID(r, "0").Children()
+	if !assert.Len(children, 4) {
+		return
+	}
+	assert.Equal("M0 0C1 -2 3 -2 4 0", SavePathString(children[0].(*Path).SubPaths))
+	assert.Equal("M1 -1Q2 -2 3 -1Q4 0 5 -1", SavePathString(children[1].(*Path).SubPaths))
+
+	ellipse := children[2].(*Path)
+	assert.InDelta(8*math.Pi, subPathsArea(ellipse.SubPaths), 0.02)
+	b := pointsBounds(FlattenSubPaths(ellipse.SubPaths, 1e-6)[0].Points)
+	assertCoordNear(assert, geom.Coord{X: -2, Y: -4}, b.Min, 1e-5)
+	assertCoordNear(assert, geom.Coord{X: 2, Y: 4}, b.Max, 1e-5)
+
+	fit := children[3].(*Path)
+	if assert.Len(fit.SubPaths, 1) && assert.Len(fit.SubPaths[0].Commands, 3) {
+		assert.InDeltaSlice([]float64{1.0 / 6, -1.0 / 6, 2.0 / 3, -1, 1, -1}, fit.SubPaths[0].Commands[1].Params, 1e-9)
+		assert.InDeltaSlice([]float64{4.0 / 3, -1, 11.0 / 6, -1.0 / 6, 2, 0}, fit.SubPaths[0].Commands[2].Params, 1e-9)
+	}
+}
@@ -0,0 +1,669 @@
+// Copyright 2018 Joe Beda
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package svgdata
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/jbeda/geom"
+	"github.com/pkg/errors"
+)
+
+// DXFOptions control how a document is written as DXF.
+type DXFOptions struct {
+	// LayerByColor puts shapes on a layer for each stroke color instead of a
+	// layer for each top level group.
+	LayerByColor bool
+
+	// FlattenCurves writes Bezier curves as polylines within Tolerance
+	// instead of SPLINE entities, for programs that can't read splines.
+	FlattenCurves bool
+
+	// Tolerance is the largest distance curves may deviate when flattened,
+	// in DXF drawing units.
+	Tolerance float64
+}
+
+// dxfInsUnits maps SVG units to the $INSUNITS value written for them and the
+// number of those drawing units in one SVG unit.  Pixels and plain numbers
+// are written unitless.
+var dxfInsUnits = map[string]struct {
+	code  int
+	scale float64
+}{
+	"":   {0, 1},
+	"px": {0, 1},
+	"in": {1, 1},
+	"mm": {4, 1},
+	"cm": {5, 1},
+	"pt": {1, 1 / ptPerInch},
+	"pc": {1, ptPerPc / ptPerInch},
+}
+
+// dxfDocumentUnits returns the $INSUNITS value for r and the scale from the
+// user space of r to drawing units.  The units come from the width of the
+// document and the scale from comparing the width to the viewBox.  Without a
+// width, or with a percentage, the drawing is unitless and in pixels.
+func dxfDocumentUnits(r *Root) (int, float64, error) {
+	vb, pxPerUnit, err := documentViewport(r)
+	if err != nil {
+		return 0, 0, err
+	}
+	w, ok := r.Attrs()["width"]
+	if !ok || strings.HasSuffix(w, "%") {
+		return 0, pxPerUnit, nil
+	}
+	caps := valueRE.FindStringSubmatch(strings.TrimSpace(w))
+	if len(caps) == 0 {
+		return 0, 0, errors.Errorf("invalid document width: %s", w)
+	}
+	u, ok := dxfInsUnits[caps[2]]
+	if !ok {
+		return 0, 0, errors.Errorf("unsupported document width: %s", w)
+	}
+	if _, ok := r.Attrs()["viewBox"]; ok {
+		wv, err := parseValue(caps[1])
+		if err != nil {
+			return 0, 0, err
+		}
+		return u.code, u.scale * wv / vb.Width(), nil
+	}
+	// Without a viewBox user units are pixels.
+	px, err := parseValue("1" + caps[2])
+	if err != nil {
+		return 0, 0, err
+	}
+	return u.code, u.scale / px, nil
+}
+
+// dxfWriter accumulates group code and value pairs.  Entities are given
+// handles from handles and are owned by the block record owner.
+type dxfWriter struct {
+	bytes.Buffer
+	handles *dxfHandles
+	owner   string
+}
+
+// dxfHandles allocates object handles, which are hexadecimal numbers unique
+// within a drawing.
+type dxfHandles int
+
+func (h *dxfHandles) next() string {
+	*h++
+	return fmt.Sprintf("%X", int(*h))
+}
+
+func (w *dxfWriter) pair(code int, v interface{}) {
+	if f, ok := v.(float64); ok {
+		// Subtracting from zero avoids writing out negative zeros.
+		v = floatToString(0 + f)
+	}
+	fmt.Fprintf(w, "%d\n%v\n", code, v)
+}
+
+func (w *dxfWriter) point(code int, p geom.Coord) {
+	w.pair(code, p.X)
+	w.pair(code+10, p.Y)
+	w.pair(code+20, 0.0)
+}
+
+// extrusion writes an extrusion direction along the Z axis.
+func (w *dxfWriter) extrusion(z float64) {
+	w.pair(210, 0.0)
+	w.pair(220, 0.0)
+	w.pair(230, z)
+}
+
+// dxfLayerEntry is a layer being written and its color.
+type dxfLayerEntry struct {
+	name   string
+	color  string
+	hidden bool
+}
+
+// MarshalDXF writes the document as an ASCII DXF R2000 drawing with the
+// handles, tables, blocks and objects that strict readers expect.  Lines and
+// circular arcs become LINE, ARC and LWPOLYLINE entities with bulges,
+// elliptical arcs become ELLIPSE entities, circles become CIRCLE entities and
+// Bezier curves become SPLINE entities or polylines.  Subpaths are split into
+// several entities where they mix these kinds.
+//
+// Each top level group becomes a layer named after its id, and shapes outside
+// of a group go on layer 0.  Groups that are hidden with display none become
+// layers that are turned off.  With LayerByColor set the layers are named
+// after stroke colors instead and hidden groups are left out.  Top level
+// groups without shapes still get a layer.  Entities are given their stroke
+// color.
+//
+// The Y axis is flipped so the drawing is the right way up in DXF.  The width
+// of the document sets $INSUNITS and coordinates are scaled so that the
+// drawing has the physical size of the document.
+func MarshalDXF(r *Root, opts DXFOptions) ([]byte, error) {
+	units, scale, err := dxfDocumentUnits(r)
+	if err != nil {
+		return nil, err
+	}
+	toDXF := NewScale(scale, -scale)
+
+	var layers []*dxfLayerEntry
+	layerIndex := map[string]*dxfLayerEntry{}
+	layer := func(name string) *dxfLayerEntry {
+		l, ok := layerIndex[name]
+		if !ok {
+			l = &dxfLayerEntry{name: name}
+			layerIndex[name] = l
+			layers = append(layers, l)
+		}
+		return l
+	}
+	layer("0")
+
+	handles := new(dxfHandles)
+	modelSpace := handles.next()
+	ents := dxfWriter{handles: handles, owner: modelSpace}
+	err = walkLayers(r, func(layerNode, n Node, ctm Transform, st Style) error {
+		hidden := false
+		if v, _ := layerNode.Attrs().GetStyle("display"); v == "none" {
+			// Only group layers can be turned off.  Layers by color are
+			// shared between groups, so hidden groups are left out instead.
+			if layerID(layerNode) == "" || opts.LayerByColor {
+				return nil
+			}
+			hidden = true
+		}
+		current := layerIndex["0"]
+		if id := layerID(layerNode); id != "" {
+			current = layer(dxfLayerName(id))
+		}
+		current.hidden = current.hidden || hidden
+		if v, ok := layerNode.Attrs().GetStyle("stroke"); ok && current.color == "" {
+			current.color = v
+		}
+
+		s, ok := n.(Shape)
+		if !ok {
+			return nil
+		}
+		stroke := st.Get("stroke", "none")
+		if isNonePaint(stroke) {
+			stroke = st.Get("fill", "black")
+		}
+		l := current
+		if opts.LayerByColor {
+			l = layer(dxfLayerName(stroke))
+			l.color = stroke
+		}
+		ents.shape(s, toDXF.Multiply(ctm), l.name, stroke, opts)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	w := dxfWriter{handles: handles}
+	w.pair(0, "SECTION")
+	w.pair(2, "CLASSES")
+	w.pair(0, "ENDSEC")
+
+	w.pair(0, "SECTION")
+	w.pair(2, "TABLES")
+	w.table("VPORT", 0)
+	w.pair(0, "ENDTAB")
+
+	ltypes := []string{"ByBlock", "ByLayer", "Continuous"}
+	owner := w.table("LTYPE", len(ltypes))
+	for _, name := range ltypes {
+		w.tableEntry("LTYPE", owner, "AcDbLinetypeTableRecord", name)
+		w.pair(3, "")
+		w.pair(72, 65)
+		w.pair(73, 0)
+		w.pair(40, 0.0)
+	}
+	w.pair(0, "ENDTAB")
+
+	owner = w.table("LAYER", len(layers))
+	for _, l := range layers {
+		aci, rgb, ok := dxfColorCodes(l.color)
+		if !ok {
+			aci = 7
+		}
+		if l.hidden {
+			aci = -aci
+		}
+		w.tableEntry("LAYER", owner, "AcDbLayerTableRecord", l.name)
+		w.pair(62, aci)
+		if ok {
+			w.pair(420, rgb)
+		}
+		w.pair(6, "Continuous")
+	}
+	w.pair(0, "ENDTAB")
+
+	owner = w.table("STYLE", 1)
+	w.tableEntry("STYLE", owner, "AcDbTextStyleTableRecord", "Standard")
+	w.pair(40, 0.0)
+	w.pair(41, 1.0)
+	w.pair(50, 0.0)
+	w.pair(71, 0)
+	w.pair(42, 2.5)
+	w.pair(3, "txt")
+	w.pair(4, "")
+	w.pair(0, "ENDTAB")
+
+	w.table("VIEW", 0)
+	w.pair(0, "ENDTAB")
+	w.table("UCS", 0)
+	w.pair(0, "ENDTAB")
+
+	owner = w.table("APPID", 1)
+	w.tableEntry("APPID", owner, "AcDbRegAppTableRecord", "ACAD")
+	w.pair(0, "ENDTAB")
+
+	owner = w.table("DIMSTYLE", 1)
+	w.tableEntry("DIMSTYLE", owner, "AcDbDimStyleTableRecord", "Standard")
+	w.pair(0, "ENDTAB")
+
+	// The model space record was allocated first so entities could refer to
+	// it.
+	owner = w.table("BLOCK_RECORD", 2)
+	records := []string{modelSpace, handles.next()}
+	blocks := []string{"*Model_Space", "*Paper_Space"}
+	for i, name := range blocks {
+		w.tableEntryHandle("BLOCK_RECORD", records[i], owner, "AcDbBlockTableRecord", name)
+	}
+	w.pair(0, "ENDTAB")
+	w.pair(0, "ENDSEC")
+
+	w.pair(0, "SECTION")
+	w.pair(2, "BLOCKS")
+	for i, name := range blocks {
+		w.owner = records[i]
+		w.blockEntity("BLOCK", i == 1)
+		w.pair(100, "AcDbBlockBegin")
+		w.pair(2, name)
+		w.pair(70, 0)
+		w.point(10, geom.Coord{})
+		w.pair(3, name)
+		w.pair(1, "")
+		w.blockEntity("ENDBLK", i == 1)
+		w.pair(100, "AcDbBlockEnd")
+	}
+	w.pair(0, "ENDSEC")
+
+	w.pair(0, "SECTION")
+	w.pair(2, "ENTITIES")
+	w.Write(ents.Bytes())
+	w.pair(0, "ENDSEC")
+
+	root, groups := handles.next(), handles.next()
+	w.pair(0, "SECTION")
+	w.pair(2, "OBJECTS")
+	w.pair(0, "DICTIONARY")
+	w.pair(5, root)
+	w.pair(330, 0)
+	w.pair(100, "AcDbDictionary")
+	w.pair(281, 1)
+	w.pair(3, "ACAD_GROUP")
+	w.pair(350, groups)
+	w.pair(0, "DICTIONARY")
+	w.pair(5, groups)
+	w.pair(330, root)
+	w.pair(100, "AcDbDictionary")
+	w.pair(281, 1)
+	w.pair(0, "ENDSEC")
+	w.pair(0, "EOF")
+
+	// The header is written last so that $HANDSEED is above every handle
+	// in use.
+	var h dxfWriter
+	h.pair(0, "SECTION")
+	h.pair(2, "HEADER")
+	h.pair(9, "$ACADVER")
+	h.pair(1, "AC1015")
+	h.pair(9, "$HANDSEED")
+	h.pair(5, handles.next())
+	h.pair(9, "$INSUNITS")
+	h.pair(70, units)
+	h.pair(0, "ENDSEC")
+	h.Write(w.Bytes())
+	return h.Bytes(), nil
+}
+
+// table starts a symbol table with n entries and returns its handle.
+func (w *dxfWriter) table(name string, n int) string {
+	h := w.handles.next()
+	w.pair(0, "TABLE")
+	w.pair(2, name)
+	w.pair(5, h)
+	w.pair(330, 0)
+	w.pair(100, "AcDbSymbolTable")
+	w.pair(70, n)
+	if name == "DIMSTYLE" {
+		w.pair(100, "AcDbDimStyleTable")
+	}
+	return h
+}
+
+// tableEntry starts a symbol table record owned by the table owner.
+func (w *dxfWriter) tableEntry(kind, owner, subclass, name string) {
+	w.tableEntryHandle(kind, w.handles.next(), owner, subclass, name)
+}
+
+func (w *dxfWriter) tableEntryHandle(kind, h, owner, subclass, name string) {
+	w.pair(0, kind)
+	// Dimension styles use a different code for their handle.
+	if kind == "DIMSTYLE" {
+		w.pair(105, h)
+	} else {
+		w.pair(5, h)
+	}
+	w.pair(330, owner)
+	w.pair(100, "AcDbSymbolTableRecord")
+	w.pair(100, subclass)
+	w.pair(2, name)
+	w.pair(70, 0)
+}
+
+// blockEntity writes the pairs common to BLOCK and ENDBLK.
+func (w *dxfWriter) blockEntity(kind string, paperSpace bool) {
+	w.pair(0, kind)
+	w.pair(5, w.handles.next())
+	w.pair(330, w.owner)
+	w.pair(100, "AcDbEntity")
+	if paperSpace {
+		w.pair(67, 1)
+	}
+	w.pair(8, "0")
+}
+
+// dxfLayerName replaces the characters that are not allowed in layer names.
+func dxfLayerName(s string) string {
+	return strings.Map(func(r rune) rune {
+		if strings.ContainsRune(`<>/\":;?*|=,`+"`", r) {
+			return '_'
+		}
+		return r
+	}, s)
+}
+
+// dxfColorCodes returns the nearest standard color index and the true color
+// value for a paint.
+func dxfColorCodes(paint string) (int, int, bool) {
+	c, ok := parseColor(paint)
+	if !ok {
+		return 0, 0, false
+	}
+	best, bestDist := 7, math.Inf(1)
+	for aci, s := range dxfColors {
+		ac, _ := parseColor(s)
+		dr, dg, db := float64(c.R)-float64(ac.R), float64(c.G)-float64(ac.G), float64(c.B)-float64(ac.B)
+		if d := dr*dr + dg*dg + db*db; d < bestDist || (d == bestDist && aci < best) {
+			best, bestDist = aci, d
+		}
+	}
+	return best, int(c.R)<<16 | int(c.G)<<8 | int(c.B), true
+}
+
+// entityStart writes the pairs common to every entity.
+func (w *dxfWriter) entityStart(kind, layer, stroke, subclass string) {
+	w.pair(0, kind)
+	w.pair(5, w.handles.next())
+	w.pair(330, w.owner)
+	w.pair(100, "AcDbEntity")
+	w.pair(8, layer)
+	if aci, rgb, ok := dxfColorCodes(stroke); ok {
+		w.pair(62, aci)
+		w.pair(420, rgb)
+	}
+	w.pair(100, subclass)
+}
+
+// shape writes the entities for a shape with t applied.
+func (w *dxfWriter) shape(s Shape, t Transform, layer, stroke string, opts DXFOptions) {
+	if c, ok := s.(*Circle); ok && dxfIsSimilarity(t) {
+		w.entityStart("CIRCLE", layer, stroke, "AcDbCircle")
+		w.point(10, t.Apply(c.Center))
+		w.pair(40, c.Radius*t.Scale())
+		return
+	}
+
+	for _, sp := range s.ToSubPaths() {
+		var segs []segment
+		for _, seg := range subPathSegments(sp) {
+			if seg.start == seg.end && seg.kind == 'L' {
+				continue
+			}
+			segs = append(segs, seg.transform(t))
+		}
+		if len(segs) == 0 {
+			continue
+		}
+		if opts.FlattenCurves {
+			segs = dxfFlattenCurves(segs, opts.Tolerance)
+		}
+
+		// Split the subpath into runs of segments that can be written as a
+		// single entity.
+		kind := func(s segment) byte {
+			switch {
+			case s.kind == 'Q' || s.kind == 'C':
+				return 'C'
+			case s.kind == 'A' && !s.ellipse().isCircular():
+				return 'E'
+			}
+			return 'L'
+		}
+		for i := 0; i < len(segs); {
+			j := i + 1
+			if kind(segs[i]) != 'E' {
+				for j < len(segs) && kind(segs[j]) == kind(segs[i]) {
+					j++
+				}
+			}
+			run := segs[i:j]
+			closed := sp.IsClosed() && i == 0 && j == len(segs)
+			switch kind(segs[i]) {
+			case 'C':
+				w.spline(run, closed, layer, stroke)
+			case 'E':
+				w.ellipse(run[0], layer, stroke)
+			default:
+				w.polyline(run, closed, layer, stroke)
+			}
+			i = j
+		}
+	}
+}
+
+// dxfIsSimilarity returns true if t keeps circles circular.
+func dxfIsSimilarity(t Transform) bool {
+	a, b := geom.Coord{X: t.A, Y: t.B}, geom.Coord{X: t.C, Y: t.D}
+	la, lb := coordLen(a), coordLen(b)
+	return math.Abs(la-lb) <= geomEpsilon*la && math.Abs(coordDot(a, b)) <= geomEpsilon*la*lb
+}
+
+// dxfFlattenCurves replaces Bezier curves with lines.
+func dxfFlattenCurves(segs []segment, tol float64) []segment {
+	var r []segment
+	for _, s := range segs {
+		if s.kind != 'Q' && s.kind != 'C' {
+			r = append(r, s)
+			continue
+		}
+		pts := s.flatten(math.Max(tol, minTolerance), []geom.Coord{s.start})
+		for i := 1; i < len(pts); i++ {
+			r = append(r, segment{kind: 'L', start: pts[i-1], end: pts[i]})
+		}
+	}
+	return r
+}
+
+// polyline writes lines and circular arcs.  A single line or arc is
+// written as a LINE or ARC and anything else as an LWPOLYLINE.  The bulge of
+// each vertex is the tangent of a quarter of the angle swept by the arc to
+// the next vertex, positive for counterclockwise.
+func (w *dxfWriter) polyline(run []segment, closed bool, layer, stroke string) {
+	if len(run) == 1 && !closed {
+		s := run[0]
+		if s.kind == 'L' {
+			w.entityStart("LINE", layer, stroke, "AcDbLine")
+			w.point(10, s.start)
+			w.point(11, s.end)
+			return
+		}
+		// Arcs run counterclockwise around their extrusion direction, so
+		// clockwise arcs are written with the direction reversed.  Their
+		// object coordinates are then mirrored in X.
+		ea := s.ellipse()
+		a0, a1 := ea.theta+ea.phi, ea.theta+ea.phi+ea.delta
+		center := ea.center
+		if ea.delta < 0 {
+			a0, a1 = math.Pi-a0, math.Pi-a1
+			center.X = 0 - center.X
+		}
+		w.entityStart("ARC", layer, stroke, "AcDbCircle")
+		w.point(10, center)
+		w.pair(40, ea.rx)
+		if ea.delta < 0 {
+			w.extrusion(-1)
+		}
+		w.pair(100, "AcDbArc")
+		w.pair(50, dxfDegrees(a0))
+		w.pair(51, dxfDegrees(a1))
+		return
+	}
+
+	n := len(run) + 1
+	if closed {
+		n = len(run)
+	}
+	flags := 0
+	if closed {
+		flags = 1
+	}
+	w.entityStart("LWPOLYLINE", layer, stroke, "AcDbPolyline")
+	w.pair(90, n)
+	w.pair(70, flags)
+	for i := 0; i < n; i++ {
+		if i == len(run) {
+			w.pair(10, run[i-1].end.X)
+			w.pair(20, run[i-1].end.Y)
+			continue
+		}
+		s := run[i]
+		w.pair(10, s.start.X)
+		w.pair(20, s.start.Y)
+		if s.kind == 'A' {
+			w.pair(42, math.Tan(s.ellipse().delta/4))
+		}
+	}
+}
+
+// dxfDegrees converts an angle to degrees in [0, 360).
+func dxfDegrees(a float64) float64 {
+	d := math.Mod(a*180/math.Pi, 360)
+	if d < 0 {
+		d += 360
+	}
+	return d
+}
+
+// ellipse writes an elliptical arc as an ELLIPSE.  The major axis is the
+// longer radius and the parameters run counterclockwise from it around the
+// extrusion direction, which points down for clockwise arcs.
+func (w *dxfWriter) ellipse(s segment, layer, stroke string) {
+	ea := s.ellipse()
+	major := geom.Coord{X: ea.rx * math.Cos(ea.phi), Y: ea.rx * math.Sin(ea.phi)}
+	ratio := ea.ry / ea.rx
+	t0 := ea.theta
+	if ea.ry > ea.rx {
+		major = coordScale(coordPerp(major), ea.ry/ea.rx)
+		ratio = ea.rx / ea.ry
+		t0 -= math.Pi / 2
+	}
+	if ea.delta < 0 {
+		t0 = -t0
+	}
+	t0 = math.Mod(t0, 2*math.Pi)
+	if t0 < 0 {
+		t0 += 2 * math.Pi
+	}
+	w.entityStart("ELLIPSE", layer, stroke, "AcDbEllipse")
+	w.point(10, ea.center)
+	w.point(11, major)
+	if ea.delta < 0 {
+		w.extrusion(-1)
+	}
+	w.pair(40, ratio)
+	w.pair(41, t0)
+	w.pair(42, t0+math.Abs(ea.delta))
+}
+
+// spline writes Bezier curves as a single SPLINE.  Quadratic curves are
+// elevated to cubics unless every curve is quadratic.  Interior knots are
+// repeated as often as the degree so that each curve is one knot span, which
+// keeps the corners between curves.
+func (w *dxfWriter) spline(run []segment, closed bool, layer, stroke string) {
+	deg := 2
+	for _, s := range run {
+		if s.kind == 'C' {
+			deg = 3
+		}
+	}
+
+	pts := []geom.Coord{run[0].start}
+	for _, s := range run {
+		if deg == 2 {
+			pts = append(pts, s.ctrl1, s.end)
+			continue
+		}
+		c := s.cubic()
+		if s.kind == 'Q' {
+			c = s.quad().Elevate()
+		}
+		pts = append(pts, c.P1, c.P2, c.P3)
+	}
+	var knots []float64
+	for i := 0; i <= len(run); i++ {
+		n := deg
+		if i == 0 || i == len(run) {
+			n = deg + 1
+		}
+		for j := 0; j < n; j++ {
+			knots = append(knots, float64(i))
+		}
+	}
+
+	flags := 8
+	if closed {
+		flags |= 1
+	}
+	w.entityStart("SPLINE", layer, stroke, "AcDbSpline")
+	w.extrusion(1)
+	w.pair(70, flags)
+	w.pair(71, deg)
+	w.pair(72, len(knots))
+	w.pair(73, len(pts))
+	w.pair(74, 0)
+	for _, k := range knots {
+		w.pair(40, k)
+	}
+	for _, p := range pts {
+		w.point(10, p)
+	}
+}
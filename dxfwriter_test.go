@@ -0,0 +1,224 @@
+// Copyright 2018 Joe Beda
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package svgdata
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/jbeda/geom"
+	"github.com/stretchr/testify/assert"
+)
+
+// assertSameOutline checks that two sets of subpaths trace the same outline
+// by comparing their lengths, areas and end points.
+func assertSameOutline(assert *assert.Assertions, expected, actual []SubPath) {
+	assert.InDelta(subPathsLength(expected), subPathsLength(actual), 1e-5)
+	assert.InDelta(subPathsArea(expected), subPathsArea(actual), 1e-3)
+	if assert.Equal(len(expected), len(actual)) {
+		for i := range expected {
+			assertCoordNear(assert, expected[i].Start(), actual[i].Start(), 1e-9)
+			assertCoordNear(assert, expected[i].End(), actual[i].End(), 1e-9)
+		}
+	}
+}
+
+func TestMarshalDXFRoundTrip(t *testing.T) {
+	assert := assert.New(t)
+
+	r, err := Unmarshal([]byte(`<svg xmlns="http://www.w3.org/2000/svg" width="100mm" height="50mm" viewBox="0 0 100 50">
+  <g id="Cut" stroke="red">
+    <path id="slot" d="M10,10 L30,10 A10,10 0 0 1 30,30 L10,30 Z"/>
+    <path id="line" d="M0,0 L100,0"/>
+    <path id="arc" d="M60,30 A10,10 0 1 0 80,30"/>
+    <path id="cwarc" d="M60,45 A5,5 0 0 1 70,45"/>
+    <path id="cubic" d="M60,10 C70,0 80,20 90,10"/>
+    <path id="quad" d="M0,40 Q10,30 20,40 T40,40" stroke="#0000ff"/>
+    <path id="ellipse" d="M50,40 A20,5 30 0 1 90,40"/>
+  </g>
+  <g id="Engrave" display="none" transform="translate(0,10) scale(2)">
+    <circle cx="10" cy="5" r="2"/>
+  </g>
+</svg>`))
+	assert.NoError(err)
+
+	data, err := MarshalDXF(r, DXFOptions{})
+	assert.NoError(err)
+	s := string(data)
+	assert.Contains(s, "$INSUNITS\n70\n4\n")
+	for _, kind := range []string{"LINE", "ARC", "LWPOLYLINE", "SPLINE", "ELLIPSE", "CIRCLE"} {
+		assert.Contains(s, "0\n"+kind+"\n")
+	}
+
+	back, err := UnmarshalDXF(data)
+	assert.NoError(err)
+	assert.True(strings.HasSuffix(back.Attrs()["width"], "mm"))
+
+	var ids []string
+	for _, c := range *back.Children() {
+		ids = append(ids, c.Attrs()["id"])
+	}
+	assert.Equal([]string{"Cut", "Engrave"}, ids)
+	assert.Equal("#ff0000", FindByID(back, "Cut").Attrs()["stroke"])
+	assert.Equal("none", FindByID(back, "Engrave").Attrs()["display"])
+
+	cut := *FindByID(back, "Cut").Children()
+	if assert.Len(cut, 7) {
+		for i, id := range []string{"slot", "line", "arc", "cwarc", "cubic", "quad", "ellipse"} {
+			assertSameOutline(assert, FindByID(r, id).(Shape).ToSubPaths(), cut[i].(Shape).ToSubPaths())
+		}
+		assert.Equal("M60 10C70 0 80 20 90 10", SavePathString(cut[4].(*Path).SubPaths))
+		assert.Equal("M0 40Q10 30 20 40Q30 50 40 40", SavePathString(cut[5].(*Path).SubPaths))
+		assert.Equal("#0000ff", cut[5].Attrs()["stroke"])
+	}
+
+	engrave := *FindByID(back, "Engrave").Children()
+	if assert.Len(engrave, 1) {
+		c := engrave[0].(*Circle)
+		assert.Equal(geom.Coord{X: 20, Y: 20}, c.Center)
+		assert.Equal(4.0, c.Radius)
+	}
+}
+
+func TestMarshalDXFStructure(t *testing.T) {
+	assert := assert.New(t)
+
+	r, err := Unmarshal([]byte(`<svg xmlns="http://www.w3.org/2000/svg">
+  <g id="Cut">
+    <path d="M0,0 L10,0 L10,10 Z"/>
+    <circle cx="5" cy="5" r="2"/>
+  </g>
+</svg>`))
+	assert.NoError(err)
+	data, err := MarshalDXF(r, DXFOptions{})
+	if !assert.NoError(err) {
+		return
+	}
+	pairs, err := readDXFPairs(data)
+	if !assert.NoError(err) {
+		return
+	}
+
+	// R2000 readers expect every section, the standard tables and blocks for
+	// model and paper space.
+	var sections, tables, blocks []string
+	var section, seed, modelSpace string
+	handles := map[string]bool{}
+	owners := map[string]bool{}
+	for i, p := range pairs {
+		prev := dxfPair{}
+		if i > 0 {
+			prev = pairs[i-1]
+		}
+		switch {
+		case prev.code == 0 && prev.value == "SECTION":
+			section = p.value
+			sections = append(sections, p.value)
+		case prev.code == 0 && prev.value == "TABLE":
+			tables = append(tables, p.value)
+		case prev.code == 100 && prev.value == "AcDbBlockBegin":
+			blocks = append(blocks, p.value)
+		case prev.code == 9 && prev.value == "$HANDSEED":
+			seed = p.value
+		case p.code == 5 || p.code == 105:
+			assert.False(handles[p.value], "duplicate handle %s", p.value)
+			handles[p.value] = true
+		case p.code == 330 && section == "ENTITIES":
+			owners[p.value] = true
+		case p.code == 2 && p.value == "*Model_Space" && pairs[i-5].value == "BLOCK_RECORD":
+			modelSpace = pairs[i-4].value
+		}
+	}
+	assert.Equal([]string{"HEADER", "CLASSES", "TABLES", "BLOCKS", "ENTITIES", "OBJECTS"}, sections)
+	assert.Equal([]string{"VPORT", "LTYPE", "LAYER", "STYLE", "VIEW", "UCS", "APPID", "DIMSTYLE", "BLOCK_RECORD"}, tables)
+	assert.Equal([]string{"*Model_Space", "*Paper_Space"}, blocks)
+	assert.Equal(map[string]bool{modelSpace: true}, owners)
+	assert.NotEmpty(modelSpace)
+	for h := range handles {
+		assert.True(len(h) < len(seed) || (len(h) == len(seed) && h < seed), "handle %s above seed %s", h, seed)
+	}
+}
+
+func TestMarshalDXFOptions(t *testing.T) {
+	assert := assert.New(t)
+
+	r, err := Unmarshal([]byte(`<svg xmlns="http://www.w3.org/2000/svg" width="4in" height="2in" viewBox="0 0 400 200">
+  <path d="M0,0 L100,0 C150,0 200,50 200,100" stroke="red"/>
+  <path d="M0,100 L100,200" stroke="blue"/>
+</svg>`))
+	assert.NoError(err)
+
+	data, err := MarshalDXF(r, DXFOptions{LayerByColor: true, FlattenCurves: true, Tolerance: 0.001})
+	assert.NoError(err)
+	assert.Contains(string(data), "$INSUNITS\n70\n1\n")
+	assert.NotContains(string(data), "SPLINE")
+
+	back, err := UnmarshalDXF(data)
+	assert.NoError(err)
+	assert.Equal("2in", back.Attrs()["width"])
+
+	var ids []string
+	for _, c := range *back.Children() {
+		ids = append(ids, c.Attrs()["id"])
+	}
+	assert.Equal([]string{"red", "blue"}, ids)
+
+	// The curve is flattened into the same polyline as the line before it.
+	// Coordinates are in inches.
+	red := *FindByID(back, "red").Children()
+	if assert.Len(red, 1) && assert.Equal("polyline", red[0].Name()) {
+		pts := red[0].(*Polyshape).Points
+		assert.Equal([]geom.Coord{{X: 0, Y: 0}, {X: 1, Y: 0}}, pts[:2])
+		assertCoordNear(assert, geom.Coord{X: 2, Y: 1}, pts[len(pts)-1], 1e-12)
+	}
+
+	// Hidden groups can't be turned off when layers are by color, so they
+	// are left out.
+	r, err = Unmarshal([]byte(`<svg xmlns="http://www.w3.org/2000/svg">
+  <path d="M0,0 L1,0" stroke="red"/>
+  <g id="Hidden" display="none">
+    <path d="M0,1 L1,1" stroke="blue"/>
+  </g>
+  <g id="Empty"/>
+</svg>`))
+	assert.NoError(err)
+	data, err = MarshalDXF(r, DXFOptions{LayerByColor: true})
+	assert.NoError(err)
+	assert.NotContains(string(data), "Hidden")
+	assert.Equal(1, strings.Count(string(data), "0\nLINE\n"))
+	back, err = UnmarshalDXF(data)
+	if assert.NoError(err) && assert.Len(*back.Children(), 1) {
+		assert.Equal("red", (*back.Children())[0].Attrs()["id"])
+	}
+
+	// Otherwise hidden groups become layers that are turned off and empty
+	// groups still get a layer.
+	data, err = MarshalDXF(r, DXFOptions{})
+	assert.NoError(err)
+	assert.Contains(string(data), "2\nHidden\n")
+	assert.Contains(string(data), "2\nEmpty\n")
+	assert.Equal(2, strings.Count(string(data), "0\nLINE\n"))
+
+	// Percentages leave the drawing unitless.
+	r.Attrs()["width"] = "100%"
+	data, err = MarshalDXF(r, DXFOptions{})
+	if assert.NoError(err) {
+		assert.Contains(string(data), "$INSUNITS\n70\n0\n")
+	}
+
+	r.Attrs()["width"] = "wide"
+	_, err = MarshalDXF(r, DXFOptions{})
+	assert.Error(err)
+}
@@ -0,0 +1,90 @@
+// Copyright 2018 Joe Beda
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package svgdata
+
+import "github.com/jbeda/geom"
+
+// Ellipse is an axis aligned ellipse.
+type Ellipse struct {
+	nodeImpl
+	Center geom.Coord
+	Rx, Ry float64
+}
+
+var _ Shape = (*Ellipse)(nil)
+
+func init() {
+	RegisterNodeCreator("ellipse", func() Node { return createEllipse() })
+}
+
+func createEllipse() *Ellipse {
+	e := &Ellipse{}
+	e.nodeImpl.name = "ellipse"
+	e.nodeImpl.onMarshalAttrs = e.marshalAttrs
+	e.nodeImpl.onUnmarshalAttrs = e.unmarshalAttrs
+	return e
+}
+
+func NewEllipse(c geom.Coord, rx, ry float64) *Ellipse {
+	n := createEllipse()
+	n.Center = c
+	n.Rx = rx
+	n.Ry = ry
+	return n
+}
+
+// ToSubPaths returns the ellipse as a closed subpath made of two half ellipse
+// arcs starting at the rightmost point.  An ellipse without a positive radius
+// isn't drawn.
+func (e *Ellipse) ToSubPaths() []SubPath {
+	rx, ry := e.Rx, e.Ry
+	if rx <= 0 || ry <= 0 {
+		return nil
+	}
+	cx, cy := e.Center.X, e.Center.Y
+	return BuildSubPaths([]PathCommand{
+		{Command: 'M', Params: []float64{cx + rx, cy}},
+		{Command: 'A', Params: []float64{rx, ry, 0, 0, 1, cx - rx, cy}},
+		{Command: 'A', Params: []float64{rx, ry, 0, 0, 1, cx + rx, cy}},
+		{Command: 'Z'},
+	})
+}
+
+func (e *Ellipse) marshalAttrs(am AttrMap) {
+	am["cx"] = floatToString(e.Center.X)
+	am["cy"] = floatToString(e.Center.Y)
+	am["rx"] = floatToString(e.Rx)
+	am["ry"] = floatToString(e.Ry)
+}
+
+func (e *Ellipse) unmarshalAttrs(am AttrMap) error {
+	cx, err := am.ExtractValue("cx")
+	if err != nil {
+		return err
+	}
+	cy, err := am.ExtractValue("cy")
+	if err != nil {
+		return err
+	}
+
+	rx, ry, err := am.extractRadii()
+	if err != nil {
+		return err
+	}
+
+	e.Center = geom.Coord{X: cx, Y: cy}
+	e.Rx, e.Ry = rx, ry
+	return nil
+}
@@ -0,0 +1,153 @@
+// Copyright 2018 Joe Beda
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package svgdata
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"strings"
+)
+
+// MarshalEPS converts the document to Encapsulated PostScript.  The bounding
+// box is the document's viewport at its physical size and drawing is clipped
+// to it.  Each shape is drawn in its own user space with concat so that
+// strokes are transformed as in SVG.  Circular arcs are written with arc and
+// arcn and other curves with curveto.  PostScript has no transparency so
+// opacity is ignored.  Gradients, patterns, clipping, masks, text and images
+// are not written.
+func MarshalEPS(r *Root) ([]byte, error) {
+	size, vt, err := viewportTransform(r)
+	if err != nil {
+		return nil, err
+	}
+	w, h := size.X*pdfPtPerPx, size.Y*pdfPtPerPx
+
+	var b bytes.Buffer
+	b.WriteString("%!PS-Adobe-3.0 EPSF-3.0\n")
+	fmt.Fprintf(&b, "%%%%BoundingBox: 0 0 %d %d\n", int(math.Ceil(w-geomEpsilon)), int(math.Ceil(h-geomEpsilon)))
+	fmt.Fprintf(&b, "%%%%HiResBoundingBox: 0 0 %s %s\n", pdfNumber(w), pdfNumber(h))
+	b.WriteString("%%LanguageLevel: 2\n%%Pages: 1\n%%EndComments\n%%Page: 1 1\n")
+	// Shapes outside of the viewport would draw outside of the bounding box.
+	fmt.Fprintf(&b, "gsave\n0 0 %s %s rectclip\n", pdfNumber(w), pdfNumber(h))
+	fmt.Fprintf(&b, "[%s 0 0 %s 0 %s] concat\n", pdfNumber(pdfPtPerPx), pdfNumber(-pdfPtPerPx), pdfNumber(h))
+
+	err = walkPaint(r, vt, NodeStyle(r), func(n Node, ctm Transform, st Style) error {
+		s, ok := n.(Shape)
+		if !ok || st.Get("visibility", "visible") != "visible" {
+			return nil
+		}
+		return epsShape(&b, s, ctm, st)
+	})
+	if err != nil {
+		return nil, err
+	}
+	b.WriteString("grestore\nshowpage\n%%EOF\n")
+	return b.Bytes(), nil
+}
+
+// epsShape writes the fill and stroke of s.
+func epsShape(b *bytes.Buffer, s Shape, ctm Transform, st Style) error {
+	fill, hasFill := parseColor(st.Get("fill", "black"))
+	stroke, hasStroke := parseColor(st.Get("stroke", "none"))
+	hasStroke = hasStroke && st.StrokeWidth() > 0
+	if !hasFill && !hasStroke {
+		return nil
+	}
+	path := epsPath(s.ToSubPaths())
+	if path == "" {
+		return nil
+	}
+
+	fmt.Fprintf(b, "gsave\n[%s] concat\nnewpath\n%s", pdfTransform(ctm), path)
+	if hasFill {
+		op := "fill"
+		if st.FillRule() == "evenodd" {
+			op = "eofill"
+		}
+		if hasStroke {
+			// Filling clears the path, so keep it for the stroke.
+			b.WriteString("gsave ")
+		}
+		fmt.Fprintf(b, "%s setrgbcolor %s\n", pdfColor(fill.R, fill.G, fill.B), op)
+		if hasStroke {
+			b.WriteString("grestore\n")
+		}
+	}
+	if hasStroke {
+		fmt.Fprintf(b, "%s setrgbcolor %s setlinewidth %d setlinecap %d setlinejoin %s setmiterlimit\n",
+			pdfColor(stroke.R, stroke.G, stroke.B), pdfNumber(st.StrokeWidth()),
+			pdfLineCaps[st.LineCap()], pdfLineJoins[st.LineJoin()], pdfNumber(st.MiterLimit()))
+		dashes, offset := dashPattern(s, st)
+		if dashes != nil {
+			ds := make([]string, len(dashes))
+			for i, d := range dashes {
+				ds[i] = pdfNumber(d)
+			}
+			fmt.Fprintf(b, "[%s] %s setdash\n", strings.Join(ds, " "), pdfNumber(offset))
+		}
+		b.WriteString("stroke\n")
+	}
+	b.WriteString("grestore\n")
+	return nil
+}
+
+// epsPath returns the path construction operators for sps.  PostScript uses
+// the same number syntax as PDF.
+func epsPath(sps []SubPath) string {
+	var b strings.Builder
+	for _, sp := range sps {
+		segs, closed := closePathSegments(sp)
+		if len(segs) == 0 {
+			continue
+		}
+		fmt.Fprintf(&b, "%s moveto\n", pdfCoords(segs[0].start))
+		for _, s := range segs {
+			switch s.kind {
+			case 'L':
+				fmt.Fprintf(&b, "%s lineto\n", pdfCoords(s.end))
+			case 'Q':
+				c := s.quad().Elevate()
+				fmt.Fprintf(&b, "%s curveto\n", pdfCoords(c.P1, c.P2, c.P3))
+			case 'C':
+				fmt.Fprintf(&b, "%s curveto\n", pdfCoords(s.ctrl1, s.ctrl2, s.end))
+			case 'A':
+				ea := s.ellipse()
+				if ea.isCircular() {
+					// Angles increase towards the positive y axis in both
+					// SVG and PostScript, so a positive sweep is arc.
+					op := "arc"
+					if ea.delta < 0 {
+						op = "arcn"
+					}
+					a0 := (ea.theta + ea.phi) * 180 / math.Pi
+					a1 := (ea.theta + ea.phi + ea.delta) * 180 / math.Pi
+					fmt.Fprintf(&b, "%s %s %s %s %s\n", pdfCoords(ea.center), pdfNumber(ea.rx),
+						pdfNumber(a0), pdfNumber(a1), op)
+					continue
+				}
+				cs := ea.cubics()
+				cs[len(cs)-1].P3 = s.end
+				for _, c := range cs {
+					fmt.Fprintf(&b, "%s curveto\n", pdfCoords(c.P1, c.P2, c.P3))
+				}
+			}
+		}
+		if closed {
+			b.WriteString("closepath\n")
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,84 @@
+// Copyright 2018 Joe Beda
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package svgdata
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMarshalEPS(t *testing.T) {
+	assert := assert.New(t)
+
+	r := mustUnmarshal(t, `<svg xmlns="http://www.w3.org/2000/svg" width="100" height="50">
+  <g transform="translate(10 5)">
+    <rect width="20" height="10" fill="#00ff00" stroke="red" stroke-width="2" stroke-linecap="round"/>
+  </g>
+  <path d="M40 10 A10 10 0 0 1 60 10 A10 5 0 0 0 80 10" fill="none" stroke="blue" stroke-dasharray="3"/>
+  <path d="M0 0 Q5 10 10 0 Z" fill-rule="evenodd"/>
+  <rect width="5" height="5" display="none"/>
+</svg>`)
+	data, err := MarshalEPS(r)
+	if !assert.NoError(err) {
+		return
+	}
+	assert.Equal(`%!PS-Adobe-3.0 EPSF-3.0
+%%BoundingBox: 0 0 75 38
+%%HiResBoundingBox: 0 0 75 37.5
+%%LanguageLevel: 2
+%%Pages: 1
+%%EndComments
+%%Page: 1 1
+gsave
+0 0 75 37.5 rectclip
+[0.75 0 0 -0.75 0 37.5] concat
+gsave
+[1 0 0 1 10 5] concat
+newpath
+0 0 moveto
+20 0 lineto
+20 10 lineto
+0 10 lineto
+closepath
+gsave 0 1 0 setrgbcolor fill
+grestore
+1 0 0 setrgbcolor 2 setlinewidth 1 setlinecap 0 setlinejoin 4 setmiterlimit
+stroke
+grestore
+gsave
+[1 0 0 1 0 0] concat
+newpath
+40 10 moveto
+50 10 10 180 360 arc
+60 12.761424 64.477153 15 70 15 curveto
+75.522847 15 80 12.761424 80 10 curveto
+0 0 1 setrgbcolor 1 setlinewidth 0 setlinecap 0 setlinejoin 4 setmiterlimit
+[3] 0 setdash
+stroke
+grestore
+gsave
+[1 0 0 1 0 0] concat
+newpath
+0 0 moveto
+3.333333 6.666667 6.666667 6.666667 10 0 curveto
+closepath
+0 0 0 setrgbcolor eofill
+grestore
+grestore
+showpage
+%%EOF
+`, string(data))
+}
@@ -0,0 +1,168 @@
+// Copyright 2018 Joe Beda
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package svgdata
+
+import (
+	"math"
+
+	"github.com/jbeda/geom"
+)
+
+// minTolerance keeps flattening from running away when given a zero or
+// negative tolerance.
+const minTolerance = 1e-6
+
+// maxSubdivisions bounds the recursion when flattening a curve.
+const maxSubdivisions = 16
+
+// Polyline is a sequence of points joined by straight lines.  For a closed
+// polyline the segment from the last point back to the first is implied and
+// the first point is not repeated at the end.
+type Polyline struct {
+	Points []geom.Coord
+	Closed bool
+}
+
+// Flatten approximates the subpath with straight lines.  No point on the
+// original subpath will be further than tol from the resulting polyline.
+func (sp SubPath) Flatten(tol float64) Polyline {
+	tol = math.Max(tol, minTolerance)
+
+	pl := Polyline{
+		Points: []geom.Coord{sp.Start()},
+		Closed: sp.IsClosed(),
+	}
+	for _, s := range subPathSegments(sp) {
+		pl.Points = s.flatten(tol, pl.Points)
+	}
+
+	if pl.Closed && len(pl.Points) > 1 && pl.Points[len(pl.Points)-1] == pl.Points[0] {
+		pl.Points = pl.Points[:len(pl.Points)-1]
+	}
+	return pl
+}
+
+// Flatten approximates each subpath of the path with a polyline.
+func (p *Path) Flatten(tol float64) []Polyline {
+	return FlattenSubPaths(p.SubPaths, tol)
+}
+
+// FlattenSubPaths approximates each subpath with a polyline.
+func FlattenSubPaths(sps []SubPath, tol float64) []Polyline {
+	var r []Polyline
+	for _, sp := range sps {
+		r = append(r, sp.Flatten(tol))
+	}
+	return r
+}
+
+// FlattenShape approximates the geometry of any shape node with polylines.
+func FlattenShape(s Shape, tol float64) []Polyline {
+	return FlattenSubPaths(s.ToSubPaths(), tol)
+}
+
+// FlattenRoot replaces every shape in the document, including rects, circles,
+// ellipses, lines and polyshapes, with a path made up only of straight lines.
+// Attributes of the original nodes are kept.
+func FlattenRoot(r *Root, tol float64) {
+	replaceShapes(r, func(s Shape) Node {
+		return newPathFromNode(s, PolylinesToSubPaths(FlattenShape(s, tol)))
+	})
+}
+
+// ToSubPath converts the polyline into a SubPath of M, L and Z commands.
+func (pl Polyline) ToSubPath() SubPath {
+	return BuildSubPaths(pl.commands())[0]
+}
+
+func (pl Polyline) commands() []PathCommand {
+	var cmds []PathCommand
+	for i, p := range pl.Points {
+		c := PathCommand{Command: 'L', Params: []float64{p.X, p.Y}}
+		if i == 0 {
+			c.Command = 'M'
+		}
+		cmds = append(cmds, c)
+	}
+	if pl.Closed {
+		cmds = append(cmds, PathCommand{Command: 'Z'})
+	}
+	return cmds
+}
+
+// PolylinesToSubPaths converts a set of polylines into SubPaths.  Empty
+// polylines are dropped.
+func PolylinesToSubPaths(pls []Polyline) []SubPath {
+	var cmds []PathCommand
+	for _, pl := range pls {
+		if len(pl.Points) == 0 {
+			continue
+		}
+		cmds = append(cmds, pl.commands()...)
+	}
+	return BuildSubPaths(cmds)
+}
+
+// flatten appends points approximating the segment to pts.  The start point of
+// the segment is assumed to already be in pts.
+func (s segment) flatten(tol float64, pts []geom.Coord) []geom.Coord {
+	switch s.kind {
+	case 'Q':
+		return flattenCubic(s.quad().Elevate(), tol, 0, pts)
+	case 'C':
+		return flattenCubic(s.cubic(), tol, 0, pts)
+	case 'A':
+		ea := s.ellipse()
+		n := ea.steps(tol)
+		for i := 1; i < n; i++ {
+			pts = append(pts, ea.point(ea.theta+ea.delta*float64(i)/float64(n)))
+		}
+	}
+	return append(pts, s.end)
+}
+
+// flattenCubic subdivides a cubic Bézier until its control points are within
+// tol of its chord.  As the curve lies within the hull of its control points
+// this bounds the distance between the curve and the chord.
+func flattenCubic(c CubicBezier, tol float64, depth int, pts []geom.Coord) []geom.Coord {
+	if depth >= maxSubdivisions ||
+		(pointSegmentDistOnly(c.P1, c.P0, c.P3) <= tol && pointSegmentDistOnly(c.P2, c.P0, c.P3) <= tol) {
+		return append(pts, c.P3)
+	}
+
+	a, b := c.Split(0.5)
+	pts = flattenCubic(a, tol, depth+1, pts)
+	return flattenCubic(b, tol, depth+1, pts)
+}
+
+func pointSegmentDistOnly(p, a, b geom.Coord) float64 {
+	d, _ := pointSegmentDist(p, a, b)
+	return d
+}
+
+// steps returns the number of equal angle chords needed so that the chords
+// are within tol of the arc.
+func (ea ellipseArc) steps(tol float64) int {
+	r := math.Max(ea.rx, ea.ry)
+	if r <= tol {
+		return 1
+	}
+	step := 2 * math.Acos(1-tol/r)
+	n := int(math.Ceil(math.Abs(ea.delta) / step))
+	if n < 1 {
+		n = 1
+	}
+	return n
+}
@@ -0,0 +1,139 @@
+// Copyright 2018 Joe Beda
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package svgdata
+
+import (
+	"math"
+	"testing"
+
+	"github.com/jbeda/geom"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFlattenLines(t *testing.T) {
+	assert := assert.New(t)
+
+	sps, err := ParsePathString("M0,0 h10 v10 H0 z m20,0 l5,5")
+	assert.NoError(err)
+	assert.Len(sps, 2)
+
+	pl := sps[0].Flatten(0.1)
+	assert.True(pl.Closed)
+	assert.Equal([]geom.Coord{{X: 0, Y: 0}, {X: 10, Y: 0}, {X: 10, Y: 10}, {X: 0, Y: 10}}, pl.Points)
+
+	pl = sps[1].Flatten(0.1)
+	assert.False(pl.Closed)
+	assert.Equal([]geom.Coord{{X: 20, Y: 0}, {X: 25, Y: 5}}, pl.Points)
+}
+
+func TestFlattenCurvesWithinTolerance(t *testing.T) {
+	assert := assert.New(t)
+
+	sps, err := ParsePathString("M0,0 C0,50 100,50 100,0 S200,-50 200,0 Q250,50 300,0 T400,0")
+	assert.NoError(err)
+
+	for _, tol := range []float64{1, 0.1, 0.01} {
+		pl := sps[0].Flatten(tol)
+		segs := subPathSegments(sps[0])
+		for _, s := range segs {
+			for i := 0; i <= 100; i++ {
+				p := s.point(float64(i) / 100)
+				assert.True(polylineDistTo(pl, p) <= tol+1e-9, "tol %v point %v", tol, p)
+			}
+		}
+	}
+
+	// Tighter tolerances should never produce fewer points.
+	assert.True(len(sps[0].Flatten(0.01).Points) > len(sps[0].Flatten(1).Points))
+}
+
+func TestFlattenArc(t *testing.T) {
+	assert := assert.New(t)
+
+	sps, err := ParsePathString("M10,0 A10,10 0 0 1 -10,0")
+	assert.NoError(err)
+
+	pl := sps[0].Flatten(0.01)
+	for _, p := range pl.Points {
+		assert.InDelta(10, math.Hypot(p.X, p.Y), 1e-9)
+		assert.True(p.Y >= -1e-9)
+	}
+	assert.Equal(geom.Coord{X: -10, Y: 0}, pl.Points[len(pl.Points)-1])
+	for i := 1; i < len(pl.Points); i++ {
+		mid := coordLerp(pl.Points[i-1], pl.Points[i], 0.5)
+		assert.True(10-math.Hypot(mid.X, mid.Y) <= 0.01)
+	}
+}
+
+func TestFlattenShapes(t *testing.T) {
+	assert := assert.New(t)
+
+	pls := FlattenShape(NewCircle(geom.Coord{X: 5, Y: 5}, 5), 0.05)
+	assert.Len(pls, 1)
+	assert.True(pls[0].Closed)
+	assert.True(len(pls[0].Points) > 8)
+
+	pls = FlattenShape(NewRectXYWH(1, 2, 3, 4), 0.05)
+	assert.Len(pls, 1)
+	assert.Equal([]geom.Coord{{X: 1, Y: 2}, {X: 4, Y: 2}, {X: 4, Y: 6}, {X: 1, Y: 6}}, pls[0].Points)
+
+	pls = FlattenShape(NewPolyline([]geom.Coord{{X: 0, Y: 0}, {X: 1, Y: 1}}), 0.05)
+	assert.Len(pls, 1)
+	assert.False(pls[0].Closed)
+}
+
+func TestFlattenRoot(t *testing.T) {
+	assert := assert.New(t)
+
+	r, err := Unmarshal([]byte(`<svg xmlns="http://www.w3.org/2000/svg"><g><circle cx="0" cy="0" r="10" fill="red"></circle></g><path d="M0,0 Q5,5 10,0"></path></svg>`))
+	assert.NoError(err)
+
+	FlattenRoot(r, 0.1)
+
+	g := (*r.Children())[0]
+	p, ok := (*g.Children())[0].(*Path)
+	assert.True(ok)
+	assert.Equal("red", p.Attrs()["fill"])
+	assert.True(p.SubPaths[0].IsClosed())
+
+	p, ok = (*r.Children())[1].(*Path)
+	assert.True(ok)
+	for _, sp := range p.SubPaths {
+		for _, c := range sp.Commands {
+			assert.Contains("MLZ", string(c.Command))
+		}
+	}
+
+	// Lines and ellipses are shapes too.
+	r, err = Unmarshal([]byte(`<svg xmlns="http://www.w3.org/2000/svg">
+  <line id="l" x1="1" y1="2" x2="3" y2="4" stroke="red"/>
+  <ellipse id="e" cx="5" cy="5" rx="4" ry="2"/>
+  <ellipse id="c" cx="5" cy="5" ry="3"/>
+</svg>`))
+	assert.NoError(err)
+	assert.InDelta(math.Pi*8, subPathsArea(FindByID(r, "e").(Shape).ToSubPaths()), 0.05)
+	assert.InDelta(math.Pi*9, subPathsArea(FindByID(r, "c").(Shape).ToSubPaths()), 0.05)
+	FlattenRoot(r, 0.01)
+	for _, c := range *r.Children() {
+		p, ok := c.(*Path)
+		if assert.True(ok, c.Name()) {
+			assert.NotContains(p.Attrs(), "rx")
+		}
+	}
+	l := FindByID(r, "l").(*Path)
+	assert.Equal("M1 2L3 4", SavePathString(l.SubPaths))
+	assert.Equal("red", l.Attrs()["stroke"])
+	assert.True(FindByID(r, "e").(*Path).SubPaths[0].IsClosed())
+}
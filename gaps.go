@@ -0,0 +1,183 @@
+// Copyright 2018 Joe Beda
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package svgdata
+
+import (
+	"math"
+	"sort"
+
+	"github.com/jbeda/geom"
+)
+
+// GapOptions control how CloseGaps joins open subpaths.
+type GapOptions struct {
+	// SnapTolerance is the largest distance between two open ends that are
+	// moved together and joined.
+	SnapTolerance float64
+
+	// BridgeTolerance is the largest distance between two open ends that are
+	// joined with a new line.  Gaps no larger than SnapTolerance are always
+	// snapped instead.  Zero disables bridging.
+	BridgeTolerance float64
+}
+
+// GapSnap records an open end that was moved onto another end.
+type GapSnap struct {
+	From, To geom.Coord
+}
+
+// GapBridge records a line that was added to join two open ends.
+type GapBridge struct {
+	From, To geom.Coord
+}
+
+// GapReport describes what CloseGaps changed and what it left open.
+type GapReport struct {
+	Snaps    []GapSnap
+	Bridges  []GapBridge
+	OpenEnds []geom.Coord // Ends that are still open afterwards
+}
+
+// CloseGaps joins open subpaths whose ends nearly meet.  The closest pairs of
+// ends are joined first, reversing subpaths where needed, and a subpath whose
+// ends nearly meet is closed.  Ends that meet exactly are joined without being
+// reported.
+func CloseGaps(sps []SubPath, opts GapOptions) ([]SubPath, *GapReport) {
+	var chains []*gapChain
+	var ends []*gapEnd
+	for _, sp := range sps {
+		segs := subPathSegments(sp)
+		if len(segs) == 0 {
+			continue
+		}
+		c := &gapChain{segs: segs, closed: sp.IsClosed()}
+		chains = append(chains, c)
+		if c.closed {
+			continue
+		}
+		c.start = &gapEnd{p: segs[0].start, chain: c}
+		c.end = &gapEnd{p: segs[len(segs)-1].end, chain: c}
+		ends = append(ends, c.start, c.end)
+	}
+
+	limit := math.Max(opts.SnapTolerance, opts.BridgeTolerance)
+	type candidate struct {
+		a, b *gapEnd
+		d    float64
+	}
+	var cands []candidate
+	for i, a := range ends {
+		for _, b := range ends[i+1:] {
+			if d := coordDist(a.p, b.p); d <= limit {
+				cands = append(cands, candidate{a, b, d})
+			}
+		}
+	}
+	sort.SliceStable(cands, func(i, j int) bool { return cands[i].d < cands[j].d })
+
+	report := &GapReport{}
+	for _, cd := range cands {
+		a, b := cd.a, cd.b
+		if a.joined || b.joined {
+			continue
+		}
+		if a.chain == b.chain && len(a.chain.segs) == 1 && a.chain.segs[0].kind == 'L' {
+			// A lone line can't be closed on itself.
+			continue
+		}
+		a.joined, b.joined = true, true
+		bridge := cd.d > opts.SnapTolerance
+		switch {
+		case bridge:
+			report.Bridges = append(report.Bridges, GapBridge{From: a.p, To: b.p})
+		case cd.d > geomEpsilon:
+			report.Snaps = append(report.Snaps, GapSnap{From: b.p, To: a.p})
+		}
+		joinGapEnds(a, b, bridge)
+	}
+
+	var r []SubPath
+	for _, c := range chains {
+		if c.dead {
+			continue
+		}
+		if !c.closed {
+			report.OpenEnds = append(report.OpenEnds, c.segs[0].start, c.segs[len(c.segs)-1].end)
+		}
+		r = append(r, BuildSubPaths(segmentsToCommands(c.segs, c.closed))...)
+	}
+	return r, report
+}
+
+// CloseGaps joins open subpaths of the path whose ends nearly meet.  See
+// CloseGaps.
+func (p *Path) CloseGaps(opts GapOptions) *GapReport {
+	sps, report := CloseGaps(p.SubPaths, opts)
+	p.SubPaths = sps
+	return report
+}
+
+// gapChain is a run of connected segments being joined by CloseGaps.
+type gapChain struct {
+	segs       []segment
+	start, end *gapEnd
+	closed     bool
+	dead       bool // Merged into another chain
+}
+
+// gapEnd is one of the open ends of a chain.
+type gapEnd struct {
+	p      geom.Coord
+	chain  *gapChain
+	joined bool
+}
+
+// reverse flips the direction of the chain.
+func (c *gapChain) reverse() {
+	c.segs = reverseSegments(c.segs)
+	c.start, c.end = c.end, c.start
+}
+
+// joinGapEnds connects end a to end b, moving b onto a or adding a line
+// between them.
+func joinGapEnds(a, b *gapEnd, bridge bool) {
+	ca, cb := a.chain, b.chain
+	if ca.end != a {
+		ca.reverse()
+	}
+	if ca == cb {
+		// Both ends of one chain, so close it.
+		if bridge {
+			ca.segs = append(ca.segs, segment{kind: 'L', start: a.p, end: b.p})
+		} else {
+			ca.segs[0].start = a.p
+		}
+		ca.start, ca.end, ca.closed = nil, nil, true
+		return
+	}
+
+	if cb.start != b {
+		cb.reverse()
+	}
+	if bridge {
+		ca.segs = append(ca.segs, segment{kind: 'L', start: a.p, end: b.p})
+	} else {
+		cb.segs[0].start = a.p
+	}
+	ca.segs = append(ca.segs, cb.segs...)
+	ca.end = cb.end
+	ca.end.chain = ca
+	cb.dead = true
+}
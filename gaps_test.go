@@ -0,0 +1,73 @@
+// Copyright 2018 Joe Beda
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package svgdata
+
+import (
+	"testing"
+
+	"github.com/jbeda/geom"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCloseGapsSnap(t *testing.T) {
+	assert := assert.New(t)
+
+	sps, report := CloseGaps(mustParsePath(t, "M0,0 L10,0 M10.001,0 L5,8 M5,8.0005 L0,0.0008"),
+		GapOptions{SnapTolerance: 0.01})
+	if assert.Len(sps, 1) {
+		assert.True(sps[0].IsClosed())
+		assert.Equal("M10 0L5 8L0 0Z", SavePathString(sps))
+	}
+	assert.Len(report.Snaps, 3)
+	assert.Empty(report.Bridges)
+	assert.Empty(report.OpenEnds)
+	assert.Contains(report.Snaps, GapSnap{From: geom.Coord{X: 10.001, Y: 0}, To: geom.Coord{X: 10, Y: 0}})
+}
+
+func TestCloseGapsReverse(t *testing.T) {
+	assert := assert.New(t)
+
+	sps, report := CloseGaps(mustParsePath(t, "M0,0 L10,0 M20,0 L10.001,0"), GapOptions{SnapTolerance: 0.01})
+	assert.Equal("M0 0L10 0L20 0", SavePathString(sps))
+	assert.Len(report.Snaps, 1)
+	assert.ElementsMatch([]geom.Coord{{X: 0, Y: 0}, {X: 20, Y: 0}}, report.OpenEnds)
+
+	// Ends that already meet are joined without a report.
+	sps, report = CloseGaps(mustParsePath(t, "M0,0 L10,0 M10,0 L10,10"), GapOptions{SnapTolerance: 0.01})
+	assert.Equal("M0 0L10 0L10 10", SavePathString(sps))
+	assert.Empty(report.Snaps)
+}
+
+func TestCloseGapsBridge(t *testing.T) {
+	assert := assert.New(t)
+
+	d := "M0,0 L10,0 M10.5,0 L20,0"
+	sps, report := CloseGaps(mustParsePath(t, d), GapOptions{SnapTolerance: 0.01})
+	assert.Len(sps, 2)
+	assert.Len(report.OpenEnds, 4)
+
+	sps, report = CloseGaps(mustParsePath(t, d), GapOptions{SnapTolerance: 0.01, BridgeTolerance: 1})
+	assert.Equal("M0 0L10 0L10.5 0L20 0", SavePathString(sps))
+	assert.Equal([]GapBridge{{From: geom.Coord{X: 10, Y: 0}, To: geom.Coord{X: 10.5, Y: 0}}}, report.Bridges)
+	assert.Empty(report.Snaps)
+	assert.Len(report.OpenEnds, 2)
+
+	// The closest ends are joined first.
+	p := NewPath()
+	p.SubPaths = mustParsePath(t, "M0,0 L10,0 M10.2,0 L20,0 M10.1,0.5 L10.1,5")
+	report = p.CloseGaps(GapOptions{BridgeTolerance: 1})
+	assert.Equal("M0 0L10 0L10.2 0L20 0M10.1 0.5L10.1 5", SavePathString(p.SubPaths))
+	assert.Len(report.Bridges, 1)
+}
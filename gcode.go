@@ -0,0 +1,387 @@
+// Copyright 2018 Joe Beda
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package svgdata
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"github.com/jbeda/geom"
+	"github.com/pkg/errors"
+)
+
+// GCodeDialect is the flavor of G-code understood by a machine's firmware.
+type GCodeDialect int
+
+const (
+	GRBL GCodeDialect = iota
+	Marlin
+)
+
+// GCodeTool describes how to cut the shapes on a layer or with a stroke
+// color.
+type GCodeTool struct {
+	// Feed is the cutting feed rate in units per minute.
+	Feed float64
+
+	// PlungeFeed is the feed rate for moving down in Z.  Zero uses Feed.
+	PlungeFeed float64
+
+	// Power is the laser power or spindle speed set with S.
+	Power float64
+
+	// Depth is how far below Z zero to cut.  A tool with no depth is treated
+	// as a laser that is switched on for each cut and off between cuts.
+	// Otherwise the spindle runs for as long as the tool is in use and every
+	// cut starts with a plunge from SafeZ.
+	Depth float64
+
+	// StepDown is the deepest cut taken in one pass.  Zero cuts the full
+	// depth at once.
+	StepDown float64
+
+	// Passes is the smallest number of times each cut is made.  Depth is
+	// divided evenly between the passes.
+	Passes int
+}
+
+// passes returns the depth of each pass.
+func (t GCodeTool) passes() []float64 {
+	n := t.Passes
+	if t.Depth > 0 && t.StepDown > 0 {
+		if m := int(math.Ceil(t.Depth/t.StepDown - geomEpsilon)); m > n {
+			n = m
+		}
+	}
+	if n < 1 {
+		n = 1
+	}
+	r := make([]float64, n)
+	for i := range r {
+		r[i] = t.Depth * float64(i+1) / float64(n)
+	}
+	return r
+}
+
+// GCodeOptions control how a document is converted to G-code.
+type GCodeOptions struct {
+	Dialect GCodeDialect
+
+	// Units is "mm" or "in".  The default is "mm".
+	Units string
+
+	// Tolerance is the largest distance curves may deviate when flattened,
+	// in output units.  Circular arcs are written as arcs.
+	Tolerance float64
+
+	// SafeZ is the height for moves between cuts with tools that have a
+	// depth.
+	SafeZ float64
+
+	// Tools are looked up first by the id of the top level group that a
+	// shape is in and then by its stroke color.
+	Tools map[string]GCodeTool
+
+	// DefaultTool is used for shapes that don't match any of Tools.  If it is
+	// nil those shapes are left out.
+	DefaultTool *GCodeTool
+
+	// Header and Footer are text/template templates written at the start and
+	// end of the program.  They are given a GCodeTemplateData.  Empty
+	// templates use the defaults for the dialect.
+	Header, Footer string
+}
+
+// GCodeTemplateData is passed to the header and footer templates.
+type GCodeTemplateData struct {
+	Units         string  // G20 or G21
+	Width, Height float64 // Size of the document in output units, rounded like coordinates
+}
+
+var gcodeTemplates = map[GCodeDialect][2]string{
+	GRBL:   {"G90\n{{.Units}}\nG17\nG94\nM5\n", "G0 X0 Y0\nM2\n"},
+	Marlin: {"G90\n{{.Units}}\nM5\n", "G0 X0 Y0\nM84\n"},
+}
+
+// MarshalGCode converts the shapes in the document to G-code for a laser or
+// CNC machine.  Shapes are cut in document order with each subpath cut in the
+// direction it is drawn, so the document should be chained and ordered first.
+// Lines become G1 moves, circular arcs become G2 and G3 moves and everything
+// else is flattened.  Shapes hidden with display none are left out.
+//
+// The document is placed with the bottom left of its viewport at the origin
+// and the Y axis pointing up, and scaled to its physical size.
+func MarshalGCode(r *Root, opts GCodeOptions) ([]byte, error) {
+	units := "G21"
+	pxPerUnit := dpi / mmPerInch
+	prec := 3
+	switch opts.Units {
+	case "", "mm":
+	case "in":
+		units, pxPerUnit, prec = "G20", dpi, 4
+	default:
+		return nil, errors.Errorf("unsupported G-code units: %s", opts.Units)
+	}
+	templates, ok := gcodeTemplates[opts.Dialect]
+	if !ok {
+		return nil, errors.Errorf("unknown G-code dialect %d", opts.Dialect)
+	}
+	header, footer := opts.Header, opts.Footer
+	if header == "" {
+		header = templates[0]
+	}
+	if footer == "" {
+		footer = templates[1]
+	}
+
+	vb, pxPerUser, err := documentViewport(r)
+	if err != nil {
+		return nil, err
+	}
+	k := pxPerUser / pxPerUnit
+	toMachine := NewScale(k, -k).Multiply(NewTranslate(-vb.Min.X, -vb.Max.Y))
+	round := func(v float64) float64 {
+		p := math.Pow(10, float64(prec))
+		return math.Round(v*p) / p
+	}
+	data := GCodeTemplateData{Units: units, Width: round(vb.Width() * k), Height: round(vb.Height() * k)}
+
+	w := &gcodeWriter{opts: opts, prec: prec, feed: -1}
+	if err := w.template("header", header, data); err != nil {
+		return nil, err
+	}
+	err = walkLayers(r, func(layer, n Node, ctm Transform, st Style) error {
+		s, ok := n.(Shape)
+		if !ok {
+			return nil
+		}
+		if v, _ := layer.Attrs().GetStyle("display"); v == "none" {
+			return nil
+		}
+		tool, ok := opts.Tools[layerID(layer)]
+		if !ok {
+			tool, ok = opts.Tools[st.Get("stroke", "none")]
+		}
+		if !ok {
+			if opts.DefaultTool == nil {
+				return nil
+			}
+			tool = *opts.DefaultTool
+		}
+		w.shape(s, toMachine.Multiply(ctm), tool)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	w.finish()
+	if err := w.template("footer", footer, data); err != nil {
+		return nil, err
+	}
+	return w.Bytes(), nil
+}
+
+// gcodeWriter tracks the state of the machine while writing moves so that
+// redundant commands can be left out.
+type gcodeWriter struct {
+	bytes.Buffer
+	opts GCodeOptions
+	prec int
+
+	tool     *GCodeTool
+	spindle  bool
+	feed     float64
+	pos      geom.Coord
+	posKnown bool
+	raised   bool
+	down     bool // The tool has plunged and not been raised since
+}
+
+func (w *gcodeWriter) template(name, text string, data GCodeTemplateData) error {
+	t, err := template.New(name).Parse(text)
+	if err != nil {
+		return errors.Wrapf(err, "invalid G-code %s template", name)
+	}
+	if err := t.Execute(w, data); err != nil {
+		return errors.Wrapf(err, "error executing G-code %s template", name)
+	}
+	if w.Len() > 0 && w.Bytes()[w.Len()-1] != '\n' {
+		w.WriteByte('\n')
+	}
+	return nil
+}
+
+func (w *gcodeWriter) num(v float64) string {
+	s := strconv.FormatFloat(v, 'f', w.prec, 64)
+	s = strings.TrimRight(strings.TrimRight(s, "0"), ".")
+	if s == "-0" {
+		return "0"
+	}
+	return s
+}
+
+func (w *gcodeWriter) line(format string, args ...interface{}) {
+	fmt.Fprintf(w, format+"\n", args...)
+}
+
+// feedWord returns the F word for a feed rate if it differs from the
+// current one.
+func (w *gcodeWriter) feedWord(f float64) string {
+	if f <= 0 || f == w.feed {
+		return ""
+	}
+	w.feed = f
+	return " F" + w.num(f)
+}
+
+// raise moves up to SafeZ if needed.
+func (w *gcodeWriter) raise() {
+	if !w.raised {
+		w.line("G0 Z%s", w.num(w.opts.SafeZ))
+		w.raised = true
+		w.down = false
+	}
+}
+
+// rapid moves to p without cutting.
+func (w *gcodeWriter) rapid(p geom.Coord) {
+	if w.posKnown && coordNear(w.pos, p, geomEpsilon) {
+		return
+	}
+	w.line("G0 X%s Y%s", w.num(p.X), w.num(p.Y))
+	w.pos, w.posKnown = p, true
+}
+
+// selectTool switches to t, stopping and starting the spindle as needed.
+func (w *gcodeWriter) selectTool(t GCodeTool) {
+	if w.tool != nil && *w.tool == t {
+		return
+	}
+	// Lift the last tool clear before moving on, even if the next one
+	// doesn't cut into the work.
+	if w.down {
+		w.raise()
+	}
+	if w.spindle {
+		w.line("M5")
+		w.spindle = false
+	}
+	w.tool = &t
+	if t.Depth > 0 {
+		w.raise()
+		w.line("M3 S%s", w.num(t.Power))
+		w.spindle = true
+	}
+}
+
+// laserOn returns the command that switches a laser on.  GRBL uses dynamic
+// power so the beam is weaker while the machine speeds up and slows down.
+func (w *gcodeWriter) laserOn() string {
+	if w.opts.Dialect == GRBL {
+		return "M4"
+	}
+	return "M3"
+}
+
+func (w *gcodeWriter) shape(s Shape, t Transform, tool GCodeTool) {
+	for _, sp := range s.ToSubPaths() {
+		var segs []segment
+		for _, seg := range subPathSegments(sp) {
+			segs = append(segs, seg.transform(t))
+		}
+		if len(segs) == 0 {
+			continue
+		}
+		w.selectTool(tool)
+		w.cut(segs, tool)
+	}
+}
+
+// cut makes every pass of tool along segs.
+func (w *gcodeWriter) cut(segs []segment, tool GCodeTool) {
+	start, end := segs[0].start, segs[len(segs)-1].end
+	closed := coordNear(start, end, geomEpsilon)
+	depth := tool.Depth > 0
+	plunge := tool.PlungeFeed
+	if plunge <= 0 {
+		plunge = tool.Feed
+	}
+
+	for i, d := range tool.passes() {
+		if i == 0 || !closed {
+			if depth || w.down {
+				w.raise()
+			}
+			w.rapid(start)
+		}
+		if depth {
+			w.line("G1 Z%s%s", w.num(-d), w.feedWord(plunge))
+			w.raised, w.down = false, true
+		} else {
+			w.line("%s S%s", w.laserOn(), w.num(tool.Power))
+		}
+		for _, s := range segs {
+			w.segment(s, tool.Feed)
+		}
+		if !depth {
+			w.line("M5")
+		}
+	}
+}
+
+// segment writes the moves for one segment.
+func (w *gcodeWriter) segment(s segment, feed float64) {
+	tol := math.Max(w.opts.Tolerance, minTolerance)
+	if s.kind == 'A' {
+		ea := s.ellipse()
+		// Arcs that are almost straight are better as lines since their
+		// centers are far away.
+		if ea.isCircular() && ea.rx*(1-math.Cos(ea.delta/2)) > tol {
+			g := "G3"
+			if ea.delta < 0 {
+				g = "G2"
+			}
+			c := coordSub(ea.center, s.start)
+			w.line("%s X%s Y%s I%s J%s%s", g, w.num(s.end.X), w.num(s.end.Y),
+				w.num(c.X), w.num(c.Y), w.feedWord(feed))
+			w.pos = s.end
+			return
+		}
+	}
+
+	pts := []geom.Coord{s.end}
+	if s.kind != 'L' {
+		pts = s.flatten(tol, nil)
+	}
+	for _, p := range pts {
+		w.line("G1 X%s Y%s%s", w.num(p.X), w.num(p.Y), w.feedWord(feed))
+	}
+	w.pos = s.end
+}
+
+// finish stops the spindle and lifts the tool clear of the work.
+func (w *gcodeWriter) finish() {
+	if w.tool != nil && w.tool.Depth > 0 {
+		w.raise()
+	}
+	if w.spindle {
+		w.line("M5")
+		w.spindle = false
+	}
+}
@@ -0,0 +1,189 @@
+// Copyright 2018 Joe Beda
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package svgdata
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func mustUnmarshal(t *testing.T, s string) *Root {
+	r, err := Unmarshal([]byte(s))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return r
+}
+
+func TestMarshalGCodeLaser(t *testing.T) {
+	assert := assert.New(t)
+
+	r := mustUnmarshal(t, `<svg xmlns="http://www.w3.org/2000/svg" width="100mm" height="100mm" viewBox="0 0 200 200">
+  <path d="M20,20 L40,20 L40,40 Z" stroke="red"/>
+  <circle cx="100" cy="100" r="20" stroke="blue"/>
+  <path d="M0,0 L10,10" stroke="green"/>
+</svg>`)
+	data, err := MarshalGCode(r, GCodeOptions{
+		Tools: map[string]GCodeTool{
+			"red":  {Feed: 1000, Power: 500},
+			"blue": {Feed: 600, Power: 1000, Passes: 2},
+		},
+	})
+	assert.NoError(err)
+	assert.Equal(`G90
+G21
+G17
+G94
+M5
+G0 X10 Y90
+M4 S500
+G1 X20 Y90 F1000
+G1 X20 Y80
+G1 X10 Y90
+M5
+G0 X60 Y50
+M4 S1000
+G2 X40 Y50 I-10 J0 F600
+G2 X60 Y50 I10 J0
+M5
+M4 S1000
+G2 X40 Y50 I-10 J0
+G2 X60 Y50 I10 J0
+M5
+G0 X0 Y0
+M2
+`, string(data))
+}
+
+func TestMarshalGCodeDepth(t *testing.T) {
+	assert := assert.New(t)
+
+	r := mustUnmarshal(t, `<svg xmlns="http://www.w3.org/2000/svg" width="100mm" height="100mm" viewBox="0 0 100 100">
+  <g id="Cut">
+    <path d="M0,100 L10,100"/>
+  </g>
+  <g id="Hidden" display="none">
+    <path d="M0,0 L10,10"/>
+  </g>
+</svg>`)
+	tool := GCodeTool{Feed: 300, PlungeFeed: 100, Power: 12000, Depth: 2.5, StepDown: 1}
+	data, err := MarshalGCode(r, GCodeOptions{
+		SafeZ:       5,
+		Tools:       map[string]GCodeTool{"Cut": tool},
+		DefaultTool: &GCodeTool{Feed: 1},
+		Header:      "; {{.Width}}x{{.Height}}",
+		Footer:      "M30",
+	})
+	assert.NoError(err)
+	assert.Equal(`; 100x100
+G0 Z5
+M3 S12000
+G0 X0 Y0
+G1 Z-0.833 F100
+G1 X10 Y0 F300
+G0 Z5
+G0 X0 Y0
+G1 Z-1.667 F100
+G1 X10 Y0 F300
+G0 Z5
+G0 X0 Y0
+G1 Z-2.5 F100
+G1 X10 Y0 F300
+G0 Z5
+M5
+M30
+`, string(data))
+}
+
+func TestMarshalGCodeMixedTools(t *testing.T) {
+	assert := assert.New(t)
+
+	// The mill is lifted before the laser moves to its first cut.
+	r := mustUnmarshal(t, `<svg xmlns="http://www.w3.org/2000/svg" width="100mm" height="100mm" viewBox="0 0 100 100">
+  <g id="Mill">
+    <path d="M0,100 L10,100"/>
+  </g>
+  <g id="Laser">
+    <path d="M50,50 L60,50"/>
+  </g>
+  <g id="Mill2">
+    <path d="M0,90 L10,90"/>
+  </g>
+</svg>`)
+	mill := GCodeTool{Feed: 300, Power: 12000, Depth: 2}
+	data, err := MarshalGCode(r, GCodeOptions{
+		SafeZ: 5,
+		Tools: map[string]GCodeTool{
+			"Mill":  mill,
+			"Laser": {Feed: 1000, Power: 500},
+			"Mill2": mill,
+		},
+	})
+	assert.NoError(err)
+	assert.Equal(`G90
+G21
+G17
+G94
+M5
+G0 Z5
+M3 S12000
+G0 X0 Y0
+G1 Z-2 F300
+G1 X10 Y0
+G0 Z5
+M5
+G0 X50 Y50
+M4 S500
+G1 X60 Y50 F1000
+M5
+M3 S12000
+G0 X0 Y10
+G1 Z-2 F300
+G1 X10 Y10
+G0 Z5
+M5
+G0 X0 Y0
+M2
+`, string(data))
+}
+
+func TestMarshalGCodeMarlin(t *testing.T) {
+	assert := assert.New(t)
+
+	// A curve is flattened and an arc is written as an arc, in inches.
+	r := mustUnmarshal(t, `<svg xmlns="http://www.w3.org/2000/svg" width="2in" height="1in">
+  <path d="M0,96 Q96,0 192,96"/>
+  <path d="M0,96 A96,96 0 0 0 192,96"/>
+</svg>`)
+	data, err := MarshalGCode(r, GCodeOptions{
+		Dialect:     Marlin,
+		Units:       "in",
+		Tolerance:   0.01,
+		DefaultTool: &GCodeTool{Feed: 50, Power: 255},
+	})
+	assert.NoError(err)
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	assert.Equal([]string{"G90", "G20", "M5", "G0 X0 Y0", "M3 S255"}, lines[:5])
+	assert.Contains(lines, "G3 X2 Y0 I1 J0")
+	assert.Equal([]string{"M5", "G0 X0 Y0", "M84"}, lines[len(lines)-3:])
+	assert.True(strings.Count(string(data), "G1 ") > 4)
+
+	_, err = MarshalGCode(r, GCodeOptions{Units: "furlong"})
+	assert.Error(err)
+	_, err = MarshalGCode(r, GCodeOptions{Header: "{{.Nope"})
+	assert.Error(err)
+}
@@ -0,0 +1,230 @@
+// Copyright 2018 Joe Beda
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package svgdata
+
+import (
+	"github.com/jbeda/geom"
+	"github.com/pkg/errors"
+)
+
+// GeoOptions control conversion between shapes and the geometry used by GIS
+// formats such as GeoJSON and WKT.
+type GeoOptions struct {
+	// Transform maps user units to geographic coordinates, for example
+	// NewScale(0.01, -0.01) to convert centimeters to meters and flip the y
+	// axis to point up.  Its inverse is used when importing.  The zero value
+	// means no transform.
+	Transform Transform
+
+	// Tolerance is the largest distance curves may deviate when flattened,
+	// in user units.  Zero means 0.1.
+	Tolerance float64
+}
+
+func (opts GeoOptions) transform() Transform {
+	if opts.Transform == (Transform{}) {
+		return IdentityTransform
+	}
+	return opts.Transform
+}
+
+func (opts GeoOptions) tolerance() float64 {
+	if opts.Tolerance <= 0 {
+		return 0.1
+	}
+	return opts.Tolerance
+}
+
+// Kinds of geoGeometry.
+const (
+	geoLineString      = "LineString"
+	geoMultiLineString = "MultiLineString"
+	geoPolygon         = "Polygon"
+	geoMultiPolygon    = "MultiPolygon"
+)
+
+// geoGeometry is geometry in the simple features model shared by GeoJSON and
+// WKT.  lines holds the parts of line strings and polygons holds the rings
+// of polygons, exterior ring first.  Rings repeat their first point at the
+// end.
+type geoGeometry struct {
+	kind     string
+	lines    [][]geom.Coord
+	polygons [][][]geom.Coord
+}
+
+// shapeGeometry flattens s, transformed by t, into lines or polygons.  If
+// every subpath is closed the result is polygons, with rings nested inside
+// others becoming holes.  Otherwise every subpath becomes a line.
+func shapeGeometry(s Shape, t Transform, tol float64) (geoGeometry, error) {
+	var pls []Polyline
+	closed := true
+	for _, pl := range FlattenShape(s, tol) {
+		if len(pl.Points) < 2 {
+			continue
+		}
+		pls = append(pls, pl.transform(t))
+		closed = closed && pl.Closed && len(pl.Points) >= 3
+	}
+	if len(pls) == 0 {
+		return geoGeometry{}, errors.Errorf("%s has no geometry", s.Name())
+	}
+
+	if !closed {
+		g := geoGeometry{kind: geoLineString}
+		if len(pls) > 1 {
+			g.kind = geoMultiLineString
+		}
+		for _, pl := range pls {
+			pts := pl.Points
+			if pl.Closed {
+				pts = append(pts, pts[0])
+			}
+			g.lines = append(g.lines, pts)
+		}
+		return g, nil
+	}
+
+	// A ring's depth is the number of other rings around it.  Rings at even
+	// depths are exteriors and the rest are holes in the ring of the next
+	// smaller depth that contains them.
+	depth := make([]int, len(pls))
+	for i, pl := range pls {
+		for j, other := range pls {
+			if i != j && other.windingNumber(pl.Points[0]) != 0 {
+				depth[i]++
+			}
+		}
+	}
+	ring := func(pl Polyline, exterior bool) []geom.Coord {
+		pts := append([]geom.Coord(nil), pl.Points...)
+		// Exterior rings are counterclockwise and holes clockwise as
+		// GeoJSON recommends.  Rings are reversed about their first point.
+		if (polylineArea(pts) > 0) != exterior {
+			for i, j := 1, len(pts)-1; i < j; i, j = i+1, j-1 {
+				pts[i], pts[j] = pts[j], pts[i]
+			}
+		}
+		return append(pts, pts[0])
+	}
+	g := geoGeometry{kind: geoPolygon}
+	index := map[int]int{}
+	for i, pl := range pls {
+		if depth[i]%2 == 0 {
+			index[i] = len(g.polygons)
+			g.polygons = append(g.polygons, [][]geom.Coord{ring(pl, true)})
+		}
+	}
+	for i, pl := range pls {
+		if depth[i]%2 == 0 {
+			continue
+		}
+		for j, other := range pls {
+			if depth[j] == depth[i]-1 && other.windingNumber(pl.Points[0]) != 0 {
+				k := index[j]
+				g.polygons[k] = append(g.polygons[k], ring(pl, false))
+				break
+			}
+		}
+	}
+	if len(g.polygons) > 1 {
+		g.kind = geoMultiPolygon
+	}
+	return g, nil
+}
+
+// node converts the geometry, transformed by t, to a shape.  A line string
+// becomes a polyline and a polygon without holes a polygon.  Everything else
+// becomes a path, using the evenodd fill rule for polygons so that holes
+// don't depend on ring orientation.  Lines are given a stroke and no fill so
+// that they are visible.
+func (g geoGeometry) node(t Transform) Node {
+	apply := func(pts []geom.Coord) []geom.Coord {
+		r := make([]geom.Coord, len(pts))
+		for i, p := range pts {
+			r[i] = t.Apply(p)
+		}
+		return r
+	}
+	// ring drops the repeated point at the end of a ring.
+	ring := func(pts []geom.Coord) Polyline {
+		if n := len(pts); n > 1 && pts[0] == pts[n-1] {
+			pts = pts[:n-1]
+		}
+		return Polyline{Points: apply(pts), Closed: true}
+	}
+
+	var n Node
+	switch {
+	case g.kind == geoLineString && len(g.lines) == 1:
+		n = NewPolyline(apply(g.lines[0]))
+	case g.kind == geoPolygon && len(g.polygons) == 1 && len(g.polygons[0]) == 1:
+		return NewPolygon(ring(g.polygons[0][0]).Points)
+	case len(g.lines) > 0:
+		var pls []Polyline
+		for _, l := range g.lines {
+			pls = append(pls, Polyline{Points: apply(l)})
+		}
+		p := NewPath()
+		p.SubPaths = PolylinesToSubPaths(pls)
+		n = p
+	default:
+		var pls []Polyline
+		for _, rings := range g.polygons {
+			for _, r := range rings {
+				pls = append(pls, ring(r))
+			}
+		}
+		p := NewPath()
+		p.SubPaths = PolylinesToSubPaths(pls)
+		p.Attrs()["fill-rule"] = "evenodd"
+		return p
+	}
+	n.Attrs()["fill"] = "none"
+	n.Attrs()["stroke"] = "black"
+	return n
+}
+
+// geoShapes calls fn with each shape at or under n and the transform from
+// its user space to geographic coordinates.  The transform of n itself is
+// included.
+func geoShapes(n Node, opts GeoOptions, fn func(s Shape, t Transform) error) error {
+	t, err := NodeTransform(n)
+	if err != nil {
+		return err
+	}
+	t = opts.transform().Multiply(t)
+	if s, ok := n.(Shape); ok {
+		if err := fn(s, t); err != nil {
+			return err
+		}
+	}
+	return walkPaint(n, t, NodeStyle(n), func(c Node, ctm Transform, st Style) error {
+		if s, ok := c.(Shape); ok {
+			return fn(s, ctm)
+		}
+		return nil
+	})
+}
+
+// inverse returns the transform from geographic coordinates to user
+// units.
+func (opts GeoOptions) inverse() (Transform, error) {
+	t, ok := opts.transform().Invert()
+	if !ok {
+		return Transform{}, errors.New("geographic transform is not invertible")
+	}
+	return t, nil
+}
@@ -0,0 +1,299 @@
+// Copyright 2018 Joe Beda
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package svgdata
+
+import (
+	"bytes"
+	"encoding/json"
+
+	"github.com/jbeda/geom"
+	"github.com/pkg/errors"
+)
+
+// geoJSONObject holds the members of any GeoJSON object that are used here.
+type geoJSONObject struct {
+	Type        string                     `json:"type"`
+	ID          json.RawMessage            `json:"id,omitempty"`
+	Geometry    json.RawMessage            `json:"geometry,omitempty"`
+	Properties  map[string]json.RawMessage `json:"properties,omitempty"`
+	Features    []json.RawMessage          `json:"features,omitempty"`
+	Coordinates json.RawMessage            `json:"coordinates,omitempty"`
+}
+
+type geoJSONFeature struct {
+	Type       string            `json:"type"`
+	ID         string            `json:"id,omitempty"`
+	Geometry   geoJSONGeometry   `json:"geometry"`
+	Properties map[string]string `json:"properties"`
+}
+
+type geoJSONGeometry struct {
+	Type        string      `json:"type"`
+	Coordinates interface{} `json:"coordinates"`
+}
+
+type geoJSONFeatureCollection struct {
+	Type     string           `json:"type"`
+	Features []geoJSONFeature `json:"features"`
+}
+
+// MarshalGeoJSON converts shapes to GeoJSON.  If n is a shape the result is
+// a Feature, otherwise it is a FeatureCollection of the shapes under n that
+// aren't hidden with display none.  Curves are flattened and transforms,
+// including the transform of n, are applied.  Closed shapes become Polygons
+// or MultiPolygons, with subpaths inside others becoming holes, and open
+// shapes become LineStrings or MultiLineStrings.  Shapes with no geometry are
+// left out of a FeatureCollection.  The id attribute becomes the feature id
+// and the other attributes become properties.
+func MarshalGeoJSON(n Node, opts GeoOptions) ([]byte, error) {
+	features := []geoJSONFeature{}
+	err := geoShapes(n, opts, func(s Shape, t Transform) error {
+		g, err := shapeGeometry(s, t, opts.tolerance())
+		if err != nil {
+			// Shapes without geometry, such as empty paths, are left out of
+			// collections.
+			if Node(s) == n {
+				return err
+			}
+			return nil
+		}
+		f := geoJSONFeature{
+			Type:       "Feature",
+			ID:         s.Attrs()["id"],
+			Geometry:   geoJSONGeometry{Type: g.kind, Coordinates: g.coordinates()},
+			Properties: map[string]string{},
+		}
+		for k, v := range s.Attrs() {
+			if k != "id" && k != "transform" {
+				f.Properties[k] = v
+			}
+		}
+		features = append(features, f)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var v interface{} = geoJSONFeatureCollection{Type: "FeatureCollection", Features: features}
+	if _, ok := n.(Shape); ok {
+		v = features[0]
+	}
+	b, err := json.Marshal(v)
+	return b, errors.WithStack(err)
+}
+
+// coordinates returns the geometry as nested arrays of positions.
+func (g geoGeometry) coordinates() interface{} {
+	positions := func(pts []geom.Coord) [][]float64 {
+		r := make([][]float64, len(pts))
+		for i, p := range pts {
+			r[i] = []float64{p.X, p.Y}
+		}
+		return r
+	}
+	rings := func(rs [][]geom.Coord) [][][]float64 {
+		r := make([][][]float64, len(rs))
+		for i, pts := range rs {
+			r[i] = positions(pts)
+		}
+		return r
+	}
+	switch g.kind {
+	case geoLineString:
+		return positions(g.lines[0])
+	case geoMultiLineString:
+		return rings(g.lines)
+	case geoPolygon:
+		return rings(g.polygons[0])
+	}
+	r := make([][][][]float64, len(g.polygons))
+	for i, p := range g.polygons {
+		r[i] = rings(p)
+	}
+	return r
+}
+
+// UnmarshalGeoJSON converts a GeoJSON FeatureCollection, Feature or geometry
+// to shapes.  LineStrings become polylines, Polygons without holes become
+// polygons and other geometries become paths.  Features without geometry, or
+// with points or geometry collections that have no shape equivalent, are
+// skipped.  Properties are set as attributes, with values other than strings
+// written as JSON, and the feature id becomes the id attribute.
+func UnmarshalGeoJSON(data []byte, opts GeoOptions) ([]Node, error) {
+	t, err := opts.inverse()
+	if err != nil {
+		return nil, err
+	}
+	var obj geoJSONObject
+	if err := json.Unmarshal(data, &obj); err != nil {
+		return nil, errors.Wrap(err, "invalid GeoJSON")
+	}
+
+	switch obj.Type {
+	case "FeatureCollection":
+		var r []Node
+		for _, raw := range obj.Features {
+			var f geoJSONObject
+			if err := json.Unmarshal(raw, &f); err != nil {
+				return nil, errors.Wrap(err, "invalid GeoJSON feature")
+			}
+			n, err := geoJSONFeatureNode(f, t)
+			if err != nil {
+				return nil, err
+			}
+			if n != nil {
+				r = append(r, n)
+			}
+		}
+		return r, nil
+	case "Feature":
+		n, err := geoJSONFeatureNode(obj, t)
+		if err != nil || n == nil {
+			return nil, err
+		}
+		return []Node{n}, nil
+	}
+	g, err := geoJSONGeometryOf(obj)
+	if err != nil {
+		return nil, err
+	}
+	return []Node{g.node(t)}, nil
+}
+
+// geoJSONSkipped holds the geometry types that features are skipped for.
+var geoJSONSkipped = map[string]bool{
+	"Point":              true,
+	"MultiPoint":         true,
+	"GeometryCollection": true,
+}
+
+// geoJSONFeatureNode converts a feature to a shape or returns nil if it has
+// no geometry or its geometry is skipped.
+func geoJSONFeatureNode(f geoJSONObject, t Transform) (Node, error) {
+	if f.Type != "Feature" {
+		return nil, errors.Errorf("expected GeoJSON Feature, got %s", f.Type)
+	}
+	if len(f.Geometry) == 0 || string(f.Geometry) == "null" {
+		return nil, nil
+	}
+	var obj geoJSONObject
+	if err := json.Unmarshal(f.Geometry, &obj); err != nil {
+		return nil, errors.Wrap(err, "invalid GeoJSON geometry")
+	}
+	if geoJSONSkipped[obj.Type] {
+		return nil, nil
+	}
+	g, err := geoJSONGeometryOf(obj)
+	if err != nil {
+		return nil, err
+	}
+	n := g.node(t)
+
+	if id, ok := geoJSONString(f.ID); ok {
+		n.Attrs()["id"] = id
+	}
+	for k, raw := range f.Properties {
+		if v, ok := geoJSONString(raw); ok {
+			n.Attrs()[k] = v
+		}
+	}
+	return n, nil
+}
+
+// geoJSONString returns a JSON string as is and other values as JSON text.
+// false is returned for a missing value or null.
+func geoJSONString(raw json.RawMessage) (string, bool) {
+	raw = bytes.TrimSpace(raw)
+	if len(raw) == 0 || string(raw) == "null" {
+		return "", false
+	}
+	var s string
+	if err := json.Unmarshal(raw, &s); err == nil {
+		return s, true
+	}
+	return string(raw), true
+}
+
+// geoJSONGeometryOf decodes the coordinates of a geometry object.  Positions
+// may have more than two values but only x and y are used.
+func geoJSONGeometryOf(obj geoJSONObject) (geoGeometry, error) {
+	coords := func(ps [][]float64) ([]geom.Coord, error) {
+		r := make([]geom.Coord, len(ps))
+		for i, p := range ps {
+			if len(p) < 2 {
+				return nil, errors.Errorf("invalid GeoJSON position in %s", obj.Type)
+			}
+			r[i] = geom.Coord{X: p[0], Y: p[1]}
+		}
+		return r, nil
+	}
+	lists := func(pss [][][]float64) ([][]geom.Coord, error) {
+		r := make([][]geom.Coord, len(pss))
+		for i, ps := range pss {
+			var err error
+			if r[i], err = coords(ps); err != nil {
+				return nil, err
+			}
+		}
+		return r, nil
+	}
+	invalid := func(err error) (geoGeometry, error) {
+		return geoGeometry{}, errors.Wrapf(err, "invalid GeoJSON %s", obj.Type)
+	}
+
+	g := geoGeometry{kind: obj.Type}
+	switch obj.Type {
+	case geoLineString:
+		var ps [][]float64
+		if err := json.Unmarshal(obj.Coordinates, &ps); err != nil {
+			return invalid(err)
+		}
+		l, err := coords(ps)
+		if err != nil {
+			return geoGeometry{}, err
+		}
+		g.lines = [][]geom.Coord{l}
+	case geoMultiLineString, geoPolygon:
+		var pss [][][]float64
+		if err := json.Unmarshal(obj.Coordinates, &pss); err != nil {
+			return invalid(err)
+		}
+		ls, err := lists(pss)
+		if err != nil {
+			return geoGeometry{}, err
+		}
+		if obj.Type == geoPolygon {
+			g.polygons = [][][]geom.Coord{ls}
+		} else {
+			g.lines = ls
+		}
+	case geoMultiPolygon:
+		var psss [][][][]float64
+		if err := json.Unmarshal(obj.Coordinates, &psss); err != nil {
+			return invalid(err)
+		}
+		for _, pss := range psss {
+			ls, err := lists(pss)
+			if err != nil {
+				return geoGeometry{}, err
+			}
+			g.polygons = append(g.polygons, ls)
+		}
+	default:
+		return geoGeometry{}, errors.Errorf("unsupported GeoJSON type: %s", obj.Type)
+	}
+	return g, nil
+}
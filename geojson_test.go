@@ -0,0 +1,198 @@
+// Copyright 2018 Joe Beda
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package svgdata
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/jbeda/geom"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMarshalGeoJSONFeature(t *testing.T) {
+	assert := assert.New(t)
+
+	r := mustUnmarshal(t, `<svg xmlns="http://www.w3.org/2000/svg">
+  <path id="room" class="kitchen" transform="translate(5 0)" d="M0 0H10V10H0Z M2 2V8H8V2Z"/>
+</svg>`)
+	data, err := MarshalGeoJSON((*r.Children())[0], GeoOptions{Transform: NewScale(1, -1)})
+	if assert.NoError(err) {
+		assert.JSONEq(`{
+  "type": "Feature",
+  "id": "room",
+  "geometry": {
+    "type": "Polygon",
+    "coordinates": [
+      [[5, 0], [5, -10], [15, -10], [15, 0], [5, 0]],
+      [[7, -2], [13, -2], [13, -8], [7, -8], [7, -2]]
+    ]
+  },
+  "properties": {"class": "kitchen"}
+}`, string(data))
+	}
+}
+
+func TestMarshalGeoJSONCollection(t *testing.T) {
+	assert := assert.New(t)
+
+	r := mustUnmarshal(t, `<svg xmlns="http://www.w3.org/2000/svg">
+  <g transform="scale(2)">
+    <polyline points="0,0 1,2 3,2" stroke="red"/>
+    <path d="M0 0H1V1H0Z M5 5H6V6H5Z M5.2 5.2H5.8V5.8H5.2Z"/>
+  </g>
+  <rect width="5" height="5" display="none"/>
+  <path id="empty" d=""/>
+  <path d="M0 0L1 1M2 2L3 3"/>
+</svg>`)
+	data, err := MarshalGeoJSON(r, GeoOptions{})
+	if !assert.NoError(err) {
+		return
+	}
+	var fc struct {
+		Type     string
+		Features []struct {
+			Geometry struct {
+				Type        string
+				Coordinates json.RawMessage
+			}
+			Properties map[string]string
+		}
+	}
+	if !assert.NoError(json.Unmarshal(data, &fc)) {
+		return
+	}
+	assert.Equal("FeatureCollection", fc.Type)
+	if !assert.Len(fc.Features, 3) {
+		return
+	}
+
+	line := fc.Features[0]
+	assert.Equal("LineString", line.Geometry.Type)
+	assert.JSONEq(`[[0, 0], [2, 4], [6, 4]]`, string(line.Geometry.Coordinates))
+	assert.Equal(map[string]string{"stroke": "red"}, line.Properties)
+
+	var polys [][][][]float64
+	assert.Equal("MultiPolygon", fc.Features[1].Geometry.Type)
+	if assert.NoError(json.Unmarshal(fc.Features[1].Geometry.Coordinates, &polys)) && assert.Len(polys, 2) {
+		assert.Len(polys[0], 1)
+		assert.Len(polys[1], 2)
+		assert.Equal([]float64{10, 10}, polys[1][0][0])
+		assert.Len(polys[1][1], 5)
+		assert.Equal(polys[1][1][0], polys[1][1][4])
+	}
+
+	assert.Equal("MultiLineString", fc.Features[2].Geometry.Type)
+	assert.JSONEq(`[[[0, 0], [1, 1]], [[2, 2], [3, 3]]]`, string(fc.Features[2].Geometry.Coordinates))
+
+	data, err = MarshalGeoJSON(NewGroup(), GeoOptions{})
+	if assert.NoError(err) {
+		assert.JSONEq(`{"type": "FeatureCollection", "features": []}`, string(data))
+	}
+
+	// Exporting the empty path by itself is an error.
+	_, err = MarshalGeoJSON(FindByID(r, "empty"), GeoOptions{})
+	assert.Error(err)
+}
+
+func TestUnmarshalGeoJSON(t *testing.T) {
+	assert := assert.New(t)
+
+	nodes, err := UnmarshalGeoJSON([]byte(`{
+  "type": "FeatureCollection",
+  "features": [
+    {
+      "type": "Feature",
+      "id": 7,
+      "geometry": {
+        "type": "Polygon",
+        "coordinates": [
+          [[0, 0], [10, 0], [10, -10], [0, -10], [0, 0]],
+          [[2, -2], [2, -8], [8, -8], [8, -2], [2, -2]]
+        ]
+      },
+      "properties": {"name": "hall", "floor": 2, "open": true, "note": null}
+    },
+    {"type": "Feature", "geometry": null, "properties": {}},
+    {"type": "Feature", "geometry": {"type": "Point", "coordinates": [1, 2]}},
+    {"type": "Feature", "geometry": {"type": "MultiPoint", "coordinates": [[1, 2]]}},
+    {
+      "type": "Feature",
+      "geometry": {
+        "type": "GeometryCollection",
+        "geometries": [{"type": "LineString", "coordinates": [[0, 0], [1, 1]]}]
+      }
+    },
+    {
+      "type": "Feature",
+      "geometry": {"type": "LineString", "coordinates": [[0, 0, 5], [3, -4, 5]]}
+    },
+    {
+      "type": "Feature",
+      "geometry": {"type": "Polygon", "coordinates": [[[0, 0], [1, 0], [1, -1], [0, 0]]]}
+    }
+  ]
+}`), GeoOptions{Transform: NewScale(1, -1)})
+	if !assert.NoError(err) || !assert.Len(nodes, 3) {
+		return
+	}
+
+	p, ok := nodes[0].(*Path)
+	if assert.True(ok) {
+		assert.Equal(AttrMap{"id": "7", "name": "hall", "floor": "2", "open": "true", "fill-rule": "evenodd"}, p.Attrs())
+		assert.Len(p.SubPaths, 2)
+		assert.InDelta(100-36, subPathsArea(p.SubPaths[:1])-subPathsArea(p.SubPaths[1:]), 1e-9)
+		assert.True(ShapeContains(p, geom.Coord{X: 1, Y: 1}))
+		assert.False(ShapeContains(p, geom.Coord{X: 5, Y: 5}))
+	}
+
+	l, ok := nodes[1].(*Polyshape)
+	if assert.True(ok) {
+		assert.False(l.IsClosed())
+		assert.Equal([]geom.Coord{{X: 0, Y: 0}, {X: 3, Y: 4}}, l.Points)
+		assert.Equal("none", l.Attrs()["fill"])
+	}
+
+	pg, ok := nodes[2].(*Polyshape)
+	if assert.True(ok) {
+		assert.True(pg.IsClosed())
+		assert.Equal([]geom.Coord{{X: 0, Y: 0}, {X: 1, Y: 0}, {X: 1, Y: 1}}, pg.Points)
+	}
+
+	_, err = UnmarshalGeoJSON([]byte(`{"type": "Point", "coordinates": [1, 2]}`), GeoOptions{})
+	assert.Error(err)
+	_, err = UnmarshalGeoJSON([]byte(`{"type": "LineString", "coordinates": [[1]]}`), GeoOptions{})
+	assert.Error(err)
+	_, err = UnmarshalGeoJSON([]byte(`[]`), GeoOptions{})
+	assert.Error(err)
+}
+
+func TestGeoJSONRoundTrip(t *testing.T) {
+	assert := assert.New(t)
+
+	r := mustUnmarshal(t, `<svg xmlns="http://www.w3.org/2000/svg">
+  <path d="M0 0H20V20H0Z M10 10m-5 0a5 5 0 1 0 10 0a5 5 0 1 0 -10 0Z"/>
+</svg>`)
+	opts := GeoOptions{Transform: NewScale(0.5, -0.5), Tolerance: 0.01}
+	data, err := MarshalGeoJSON(r, opts)
+	if !assert.NoError(err) {
+		return
+	}
+	nodes, err := UnmarshalGeoJSON(data, opts)
+	if assert.NoError(err) && assert.Len(nodes, 1) {
+		p := nodes[0].(*Path)
+		assert.InDelta(400-25*3.14159, subPathsArea(p.SubPaths[:1])-subPathsArea(p.SubPaths[1:]), 0.5)
+	}
+}
@@ -0,0 +1,205 @@
+// Copyright 2018 Joe Beda
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package svgdata
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"go/token"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/jbeda/geom"
+	"github.com/pkg/errors"
+)
+
+// GoSourceOptions control the Go source generated by MarshalGoSource.
+type GoSourceOptions struct {
+	// Package is the package of the generated file.  Empty means "assets".
+	Package string
+
+	// FuncName is the name of the generated function.  Empty means
+	// "NewDocument".
+	FuncName string
+}
+
+// goImportPath is the import path of this package in generated code.
+const goImportPath = "github.com/jbeda/svgdata-go"
+
+// MarshalGoSource generates a Go source file with a function that rebuilds
+// the document with constructors such as NewPath, NewCircle and NewRectXYWH,
+// so that it can be compiled into a program instead of parsed at run time.
+// Paths are built from their commands with BuildSubPaths and other elements
+// are created by name.  Attributes and text are copied as is.
+func MarshalGoSource(r *Root, opts GoSourceOptions) ([]byte, error) {
+	pkg, name := opts.Package, opts.FuncName
+	if pkg == "" {
+		pkg = "assets"
+	}
+	if name == "" {
+		name = "NewDocument"
+	}
+	if !token.IsIdentifier(pkg) || !token.IsIdentifier(name) {
+		return nil, errors.Errorf("invalid Go package or function name: %s.%s", pkg, name)
+	}
+
+	g := &goWriter{}
+	g.line("r := svgdata.CreateRoot()")
+	g.attrs("r", r)
+	g.children("r", r)
+	g.line("return r")
+
+	var b bytes.Buffer
+	b.WriteString("// Code generated by svgdata. DO NOT EDIT.\n\n")
+	fmt.Fprintf(&b, "package %s\n\nimport (\n", pkg)
+	if g.usesXML {
+		b.WriteString("\t\"encoding/xml\"\n\n")
+	}
+	if g.usesGeom {
+		b.WriteString("\t\"github.com/jbeda/geom\"\n")
+	}
+	fmt.Fprintf(&b, "\tsvgdata %q\n)\n\n", goImportPath)
+	fmt.Fprintf(&b, "// %s returns a new copy of the document.\n", name)
+	fmt.Fprintf(&b, "func %s() *svgdata.Root {\n", name)
+	b.Write(g.b.Bytes())
+	b.WriteString("}\n")
+
+	src, err := format.Source(b.Bytes())
+	return src, errors.Wrap(err, "formatting generated Go")
+}
+
+// goWriter writes the body of the generated function.
+type goWriter struct {
+	b        bytes.Buffer
+	vars     int
+	usesXML  bool
+	usesGeom bool
+}
+
+func (g *goWriter) line(format string, args ...interface{}) {
+	fmt.Fprintf(&g.b, format, args...)
+	g.b.WriteByte('\n')
+}
+
+// attrs copies the attributes and text of n in a stable order.  The
+// namespace is left out since the root adds it when marshaled, and so is the
+// text of nodes with children since only their children are marshaled.
+func (g *goWriter) attrs(v string, n Node) {
+	var keys []string
+	for k := range n.Attrs() {
+		if k != "xmlns" || n.Name() != "svg" {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		g.line("%s.Attrs()[%q] = %q", v, k, n.Attrs()[k])
+	}
+	if t := n.GetText(); t != "" && len(*n.Children()) == 0 {
+		g.line("%s.SetText(%q)", v, t)
+	}
+}
+
+func (g *goWriter) children(parent string, n Node) {
+	for _, c := range *n.Children() {
+		g.vars++
+		v := fmt.Sprintf("n%d", g.vars)
+		g.line("%s := %s", v, g.constructor(c))
+		if p, ok := c.(*Path); ok {
+			g.subPaths(v, p.SubPaths)
+		}
+		if r, ok := c.(*Rect); ok && (r.Rx != 0 || r.Ry != 0) {
+			g.line("%s.Rx, %s.Ry = %s, %s", v, v, goNumber(r.Rx), goNumber(r.Ry))
+		}
+		g.attrs(v, c)
+		g.children(v, c)
+		g.line("%s.AddChild(%s)", parent, v)
+	}
+}
+
+// constructor returns an expression that creates a node like n without its
+// attributes.
+func (g *goWriter) constructor(n Node) string {
+	switch n := n.(type) {
+	case *Path:
+		return "svgdata.NewPath()"
+	case *Circle:
+		g.usesGeom = true
+		return fmt.Sprintf("svgdata.NewCircle(%s, %s)", g.coord(n.Center), goNumber(n.Radius))
+	case *Ellipse:
+		g.usesGeom = true
+		return fmt.Sprintf("svgdata.NewEllipse(%s, %s, %s)", g.coord(n.Center), goNumber(n.Rx), goNumber(n.Ry))
+	case *Line:
+		g.usesGeom = true
+		return fmt.Sprintf("svgdata.NewLine(%s, %s)", g.coord(n.P1), g.coord(n.P2))
+	case *Rect:
+		return fmt.Sprintf("svgdata.NewRectXYWH(%s, %s, %s, %s)", goNumber(n.R.Min.X), goNumber(n.R.Min.Y),
+			goNumber(n.R.Width()), goNumber(n.R.Height()))
+	case *Polyshape:
+		g.usesGeom = true
+		var pts []string
+		for _, p := range n.Points {
+			pts = append(pts, fmt.Sprintf("{X: %s, Y: %s}", goNumber(p.X), goNumber(p.Y)))
+		}
+		ctor := "NewPolyline"
+		if n.IsClosed() {
+			ctor = "NewPolygon"
+		}
+		return fmt.Sprintf("svgdata.%s([]geom.Coord{%s})", ctor, strings.Join(pts, ", "))
+	}
+	switch n.Name() {
+	case "g":
+		return "svgdata.NewGroup()"
+	case "defs":
+		return "svgdata.NewDefs()"
+	case "style":
+		return "svgdata.NewStyle()"
+	}
+	g.usesXML = true
+	return fmt.Sprintf("svgdata.CreateNodeFromName(xml.Name{Space: svgdata.SvgNs, Local: %q})", n.Name())
+}
+
+func (g *goWriter) coord(c geom.Coord) string {
+	return fmt.Sprintf("geom.Coord{X: %s, Y: %s}", goNumber(c.X), goNumber(c.Y))
+}
+
+// subPaths sets the subpaths of the path in v from their commands.
+func (g *goWriter) subPaths(v string, sps []SubPath) {
+	if len(sps) == 0 {
+		return
+	}
+	g.line("%s.SubPaths = svgdata.BuildSubPaths([]svgdata.PathCommand{", v)
+	for _, sp := range sps {
+		for _, c := range sp.Commands {
+			ps := make([]string, len(c.Params))
+			for i, p := range c.Params {
+				ps[i] = goNumber(p)
+			}
+			if len(ps) == 0 {
+				g.line("{Command: %q},", c.Command)
+			} else {
+				g.line("{Command: %q, Params: []float64{%s}},", c.Command, strings.Join(ps, ", "))
+			}
+		}
+	}
+	g.line("})")
+}
+
+// goNumber formats a number as a Go literal that reads back exactly.
+func goNumber(f float64) string {
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
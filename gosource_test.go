@@ -0,0 +1,114 @@
+// Copyright 2018 Joe Beda
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package svgdata
+
+import (
+	"go/parser"
+	"go/token"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMarshalGoSource(t *testing.T) {
+	assert := assert.New(t)
+
+	r := mustUnmarshal(t, `<svg xmlns="http://www.w3.org/2000/svg" width="24" height="24">
+  <g id="icon" transform="rotate(10)">
+    <path class="a" d="M1 1h4v4zM6 6a1 2 30 1 0 1 1"/>
+    <circle cx="6" cy="6" r="2.5" fill="none"/>
+    <rect x="1" y="2" width="3" height="4" rx="1"/>
+    <polyline points="0,0 1,1e-7"/>
+    <text x="1" y="11">Hi "there"</text>
+  </g>
+</svg>`)
+	src, err := MarshalGoSource(r, GoSourceOptions{Package: "icons", FuncName: "NewIcon"})
+	if !assert.NoError(err) {
+		return
+	}
+	assert.Equal(`// Code generated by svgdata. DO NOT EDIT.
+
+package icons
+
+import (
+	"encoding/xml"
+
+	"github.com/jbeda/geom"
+	svgdata "github.com/jbeda/svgdata-go"
+)
+
+// NewIcon returns a new copy of the document.
+func NewIcon() *svgdata.Root {
+	r := svgdata.CreateRoot()
+	r.Attrs()["height"] = "24"
+	r.Attrs()["width"] = "24"
+	n1 := svgdata.NewGroup()
+	n1.Attrs()["id"] = "icon"
+	n1.Attrs()["transform"] = "rotate(10)"
+	n2 := svgdata.NewPath()
+	n2.SubPaths = svgdata.BuildSubPaths([]svgdata.PathCommand{
+		{Command: 'M', Params: []float64{1, 1}},
+		{Command: 'h', Params: []float64{4}},
+		{Command: 'v', Params: []float64{4}},
+		{Command: 'z'},
+		{Command: 'M', Params: []float64{6, 6}},
+		{Command: 'a', Params: []float64{1, 2, 30, 1, 0, 1, 1}},
+	})
+	n2.Attrs()["class"] = "a"
+	n1.AddChild(n2)
+	n3 := svgdata.NewCircle(geom.Coord{X: 6, Y: 6}, 2.5)
+	n3.Attrs()["fill"] = "none"
+	n1.AddChild(n3)
+	n4 := svgdata.NewRectXYWH(1, 2, 3, 4)
+	n4.Rx, n4.Ry = 1, 1
+	n1.AddChild(n4)
+	n5 := svgdata.NewPolyline([]geom.Coord{{X: 0, Y: 0}, {X: 1, Y: 1e-07}})
+	n1.AddChild(n5)
+	n6 := svgdata.CreateNodeFromName(xml.Name{Space: svgdata.SvgNs, Local: "text"})
+	n6.Attrs()["x"] = "1"
+	n6.Attrs()["y"] = "11"
+	n6.SetText("Hi \"there\"")
+	n1.AddChild(n6)
+	r.AddChild(n1)
+	return r
+}
+`, string(src))
+
+	// Without circles, polyshapes or unknown elements, only the package itself is
+	// imported.
+	src, err = MarshalGoSource(mustUnmarshal(t, `<svg xmlns="http://www.w3.org/2000/svg"><g/></svg>`), GoSourceOptions{})
+	if assert.NoError(err) {
+		f, err := parser.ParseFile(token.NewFileSet(), "", src, parser.ImportsOnly)
+		if assert.NoError(err) {
+			assert.Equal("assets", f.Name.Name)
+			if assert.Len(f.Imports, 1) {
+				assert.Equal(`"github.com/jbeda/svgdata-go"`, f.Imports[0].Path.Value)
+			}
+		}
+		assert.Contains(string(src), "func NewDocument() *svgdata.Root {")
+	}
+
+	src, err = MarshalGoSource(mustUnmarshal(t, `<svg xmlns="http://www.w3.org/2000/svg">
+  <line x1="1" y1="2" x2="3" y2="4"/>
+  <ellipse cx="5" cy="6" rx="7" ry="8"/>
+</svg>`), GoSourceOptions{})
+	if assert.NoError(err) {
+		assert.Contains(string(src), "svgdata.NewLine(geom.Coord{X: 1, Y: 2}, geom.Coord{X: 3, Y: 4})")
+		assert.Contains(string(src), "svgdata.NewEllipse(geom.Coord{X: 5, Y: 6}, 7, 8)")
+	}
+
+	_, err = MarshalGoSource(r, GoSourceOptions{Package: "my-icons"})
+	assert.Error(err)
+}
@@ -0,0 +1,300 @@
+// Copyright 2018 Joe Beda
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package svgdata
+
+import (
+	"math"
+	"sort"
+
+	"github.com/jbeda/geom"
+	"github.com/pkg/errors"
+)
+
+// HatchOptions describe the parallel lines used to fill an area.
+type HatchOptions struct {
+	// Angle is the direction of the hatch lines in degrees.  Zero gives
+	// horizontal lines and positive angles turn clockwise on screen.
+	Angle float64
+
+	// Spacing is the distance between neighbouring lines.
+	Spacing float64
+
+	// CrossHatch adds a second set of lines at right angles to the first.
+	CrossHatch bool
+
+	// ZigZag joins the end of each line to the start of the next one where
+	// the connection stays inside the area, so that a pen can draw several
+	// lines without lifting.
+	ZigZag bool
+
+	// Tolerance is the largest distance curves may deviate when flattened.
+	Tolerance float64
+}
+
+// HatchSubPaths returns open subpaths of straight lines that fill the area
+// enclosed by sps using the given fill rule, either "nonzero" or "evenodd".
+// Lines are placed halfway between multiples of the spacing, measured across
+// the lines from the origin, so that neighbouring areas hatched with the same
+// options line up.
+func HatchSubPaths(sps []SubPath, fillRule string, opts HatchOptions) []SubPath {
+	r := region{pls: FlattenSubPaths(sps, opts.Tolerance), evenOdd: fillRule == "evenodd"}
+	return PolylinesToSubPaths(hatchRegion(r, opts))
+}
+
+// HatchShape returns a new path with hatching for the fill of s.  The path is
+// stroked with the fill paint of s and is not added to the document.
+func HatchShape(s Shape, opts HatchOptions) *Path {
+	return hatchToPath(s, NodeStyle(s), IdentityTransform, opts)
+}
+
+// Hatch adds hatching for every filled shape in the document.  The hatching is
+// computed in the user space of r, so spacing is not affected by transforms,
+// and each set of lines is appended to r as a new path stroked with the fill
+// paint of its shape.  The original shapes are left unchanged.  The new paths
+// are returned in document order.
+func (r *Root) Hatch(opts HatchOptions) ([]*Path, error) {
+	if opts.Spacing <= 0 {
+		return nil, errors.Errorf("invalid hatch spacing %v", opts.Spacing)
+	}
+
+	var ps []*Path
+	err := walkPaint(r, IdentityTransform, NodeStyle(r), func(n Node, ctm Transform, st Style) error {
+		s, ok := n.(Shape)
+		if !ok || !st.HasFill() || st.Get("visibility", "visible") != "visible" {
+			return nil
+		}
+		if p := hatchToPath(s, st, ctm, opts); len(p.SubPaths) > 0 {
+			ps = append(ps, p)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range ps {
+		r.AddChild(p)
+	}
+	return ps, nil
+}
+
+func hatchToPath(s Shape, st Style, ctm Transform, opts HatchOptions) *Path {
+	pls := hatchRegion(shapeRegion(s, ctm, st, opts.Tolerance), opts)
+
+	p := NewPath()
+	p.SubPaths = PolylinesToSubPaths(pls)
+	p.attrs = AttrMap{}
+	p.attrs.SetStyle("fill", "none")
+	p.attrs.SetStyle("stroke", st.Get("fill", "black"))
+	if v, ok := st["fill-opacity"]; ok {
+		p.attrs.SetStyle("stroke-opacity", v)
+	}
+	return p
+}
+
+// hatchRegion returns the hatch lines for r.  The region is rotated so the
+// lines are horizontal, each line is intersected with the edges of the region
+// and the fill rule picks the spans that are inside.
+func hatchRegion(r region, opts HatchOptions) []Polyline {
+	if opts.Spacing <= 0 {
+		return nil
+	}
+	pls := hatchLines(r, opts.Angle, opts)
+	if opts.CrossHatch {
+		pls = append(pls, hatchLines(r, opts.Angle+90, opts)...)
+	}
+	return pls
+}
+
+// hatchEdge is an edge of the rotated region.
+type hatchEdge struct {
+	a, b geom.Coord
+	dir  int
+}
+
+// hatchCrossing is where a hatch line meets an edge.
+type hatchCrossing struct {
+	x   float64
+	dir int
+}
+
+// hatchChain is a run of spans joined by zig-zag connections.
+type hatchChain struct {
+	pts   []geom.Coord
+	right bool // The last span was drawn towards +x
+}
+
+func hatchLines(r region, angle float64, opts HatchOptions) []Polyline {
+	toLines, fromLines := NewRotate(-angle), NewRotate(angle)
+
+	var edges, flat []hatchEdge
+	var bounds geom.Rect
+	first := true
+	for _, pl := range r.pls {
+		n := len(pl.Points)
+		for i := 0; i < n; i++ {
+			a, b := toLines.Apply(pl.Points[i]), toLines.Apply(pl.Points[(i+1)%n])
+			if first {
+				bounds = geom.Rect{Min: a, Max: a}
+				first = false
+			}
+			bounds.ExpandToContainCoord(a)
+			switch {
+			case a.Y < b.Y:
+				edges = append(edges, hatchEdge{a: a, b: b, dir: 1})
+			case a.Y > b.Y:
+				edges = append(edges, hatchEdge{a: a, b: b, dir: -1})
+			default:
+				flat = append(flat, hatchEdge{a: a, b: b})
+			}
+		}
+	}
+	if len(edges) == 0 {
+		return nil
+	}
+	all := append(append([]hatchEdge{}, edges...), flat...)
+	sort.Slice(edges, func(i, j int) bool {
+		return math.Min(edges[i].a.Y, edges[i].b.Y) < math.Min(edges[j].a.Y, edges[j].b.Y)
+	})
+
+	inside := func(wn int) bool {
+		switch {
+		case r.positive:
+			return wn > 0
+		case r.evenOdd:
+			return wn%2 != 0
+		}
+		return wn != 0
+	}
+
+	var chains []*hatchChain
+	var open []*hatchChain
+	kmin := int(math.Ceil(bounds.Min.Y/opts.Spacing - 0.5))
+	kmax := int(math.Floor(bounds.Max.Y/opts.Spacing - 0.5))
+	for k := kmin; k <= kmax; k++ {
+		y := (float64(k) + 0.5) * opts.Spacing
+
+		// Edges include their lower end but not their upper end so that a
+		// line through a vertex counts it once.
+		var xs []hatchCrossing
+		for _, e := range edges {
+			lo, hi := e.a, e.b
+			if lo.Y > hi.Y {
+				lo, hi = hi, lo
+			}
+			if lo.Y > y {
+				break
+			}
+			if y >= hi.Y {
+				continue
+			}
+			t := (y - lo.Y) / (hi.Y - lo.Y)
+			xs = append(xs, hatchCrossing{x: lo.X + t*(hi.X-lo.X), dir: e.dir})
+		}
+		sort.Slice(xs, func(i, j int) bool { return xs[i].x < xs[j].x })
+
+		var spans [][2]geom.Coord
+		wn := 0
+		for i, c := range xs {
+			wn += c.dir
+			if i+1 < len(xs) && inside(wn) {
+				a, b := geom.Coord{X: c.x, Y: y}, geom.Coord{X: xs[i+1].x, Y: y}
+				if n := len(spans); n > 0 && spans[n-1][1].X >= a.X-geomEpsilon {
+					spans[n-1][1] = b
+				} else if b.X-a.X > geomEpsilon {
+					spans = append(spans, [2]geom.Coord{a, b})
+				}
+			}
+		}
+
+		var next []*hatchChain
+		used := make([]bool, len(spans))
+		if opts.ZigZag {
+			for _, ch := range open {
+				end := ch.pts[len(ch.pts)-1]
+				best, bestDist := -1, math.Inf(1)
+				for i, s := range spans {
+					start := s[0]
+					if ch.right {
+						start = s[1]
+					}
+					if d := coordDist(end, start); !used[i] && d < bestDist && hatchConnects(r, all, fromLines, end, start) {
+						best, bestDist = i, d
+					}
+				}
+				if best < 0 {
+					continue
+				}
+				used[best] = true
+				s := spans[best]
+				if ch.right {
+					ch.pts = append(ch.pts, s[1], s[0])
+				} else {
+					ch.pts = append(ch.pts, s[0], s[1])
+				}
+				ch.right = !ch.right
+				next = append(next, ch)
+			}
+		}
+		for i, s := range spans {
+			if used[i] {
+				continue
+			}
+			ch := &hatchChain{pts: []geom.Coord{s[0], s[1]}, right: true}
+			chains = append(chains, ch)
+			next = append(next, ch)
+		}
+		open = next
+	}
+
+	pls := make([]Polyline, len(chains))
+	for i, ch := range chains {
+		pls[i].Points = make([]geom.Coord, len(ch.pts))
+		for j, p := range ch.pts {
+			pls[i].Points[j] = fromLines.Apply(p)
+		}
+	}
+	return pls
+}
+
+// hatchConnects returns true if the straight line from a to b, both in the
+// rotated space, stays inside r.  The line must not properly cross any edge
+// and its midpoint must be inside or on an edge, as it is when the line runs
+// along the boundary.
+func hatchConnects(r region, edges []hatchEdge, fromLines Transform, a, b geom.Coord) bool {
+	d := coordSub(b, a)
+	l := coordLen(d)
+	if l <= geomEpsilon {
+		return true
+	}
+	eps := geomEpsilon * math.Max(1, l)
+	for _, e := range edges {
+		ed := coordSub(e.b, e.a)
+		s1 := coordCross(d, coordSub(e.a, a))
+		s2 := coordCross(d, coordSub(e.b, a))
+		s3 := coordCross(ed, coordSub(a, e.a))
+		s4 := coordCross(ed, coordSub(b, e.a))
+		if ((s1 > eps && s2 < -eps) || (s1 < -eps && s2 > eps)) &&
+			((s3 > eps && s4 < -eps) || (s3 < -eps && s4 > eps)) {
+			return false
+		}
+	}
+	m := coordLerp(a, b, 0.5)
+	for _, e := range edges {
+		if pointSegmentDistOnly(m, e.a, e.b) <= eps {
+			return true
+		}
+	}
+	return r.contains(fromLines.Apply(m))
+}
@@ -0,0 +1,124 @@
+// Copyright 2018 Joe Beda
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package svgdata
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHatchSquare(t *testing.T) {
+	assert := assert.New(t)
+
+	sq := mustParsePath(t, "M0,0 h10 v10 h-10 z")
+	sps := HatchSubPaths(sq, "nonzero", HatchOptions{Spacing: 1})
+	if assert.Len(sps, 10) {
+		assert.Equal("M0 0.5L10 0.5", SavePathString(sps[:1]))
+		assert.Equal("M0 9.5L10 9.5", SavePathString(sps[9:]))
+	}
+	assert.InDelta(100, subPathsLength(sps), 1e-9)
+
+	// Vertical lines.
+	sps = HatchSubPaths(sq, "nonzero", HatchOptions{Angle: 90, Spacing: 2})
+	if assert.Len(sps, 5) {
+		for _, sp := range sps {
+			assert.InDelta(sp.Start().X, sp.End().X, 1e-9)
+			assert.InDelta(10, math.Abs(sp.End().Y-sp.Start().Y), 1e-9)
+		}
+	}
+
+	// Diagonal lines cover the area at the given spacing.
+	sps = HatchSubPaths(sq, "nonzero", HatchOptions{Angle: 45, Spacing: 0.1})
+	assert.InDelta(1000, subPathsLength(sps), 1)
+
+	sps = HatchSubPaths(sq, "nonzero", HatchOptions{Spacing: 1, CrossHatch: true})
+	assert.Len(sps, 20)
+	assert.InDelta(200, subPathsLength(sps), 1e-9)
+
+	assert.Empty(HatchSubPaths(sq, "nonzero", HatchOptions{}))
+}
+
+func TestHatchFillRule(t *testing.T) {
+	assert := assert.New(t)
+
+	// The inner square winds the same way as the outer one, so it is only a
+	// hole with the evenodd rule.
+	d := "M0,0 h10 v10 h-10 z M3,3 h4 v4 h-4 z"
+	opts := HatchOptions{Spacing: 1}
+	assert.Len(HatchSubPaths(mustParsePath(t, d), "nonzero", opts), 10)
+	sps := HatchSubPaths(mustParsePath(t, d), "evenodd", opts)
+	assert.Len(sps, 14)
+	assert.InDelta(84, subPathsLength(sps), 1e-9)
+	for _, sp := range sps {
+		mid := coordLerp(sp.Start(), sp.End(), 0.5)
+		assert.False(mid.X > 3 && mid.X < 7 && mid.Y > 3 && mid.Y < 7)
+	}
+
+	// Reversing the inner square makes it a hole for both rules.
+	d = "M0,0 h10 v10 h-10 z M3,3 v4 h4 v-4 z"
+	assert.Len(HatchSubPaths(mustParsePath(t, d), "nonzero", opts), 14)
+}
+
+func TestHatchZigZag(t *testing.T) {
+	assert := assert.New(t)
+
+	opts := HatchOptions{Spacing: 1, ZigZag: true}
+	sps := HatchSubPaths(mustParsePath(t, "M0,0 h10 v10 h-10 z"), "nonzero", opts)
+	if assert.Len(sps, 1) {
+		assert.Equal("M0 0.5L10 0.5L10 1.5L0 1.5L0 2.5L10 2.5", SavePathString(sps)[:39])
+		assert.InDelta(109, subPathsLength(sps), 1e-9)
+	}
+
+	// A U shape needs separate runs for each arm since connecting across the
+	// gap would leave the area.
+	u := "M0,0 h3 v7 h4 v-7 h3 v10 h-10 z"
+	sps = HatchSubPaths(mustParsePath(t, u), "nonzero", opts)
+	assert.Len(sps, 2)
+	for _, sp := range sps {
+		for _, s := range subPathSegments(sp) {
+			mid := s.point(0.5)
+			assert.False(mid.X > 3 && mid.X < 7 && mid.Y < 7, "%v", mid)
+		}
+	}
+}
+
+func TestRootHatch(t *testing.T) {
+	assert := assert.New(t)
+
+	r, err := Unmarshal([]byte(`<svg xmlns="http://www.w3.org/2000/svg">
+  <g transform="scale(2)" fill="red">
+    <rect x="0" y="0" width="5" height="5"/>
+    <rect x="10" y="0" width="5" height="5" fill="none" stroke="black"/>
+    <rect x="20" y="0" width="5" height="5" fill-opacity="0.5" visibility="hidden"/>
+  </g>
+</svg>`))
+	assert.NoError(err)
+
+	ps, err := r.Hatch(HatchOptions{Spacing: 1})
+	assert.NoError(err)
+	if assert.Len(ps, 1) {
+		assert.Equal(ps[0], (*r.Children())[1])
+		assert.Equal("red", ps[0].Attrs()["stroke"])
+		assert.Equal("none", ps[0].Attrs()["fill"])
+		// Spacing is in the user space of the document.
+		assert.Len(ps[0].SubPaths, 10)
+		assert.InDelta(100, subPathsLength(ps[0].SubPaths), 1e-9)
+	}
+
+	_, err = r.Hatch(HatchOptions{})
+	assert.Error(err)
+}
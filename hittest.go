@@ -0,0 +1,117 @@
+// Copyright 2018 Joe Beda
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package svgdata
+
+import (
+	"github.com/jbeda/geom"
+)
+
+// hitTolerance is the flattening tolerance, in user units, used when testing
+// points against curved geometry.
+const hitTolerance = 0.01
+
+// WindingNumber returns the number of times the subpaths wind around p.  Each
+// subpath is treated as closed, as it is when filled.
+func WindingNumber(sps []SubPath, p geom.Coord) int {
+	wn := 0
+	for _, pl := range FlattenSubPaths(sps, hitTolerance) {
+		wn += pl.windingNumber(p)
+	}
+	return wn
+}
+
+func (pl Polyline) windingNumber(p geom.Coord) int {
+	wn := 0
+	n := len(pl.Points)
+	for i := 0; i < n; i++ {
+		a, b := pl.Points[i], pl.Points[(i+1)%n]
+		isLeft := coordCross(coordSub(b, a), coordSub(p, a))
+		if a.Y <= p.Y {
+			if b.Y > p.Y && isLeft > 0 {
+				wn++
+			}
+		} else if b.Y <= p.Y && isLeft < 0 {
+			wn--
+		}
+	}
+	return wn
+}
+
+// FillContains returns true if p is inside the area filled by the subpaths
+// using the given fill rule, either "nonzero" or "evenodd".
+func FillContains(sps []SubPath, p geom.Coord, fillRule string) bool {
+	wn := WindingNumber(sps, p)
+	if fillRule == "evenodd" {
+		return wn%2 != 0
+	}
+	return wn != 0
+}
+
+// ShapeContains returns true if p is inside the area filled by the shape.  The
+// fill-rule of the node is honored.
+func ShapeContains(s Shape, p geom.Coord) bool {
+	return FillContains(s.ToSubPaths(), p, NodeStyle(s).FillRule())
+}
+
+// StrokeContains returns true if p is within half of width of the outline of
+// the subpaths.  Caps and joins are treated as round.
+func StrokeContains(sps []SubPath, p geom.Coord, width float64) bool {
+	for _, sp := range sps {
+		if len(sp.Commands) == 0 {
+			continue
+		}
+		np, _ := sp.NearestPoint(p)
+		if coordDist(np, p) <= width/2 {
+			return true
+		}
+	}
+	return false
+}
+
+// ShapeStrokeContains returns true if p is on the stroke of the shape drawn
+// with the given width.
+func ShapeStrokeContains(s Shape, p geom.Coord, width float64) bool {
+	return StrokeContains(s.ToSubPaths(), p, width)
+}
+
+// NodeAt returns the topmost shape in paint order that has its fill or stroke
+// under p.  p is in the user space of the root's children.  Fill, stroke,
+// stroke-width, fill-rule and transforms are taken from the document.  nil is
+// returned if there is nothing under p.
+func (r *Root) NodeAt(p geom.Coord) (Node, error) {
+	var hit Node
+	err := walkPaint(r, IdentityTransform, NodeStyle(r), func(n Node, ctm Transform, st Style) error {
+		s, ok := n.(Shape)
+		if !ok || st.Get("visibility", "visible") != "visible" {
+			return nil
+		}
+		inv, ok := ctm.Invert()
+		if !ok {
+			return nil
+		}
+		lp := inv.Apply(p)
+
+		sps := s.ToSubPaths()
+		if (st.HasFill() && FillContains(sps, lp, st.FillRule())) ||
+			(st.HasStroke() && StrokeContains(sps, lp, st.StrokeWidth())) {
+			hit = n
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return hit, nil
+}
@@ -0,0 +1,114 @@
+// Copyright 2018 Joe Beda
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package svgdata
+
+import (
+	"testing"
+
+	"github.com/jbeda/geom"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFillRules(t *testing.T) {
+	assert := assert.New(t)
+
+	// Two nested squares wound the same way.
+	sps, err := ParsePathString("M0,0 h10 v10 h-10 z M2,2 h6 v6 h-6 z")
+	assert.NoError(err)
+
+	inner := geom.Coord{X: 5, Y: 5}
+	ring := geom.Coord{X: 1, Y: 5}
+	outside := geom.Coord{X: 11, Y: 5}
+
+	assert.Equal(2, abs(WindingNumber(sps, inner)))
+	assert.True(FillContains(sps, inner, "nonzero"))
+	assert.False(FillContains(sps, inner, "evenodd"))
+	assert.True(FillContains(sps, ring, "evenodd"))
+	assert.False(FillContains(sps, outside, "nonzero"))
+
+	p := NewPath()
+	p.SubPaths = sps
+	assert.True(ShapeContains(p, inner))
+	p.Attrs()["style"] = "fill-rule: evenodd"
+	assert.False(ShapeContains(p, inner))
+}
+
+func TestShapeContains(t *testing.T) {
+	assert := assert.New(t)
+
+	c := NewCircle(geom.Coord{X: 10, Y: 10}, 5)
+	assert.True(ShapeContains(c, geom.Coord{X: 13, Y: 13}))
+	assert.False(ShapeContains(c, geom.Coord{X: 14, Y: 14}))
+
+	r := NewRectXYWH(0, 0, 4, 2)
+	assert.True(ShapeContains(r, geom.Coord{X: 3, Y: 1}))
+	assert.False(ShapeContains(r, geom.Coord{X: 3, Y: 3}))
+
+	// An open polyline is closed when it is filled.
+	pl := NewPolyline([]geom.Coord{{X: 0, Y: 0}, {X: 10, Y: 0}, {X: 0, Y: 10}})
+	assert.True(ShapeContains(pl, geom.Coord{X: 2, Y: 2}))
+	assert.False(ShapeContains(pl, geom.Coord{X: 8, Y: 8}))
+}
+
+func TestStrokeContains(t *testing.T) {
+	assert := assert.New(t)
+
+	c := NewCircle(geom.Coord{X: 0, Y: 0}, 10)
+	assert.True(ShapeStrokeContains(c, geom.Coord{X: 0, Y: 10.9}, 2))
+	assert.False(ShapeStrokeContains(c, geom.Coord{X: 0, Y: 11.1}, 2))
+	assert.False(ShapeStrokeContains(c, geom.Coord{X: 0, Y: 0}, 2))
+
+	// The closing segment of a subpath is part of the stroke.
+	sps, err := ParsePathString("M0,0 h10 v10 z")
+	assert.NoError(err)
+	assert.True(StrokeContains(sps, geom.Coord{X: 5, Y: 5.2}, 1))
+}
+
+func TestNodeAt(t *testing.T) {
+	assert := assert.New(t)
+
+	r, err := Unmarshal([]byte(`<svg xmlns="http://www.w3.org/2000/svg">
+<rect id="back" x="0" y="0" width="100" height="100"></rect>
+<g transform="translate(50,50)" fill="none" stroke="red" stroke-width="4">
+  <circle id="ring" cx="0" cy="0" r="20"></circle>
+</g>
+<g style="display:none"><rect id="hidden" x="0" y="0" width="100" height="100"></rect></g>
+<rect id="nofill" x="0" y="0" width="10" height="10" fill="none"></rect>
+</svg>`))
+	assert.NoError(err)
+
+	n, err := r.NodeAt(geom.Coord{X: 50, Y: 71})
+	assert.NoError(err)
+	assert.Equal("ring", n.Attrs()["id"])
+
+	n, err = r.NodeAt(geom.Coord{X: 50, Y: 50})
+	assert.NoError(err)
+	assert.Equal("back", n.Attrs()["id"])
+
+	n, err = r.NodeAt(geom.Coord{X: 5, Y: 5})
+	assert.NoError(err)
+	assert.Equal("back", n.Attrs()["id"])
+
+	n, err = r.NodeAt(geom.Coord{X: 150, Y: 50})
+	assert.NoError(err)
+	assert.Nil(n)
+}
+
+func abs(i int) int {
+	if i < 0 {
+		return -i
+	}
+	return i
+}
@@ -0,0 +1,207 @@
+// Copyright 2018 Joe Beda
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package svgdata
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/jbeda/geom"
+)
+
+// HPGLOptions control how a document is converted to HPGL.
+type HPGLOptions struct {
+	// UnitsPerInch is the resolution of the plotter.  Zero means the usual
+	// 1016, or 40 units per millimeter.
+	UnitsPerInch float64
+
+	// Pens maps stroke colors to pen numbers.  Other colors are given the
+	// lowest pen numbers that aren't in Pens, in the order they are first
+	// used.
+	Pens map[string]int
+
+	// Arcs writes circular arcs with AA instead of flattening them.
+	Arcs bool
+
+	// Overcut is how far to keep cutting past the start of closed subpaths,
+	// in plotter units, so that the cut meets up cleanly.
+	Overcut float64
+
+	// Tolerance is the largest distance curves may deviate when flattened,
+	// in plotter units.  Zero means half a unit.
+	Tolerance float64
+}
+
+// MarshalHPGL converts the shapes in the document to HPGL for pen plotters
+// and vinyl cutters.  Each subpath is drawn with PU and PD commands in
+// document order, selecting pens by stroke color with SP.  Shapes without a
+// stroke use their fill color.  Shapes hidden with display none are left
+// out.
+//
+// The document is placed with the bottom left of its viewport at the origin
+// and the Y axis pointing up, and scaled to its physical size.
+func MarshalHPGL(r *Root, opts HPGLOptions) ([]byte, error) {
+	upi := opts.UnitsPerInch
+	if upi <= 0 {
+		upi = 1016
+	}
+	if opts.Tolerance <= 0 {
+		opts.Tolerance = 0.5
+	}
+
+	vb, pxPerUser, err := documentViewport(r)
+	if err != nil {
+		return nil, err
+	}
+	k := pxPerUser / dpi * upi
+	toPlotter := NewScale(k, -k).Multiply(NewTranslate(-vb.Min.X, -vb.Max.Y))
+
+	w := &hpglWriter{opts: opts, pens: map[string]int{}, used: map[int]bool{}}
+	for c, p := range opts.Pens {
+		w.pens[c] = p
+		w.used[p] = true
+	}
+	w.WriteString("IN;")
+	err = walkPaint(r, IdentityTransform, NodeStyle(r), func(n Node, ctm Transform, st Style) error {
+		s, ok := n.(Shape)
+		if !ok {
+			return nil
+		}
+		paint := st.Get("stroke", "none")
+		if isNonePaint(paint) {
+			paint = st.Get("fill", "black")
+		}
+		w.shape(s, toPlotter.Multiply(ctm), paint)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	w.WriteString("PU;SP0;\n")
+	return w.Bytes(), nil
+}
+
+// hpglWriter tracks the plotter state while writing commands.
+type hpglWriter struct {
+	bytes.Buffer
+	opts HPGLOptions
+	pens map[string]int
+	used map[int]bool
+	pen  int
+
+	pos     [2]int
+	down    []string // Coordinates of the PD command being written
+	penDown bool
+}
+
+// selectPen switches to the pen for paint.
+func (w *hpglWriter) selectPen(paint string) {
+	p, ok := w.pens[paint]
+	if !ok {
+		for p = 1; w.used[p]; p++ {
+		}
+		w.pens[paint] = p
+		w.used[p] = true
+	}
+	if p != w.pen {
+		w.flush()
+		fmt.Fprintf(w, "SP%d;", p)
+		w.pen = p
+	}
+}
+
+func hpglPoint(p geom.Coord) [2]int {
+	return [2]int{int(math.Round(p.X)), int(math.Round(p.Y))}
+}
+
+// flush ends the PD command being written.
+func (w *hpglWriter) flush() {
+	if len(w.down) > 0 {
+		fmt.Fprintf(w, "PD%s;", strings.Join(w.down, ","))
+		w.down = nil
+		w.penDown = true
+	}
+}
+
+// lineTo adds a point to the current PD command.
+func (w *hpglWriter) lineTo(p geom.Coord) {
+	ip := hpglPoint(p)
+	if ip == w.pos {
+		return
+	}
+	w.down = append(w.down, fmt.Sprintf("%d,%d", ip[0], ip[1]))
+	w.pos = ip
+}
+
+func (w *hpglWriter) shape(s Shape, t Transform, paint string) {
+	for _, sp := range s.ToSubPaths() {
+		var segs []segment
+		for _, seg := range subPathSegments(sp) {
+			segs = append(segs, seg.transform(t))
+		}
+		if len(segs) == 0 {
+			continue
+		}
+		if w.opts.Overcut > 0 && coordNear(segs[0].start, segs[len(segs)-1].end, geomEpsilon) {
+			lens := make([]float64, len(segs))
+			total := 0.0
+			for i, s := range segs {
+				lens[i] = s.length()
+				total += lens[i]
+			}
+			segs = append(segs, segmentsInRange(segs, lens, 0, math.Min(w.opts.Overcut, total))...)
+		}
+
+		w.selectPen(paint)
+		w.flush()
+		w.pos = hpglPoint(segs[0].start)
+		fmt.Fprintf(w, "PU%d,%d;", w.pos[0], w.pos[1])
+		w.penDown = false
+		for _, s := range segs {
+			w.segment(s)
+		}
+		w.flush()
+		w.WriteString("\n")
+	}
+}
+
+func (w *hpglWriter) segment(s segment) {
+	if s.kind == 'A' && w.opts.Arcs {
+		ea := s.ellipse()
+		if ea.isCircular() {
+			// AA draws from the current position, so the arc must start
+			// there even after rounding.
+			w.lineTo(s.start)
+			w.flush()
+			if !w.penDown {
+				w.WriteString("PD;")
+				w.penDown = true
+			}
+			c := hpglPoint(ea.center)
+			fmt.Fprintf(w, "AA%d,%d,%s;", c[0], c[1], floatToString(math.Round(ea.delta*180/math.Pi*1000)/1000))
+			w.pos = hpglPoint(s.end)
+			return
+		}
+	}
+	if s.kind == 'L' {
+		w.lineTo(s.end)
+		return
+	}
+	for _, p := range s.flatten(w.opts.Tolerance, nil) {
+		w.lineTo(p)
+	}
+}
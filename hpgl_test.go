@@ -0,0 +1,47 @@
+// Copyright 2018 Joe Beda
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package svgdata
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMarshalHPGL(t *testing.T) {
+	assert := assert.New(t)
+
+	r := mustUnmarshal(t, `<svg xmlns="http://www.w3.org/2000/svg" width="1in" height="1in" viewBox="0 0 1016 1016">
+  <path d="M0,1016 L100,1016 L100,916" stroke="red"/>
+  <path d="M200,200 h100 v100 h-100 z" fill="blue"/>
+  <circle cx="500" cy="516" r="100" stroke="red"/>
+  <path d="M0,0 L1,1" stroke="green" display="none"/>
+</svg>`)
+
+	data, err := MarshalHPGL(r, HPGLOptions{Pens: map[string]int{"blue": 1}})
+	assert.NoError(err)
+	s := string(data)
+	assert.Equal("IN;SP2;PU0,0;PD100,0,100,100;\nSP1;PU200,816;PD300,816,300,716,200,716,200,816;\n", s[:79])
+	assert.Contains(s, "SP2;PU600,500;PD")
+	assert.Equal("PU;SP0;\n", s[len(s)-8:])
+
+	data, err = MarshalHPGL(r, HPGLOptions{Arcs: true, Overcut: 20, UnitsPerInch: 2032})
+	assert.NoError(err)
+	assert.Equal(`IN;SP1;PU0,0;PD200,0,200,200;
+SP2;PU400,1632;PD600,1632,600,1432,400,1432,400,1632,420,1632;
+SP1;PU1200,1000;PD;AA1000,1000,-180;AA1000,1000,-180;AA1000,1000,-5.73;
+PU;SP0;
+`, string(data))
+}
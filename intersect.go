@@ -0,0 +1,582 @@
+// Copyright 2018 Joe Beda
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package svgdata
+
+import (
+	"math"
+	"sort"
+
+	"github.com/jbeda/geom"
+)
+
+const (
+	// intersectTolerance is the distance, relative to the size of the
+	// segments, at which curves are treated as straight and points are
+	// treated as meeting.
+	intersectTolerance = 1e-9
+
+	// intersectMergeTolerance is the relative distance within which
+	// intersection points are considered the same.
+	intersectMergeTolerance = 1e-6
+
+	// intersectMaxDepth bounds the subdivision of overlapping curves.
+	intersectMaxDepth = 48
+)
+
+// SegmentIntersection is a point where two segments meet.
+type SegmentIntersection struct {
+	Point  geom.Coord
+	T1, T2 float64 // Parameters on the first and second segment in [0, 1]
+}
+
+// IntersectCommands returns the points where the path commands a and b meet,
+// ordered along a.  The commands must come from a parsed path so that their
+// start points are known.  Since the command before an S or T is not
+// available, their implied control point is taken to be their start.
+// Segments that overlap report points along the shared part.
+func IntersectCommands(a, b PathCommand) []SegmentIntersection {
+	sa := subPathSegments(SubPath{Commands: []PathCommand{a}})
+	sb := subPathSegments(SubPath{Commands: []PathCommand{b}})
+	if len(sa) == 0 || len(sb) == 0 {
+		return nil
+	}
+	return intersectSegmentPair(sa[0], sb[0])
+}
+
+// IntersectSubPaths returns the points where a and b meet.
+func IntersectSubPaths(a, b SubPath) []geom.Coord {
+	return intersectSegmentLists(subPathSegments(a), subPathSegments(b))
+}
+
+// SelfIntersections returns the points where the subpath crosses or touches
+// itself.  The points where consecutive segments join are not included.
+func (sp SubPath) SelfIntersections() []geom.Coord {
+	return selfIntersections(subPathSegments(sp))
+}
+
+// Crossing records where two subpaths in a document meet.  A and B are the
+// same node when two of its own subpaths meet.
+type Crossing struct {
+	A, B               Node
+	SubPathA, SubPathB int // Index of the subpath within each node's shape
+	Points             []geom.Coord
+}
+
+// SelfIntersection records where a subpath in a document meets itself.
+type SelfIntersection struct {
+	Node    Node
+	SubPath int
+	Points  []geom.Coord
+}
+
+// IntersectionReport lists the places where the outlines in a document cross
+// or touch.
+type IntersectionReport struct {
+	Crossings         []Crossing
+	SelfIntersections []SelfIntersection
+}
+
+// Empty returns true if nothing in the document intersects.
+func (ir *IntersectionReport) Empty() bool {
+	return len(ir.Crossings) == 0 && len(ir.SelfIntersections) == 0
+}
+
+// Intersections checks every rendered shape in the document for subpaths that
+// meet each other or themselves.  Transforms are applied so points are in the
+// user space of the root's children and shapes in different groups are
+// compared correctly.
+func (r *Root) Intersections() (*IntersectionReport, error) {
+	type outline struct {
+		node   Node
+		index  int
+		segs   []segment
+		bounds geom.Rect
+	}
+	var outlines []outline
+	err := walkPaint(r, IdentityTransform, NodeStyle(r), func(n Node, ctm Transform, st Style) error {
+		s, ok := n.(Shape)
+		if !ok {
+			return nil
+		}
+		for i, sp := range s.ToSubPaths() {
+			segs := subPathSegments(sp)
+			if len(segs) == 0 {
+				continue
+			}
+			for j := range segs {
+				segs[j] = segs[j].transform(ctm)
+			}
+			outlines = append(outlines, outline{n, i, segs, unionRects(segmentsBounds(segs))})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	ir := &IntersectionReport{}
+	for i, a := range outlines {
+		if pts := selfIntersections(a.segs); len(pts) > 0 {
+			ir.SelfIntersections = append(ir.SelfIntersections, SelfIntersection{a.node, a.index, pts})
+		}
+		for _, b := range outlines[i+1:] {
+			if !rectsOverlap(a.bounds, b.bounds, intersectTolerance*rectSize(a.bounds, b.bounds)) {
+				continue
+			}
+			if pts := intersectSegmentLists(a.segs, b.segs); len(pts) > 0 {
+				ir.Crossings = append(ir.Crossings, Crossing{a.node, b.node, a.index, b.index, pts})
+			}
+		}
+	}
+	return ir, nil
+}
+
+// piece is the part of a segment between two of its parameters.
+type piece struct {
+	s      segment
+	t0, t1 float64
+}
+
+// param maps a parameter on the piece to one on the original segment.
+func (p piece) param(t float64) float64 {
+	return p.t0 + t*(p.t1-p.t0)
+}
+
+func (p piece) split() (piece, piece) {
+	a, b := p.s.split(0.5)
+	m := p.param(0.5)
+	return piece{a, p.t0, m}, piece{b, m, p.t1}
+}
+
+// hull returns points whose convex hull contains the piece.  Arcs must span
+// no more than a quarter turn.
+func (p piece) hull() []geom.Coord {
+	s := p.s
+	switch s.kind {
+	case 'Q':
+		return []geom.Coord{s.start, s.ctrl1, s.end}
+	case 'C':
+		return []geom.Coord{s.start, s.ctrl1, s.ctrl2, s.end}
+	case 'A':
+		c, ok := lineIntersection(s.start, s.tangent(0), s.end, s.tangent(1))
+		if !ok {
+			c = s.point(0.5)
+		}
+		return []geom.Coord{s.start, c, s.end}
+	}
+	return []geom.Coord{s.start, s.end}
+}
+
+// flatness returns how far the piece may stray from its chord.
+func (p piece) flatness() float64 {
+	if p.s.kind == 'L' {
+		return 0
+	}
+	f := 0.0
+	for _, c := range p.hull() {
+		if d, _ := pointSegmentDist(c, p.s.start, p.s.end); d > f {
+			f = d
+		}
+	}
+	return f
+}
+
+// segmentPieces splits arcs into quarter turns so that hull applies.
+func segmentPieces(s segment) []piece {
+	if s.kind != 'A' {
+		return []piece{{s, 0, 1}}
+	}
+	n := int(math.Ceil(math.Abs(s.ellipse().delta) / (math.Pi / 2)))
+	r := make([]piece, 0, n)
+	for i := 0; i < n; i++ {
+		t0, t1 := float64(i)/float64(n), float64(i+1)/float64(n)
+		r = append(r, piece{s.sub(t0, t1), t0, t1})
+	}
+	return r
+}
+
+func pointsBounds(pts []geom.Coord) geom.Rect {
+	r := geom.Rect{Min: pts[0], Max: pts[0]}
+	for _, p := range pts[1:] {
+		r.ExpandToContainCoord(p)
+	}
+	return r
+}
+
+func segmentBounds(s segment) geom.Rect {
+	var pts []geom.Coord
+	for _, p := range segmentPieces(s) {
+		pts = append(pts, p.hull()...)
+	}
+	return pointsBounds(pts)
+}
+
+func segmentsBounds(segs []segment) []geom.Rect {
+	r := make([]geom.Rect, len(segs))
+	for i, s := range segs {
+		r[i] = segmentBounds(s)
+	}
+	return r
+}
+
+// unionRects returns the smallest rectangle containing all of rs, which must
+// not be empty.
+func unionRects(rs []geom.Rect) geom.Rect {
+	u := rs[0]
+	for _, r := range rs[1:] {
+		u.ExpandToContainCoord(r.Min)
+		u.ExpandToContainCoord(r.Max)
+	}
+	return u
+}
+
+func rectsOverlap(a, b geom.Rect, tol float64) bool {
+	return a.Min.X <= b.Max.X+tol && b.Min.X <= a.Max.X+tol &&
+		a.Min.Y <= b.Max.Y+tol && b.Min.Y <= a.Max.Y+tol
+}
+
+// intersector finds the intersections between two segments by recursively
+// splitting them until the parts that might meet are flat and then
+// intersecting their chords.
+type intersector struct {
+	a, b     segment
+	tol      float64
+	mergeTol float64
+	r        []SegmentIntersection
+}
+
+func intersectSegmentPair(a, b segment) []SegmentIntersection {
+	ba, bb := segmentBounds(a), segmentBounds(b)
+	scale := rectSize(ba, bb)
+	x := &intersector{a: a, b: b, tol: intersectTolerance * scale, mergeTol: intersectMergeTolerance * scale}
+	if !rectsOverlap(ba, bb, x.tol) {
+		return nil
+	}
+	for _, pa := range segmentPieces(a) {
+		for _, pb := range segmentPieces(b) {
+			x.recurse(pa, pb, 0)
+		}
+	}
+	return x.collapseOverlaps(mergeIntersections(x.r, x.mergeTol))
+}
+
+func (x *intersector) recurse(a, b piece, depth int) {
+	if !rectsOverlap(pointsBounds(a.hull()), pointsBounds(b.hull()), x.tol) {
+		return
+	}
+	if rev, ok := coincidentPieces(a, b, x.mergeTol); ok {
+		// Report the ends of the overlap rather than subdividing it.
+		if rev {
+			x.add(a.t0, b.t1)
+			x.add(a.t1, b.t0)
+		} else {
+			x.add(a.t0, b.t0)
+			x.add(a.t1, b.t1)
+		}
+		return
+	}
+	aFlat := depth >= intersectMaxDepth || a.flatness() <= x.tol
+	bFlat := depth >= intersectMaxDepth || b.flatness() <= x.tol
+	switch {
+	case aFlat && bFlat:
+		for _, tu := range intersectChords(a.s.start, a.s.end, b.s.start, b.s.end, x.tol) {
+			x.add(a.param(tu[0]), b.param(tu[1]))
+		}
+	case aFlat:
+		b1, b2 := b.split()
+		x.recurse(a, b1, depth+1)
+		x.recurse(a, b2, depth+1)
+	case bFlat:
+		a1, a2 := a.split()
+		x.recurse(a1, b, depth+1)
+		x.recurse(a2, b, depth+1)
+	default:
+		a1, a2 := a.split()
+		b1, b2 := b.split()
+		x.recurse(a1, b1, depth+1)
+		x.recurse(a1, b2, depth+1)
+		x.recurse(a2, b1, depth+1)
+		x.recurse(a2, b2, depth+1)
+	}
+}
+
+// coincidentPieces returns true if a and b trace the same curve, and whether
+// they do so in opposite directions.
+func coincidentPieces(a, b piece, tol float64) (bool, bool) {
+	if a.s.kind != b.s.kind {
+		return false, false
+	}
+	var rev bool
+	switch {
+	case coordNear(a.s.start, b.s.start, tol) && coordNear(a.s.end, b.s.end, tol):
+	case coordNear(a.s.start, b.s.end, tol) && coordNear(a.s.end, b.s.start, tol):
+		rev = true
+	default:
+		return false, false
+	}
+	// Curves of the same kind that agree at this many points are the same.
+	for _, t := range []float64{0.25, 0.5, 0.75} {
+		u := t
+		if rev {
+			u = 1 - t
+		}
+		if !coordNear(a.s.point(t), b.s.point(u), tol) {
+			return false, false
+		}
+	}
+	return rev, true
+}
+
+// collapseOverlaps replaces each run of intersections that lie along a
+// shared part of the segments with the ends of the run.
+func (x *intersector) collapseOverlaps(is []SegmentIntersection) []SegmentIntersection {
+	var r []SegmentIntersection
+	for i := 0; i < len(is); {
+		j := i
+		for j+1 < len(is) {
+			t := (is[j].T1 + is[j+1].T1) / 2
+			u := (is[j].T2 + is[j+1].T2) / 2
+			if !coordNear(x.a.point(t), x.b.point(u), x.mergeTol) {
+				break
+			}
+			j++
+		}
+		r = append(r, is[i])
+		if j > i {
+			r = append(r, is[j])
+		}
+		i = j + 1
+	}
+	return r
+}
+
+// add records an intersection near parameters t and u, polishing it with
+// Newton's method when curves are involved.
+func (x *intersector) add(t, u float64) {
+	if x.a.kind != 'L' || x.b.kind != 'L' {
+		t, u = refineIntersection(x.a, x.b, t, u)
+	}
+	x.r = append(x.r, SegmentIntersection{
+		Point: coordLerp(x.a.point(t), x.b.point(u), 0.5),
+		T1:    t,
+		T2:    u,
+	})
+}
+
+// refineIntersection improves the estimate that a(t) and b(u) are the same
+// point.  The estimate is returned unchanged if the iteration does not
+// improve it, as happens where the segments touch without crossing.
+func refineIntersection(a, b segment, t, u float64) (float64, float64) {
+	best := coordDist(a.point(t), b.point(u))
+	for i := 0; i < 8 && best > 0; i++ {
+		f := coordSub(a.point(t), b.point(u))
+		da, db := a.derivative(t), b.derivative(u)
+		den := coordCross(da, db)
+		if math.Abs(den) <= geomEpsilon*coordLen(da)*coordLen(db) {
+			break
+		}
+		// Solve da*dt - db*du = -f.
+		nt := t - coordCross(f, db)/den
+		nu := u - coordCross(f, da)/den
+		nt, nu = math.Max(0, math.Min(1, nt)), math.Max(0, math.Min(1, nu))
+		d := coordDist(a.point(nt), b.point(nu))
+		if d >= best {
+			break
+		}
+		t, u, best = nt, nu, d
+	}
+	return t, u
+}
+
+// intersectChords returns the parameter pairs where the straight segments
+// a1-a2 and b1-b2 meet.  Segments that come within tol of each other without
+// crossing, including parallel overlaps, meet at the end points that are
+// within tol of the other segment.
+func intersectChords(a1, a2, b1, b2 geom.Coord, tol float64) [][2]float64 {
+	r, s := coordSub(a2, a1), coordSub(b2, b1)
+	rl, sl := coordLen(r), coordLen(s)
+	if rl <= geomEpsilon || sl <= geomEpsilon {
+		if rl <= geomEpsilon && sl <= geomEpsilon {
+			if coordNear(a1, b1, tol) {
+				return [][2]float64{{0, 0}}
+			}
+			return nil
+		}
+	} else if den := coordCross(r, s); math.Abs(den) > geomEpsilon*rl*sl {
+		qp := coordSub(b1, a1)
+		t := coordCross(qp, s) / den
+		u := coordCross(qp, r) / den
+		et, eu := tol/rl, tol/sl
+		if t >= -et && t <= 1+et && u >= -eu && u <= 1+eu {
+			return [][2]float64{{math.Max(0, math.Min(1, t)), math.Max(0, math.Min(1, u))}}
+		}
+	}
+
+	var res [][2]float64
+	for i, p := range []geom.Coord{b1, b2} {
+		if d, t := pointSegmentDist(p, a1, a2); d <= tol {
+			res = append(res, [2]float64{t, float64(i)})
+		}
+	}
+	for i, p := range []geom.Coord{a1, a2} {
+		if d, u := pointSegmentDist(p, b1, b2); d <= tol {
+			res = append(res, [2]float64{float64(i), u})
+		}
+	}
+	return res
+}
+
+// mergeIntersections orders intersections along the first segment and
+// drops those within tol of the one before.
+func mergeIntersections(is []SegmentIntersection, tol float64) []SegmentIntersection {
+	sort.Slice(is, func(i, j int) bool { return is[i].T1 < is[j].T1 })
+	var r []SegmentIntersection
+	for _, x := range is {
+		if len(r) > 0 && coordNear(r[len(r)-1].Point, x.Point, tol) {
+			continue
+		}
+		r = append(r, x)
+	}
+	return r
+}
+
+// intersectSegmentLists returns the points where any segment in a meets any
+// segment in b.
+func intersectSegmentLists(a, b []segment) []geom.Coord {
+	if len(a) == 0 || len(b) == 0 {
+		return nil
+	}
+	ba, bb := segmentsBounds(a), segmentsBounds(b)
+	var r []geom.Coord
+	for i, sa := range a {
+		for j, sb := range b {
+			if !rectsOverlap(ba[i], bb[j], intersectTolerance*rectSize(ba[i], bb[j])) {
+				continue
+			}
+			for _, x := range intersectSegmentPair(sa, sb) {
+				r = append(r, x.Point)
+			}
+		}
+	}
+	return mergePoints(r, intersectMergeTolerance*rectSize(unionRects(ba), unionRects(bb)))
+}
+
+// selfIntersections returns the points where a chain of segments meets
+// itself other than where consecutive segments join.
+func selfIntersections(segs []segment) []geom.Coord {
+	if len(segs) == 0 {
+		return nil
+	}
+	closed := segs[len(segs)-1].end == segs[0].start
+	bounds := segmentsBounds(segs)
+
+	var r []geom.Coord
+	for i, sa := range segs {
+		r = append(r, cubicSelfIntersections(sa)...)
+		for j := i + 1; j < len(segs); j++ {
+			sb := segs[j]
+			scale := rectSize(bounds[i], bounds[j])
+			if !rectsOverlap(bounds[i], bounds[j], intersectTolerance*scale) {
+				continue
+			}
+			for _, x := range intersectSegmentPair(sa, sb) {
+				joint := intersectMergeTolerance * scale
+				if j == i+1 && coordNear(x.Point, sa.end, joint) {
+					continue
+				}
+				if closed && i == 0 && j == len(segs)-1 && coordNear(x.Point, sa.start, joint) {
+					continue
+				}
+				r = append(r, x.Point)
+			}
+		}
+	}
+	all := unionRects(bounds)
+	return mergePoints(r, intersectMergeTolerance*rectSize(all, all))
+}
+
+// cubicSelfIntersections returns the point where a cubic crosses itself, if
+// any.  A curve that turns through less than half a turn cannot cross itself
+// so the curve is halved until each part turns less than that, checking the
+// halves against each other along the way.
+func cubicSelfIntersections(s segment) []geom.Coord {
+	if s.kind != 'C' {
+		return nil
+	}
+	var r []geom.Coord
+	var check func(s segment, depth int)
+	check = func(s segment, depth int) {
+		if depth >= intersectMaxDepth || controlTurning(s) < math.Pi {
+			return
+		}
+		a, b := s.split(0.5)
+		tol := intersectMergeTolerance * rectSize(segmentBounds(a), segmentBounds(b))
+		for _, x := range intersectSegmentPair(a, b) {
+			if !coordNear(x.Point, a.end, tol) {
+				r = append(r, x.Point)
+			}
+		}
+		check(a, depth+1)
+		check(b, depth+1)
+	}
+	check(s, 0)
+	return r
+}
+
+// controlTurning returns the total turning of a cubic's control polygon,
+// which bounds the turning of the curve.
+func controlTurning(s segment) float64 {
+	var dirs []geom.Coord
+	for _, d := range []geom.Coord{
+		coordSub(s.ctrl1, s.start), coordSub(s.ctrl2, s.ctrl1), coordSub(s.end, s.ctrl2),
+	} {
+		if coordLen(d) > geomEpsilon {
+			dirs = append(dirs, d)
+		}
+	}
+	total := 0.0
+	for i := 1; i < len(dirs); i++ {
+		total += math.Abs(math.Atan2(coordCross(dirs[i-1], dirs[i]), coordDot(dirs[i-1], dirs[i])))
+	}
+	return total
+}
+
+// rectSize returns the largest dimension of two rectangles, or 1 if they are
+// both points.
+func rectSize(a, b geom.Rect) float64 {
+	s := math.Max(math.Max(a.Width(), a.Height()), math.Max(b.Width(), b.Height()))
+	if s <= 0 {
+		return 1
+	}
+	return s
+}
+
+// mergePoints removes points that are within tol of an earlier point.
+func mergePoints(pts []geom.Coord, tol float64) []geom.Coord {
+	var r []geom.Coord
+	for _, p := range pts {
+		dup := false
+		for _, q := range r {
+			if coordNear(p, q, tol) {
+				dup = true
+				break
+			}
+		}
+		if !dup {
+			r = append(r, p)
+		}
+	}
+	return r
+}
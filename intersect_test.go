@@ -0,0 +1,189 @@
+// Copyright 2018 Joe Beda
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package svgdata
+
+import (
+	"math"
+	"testing"
+
+	"github.com/jbeda/geom"
+	"github.com/stretchr/testify/assert"
+)
+
+// commandAt returns the ith command of a parsed path.
+func commandAt(t *testing.T, d string, i int) PathCommand {
+	var cmds []PathCommand
+	for _, sp := range mustParsePath(t, d) {
+		cmds = append(cmds, sp.Commands...)
+	}
+	return cmds[i]
+}
+
+func TestIntersectLines(t *testing.T) {
+	assert := assert.New(t)
+
+	a := commandAt(t, "M0,0 L10,10", 1)
+	b := commandAt(t, "M0,10 L10,0", 1)
+	is := IntersectCommands(a, b)
+	if assert.Len(is, 1) {
+		assert.InDelta(5, is[0].Point.X, 1e-9)
+		assert.InDelta(5, is[0].Point.Y, 1e-9)
+		assert.InDelta(0.5, is[0].T1, 1e-9)
+		assert.InDelta(0.5, is[0].T2, 1e-9)
+	}
+
+	// Parallel lines never meet and collinear ones meet where they overlap.
+	assert.Empty(IntersectCommands(a, commandAt(t, "M1,0 L11,10", 1)))
+	is = IntersectCommands(a, commandAt(t, "M5,5 L20,20", 1))
+	if assert.Len(is, 2) {
+		assert.InDelta(0.5, is[0].T1, 1e-9)
+		assert.InDelta(1, is[1].T1, 1e-9)
+	}
+}
+
+func TestIntersectCurves(t *testing.T) {
+	assert := assert.New(t)
+
+	// y = 30t(1-t) crosses y = 5 where t(1-t) = 1/6.
+	cubic := commandAt(t, "M0,0 C0,10 10,10 10,0", 1)
+	line := commandAt(t, "M0,5 L10,5", 1)
+	is := IntersectCommands(cubic, line)
+	if assert.Len(is, 2) {
+		t0 := (1 - math.Sqrt(1-4.0/6)) / 2
+		assert.InDelta(t0, is[0].T1, 1e-9)
+		assert.InDelta(1-t0, is[1].T1, 1e-9)
+		for _, x := range is {
+			assert.InDelta(5, x.Point.Y, 1e-9)
+			assert.InDelta(x.Point.X/10, x.T2, 1e-9)
+		}
+	}
+
+	// A half circle and the vertical line through its center.
+	arc := commandAt(t, "M0,0 A5,5 0 0 1 10,0", 1)
+	is = IntersectCommands(arc, commandAt(t, "M5,-10 L5,10", 1))
+	if assert.Len(is, 1) {
+		assert.InDelta(5, is[0].Point.X, 1e-9)
+		assert.InDelta(5, math.Abs(is[0].Point.Y), 1e-9)
+		assert.InDelta(0.5, is[0].T1, 1e-9)
+	}
+
+	// Overlapping curves report the ends of the overlap.
+	is = IntersectCommands(cubic, cubic)
+	if assert.Len(is, 2) {
+		assert.Equal(0.0, is[0].T1)
+		assert.Equal(1.0, is[1].T1)
+	}
+
+	// Two quadratics that are mirror images cross on the mirror line.
+	q1 := commandAt(t, "M0,0 Q5,10 10,0", 1)
+	q2 := commandAt(t, "M0,6 Q5,-4 10,6", 1)
+	is = IntersectCommands(q1, q2)
+	if assert.Len(is, 2) {
+		for _, x := range is {
+			assert.InDelta(3, x.Point.Y, 1e-9)
+		}
+	}
+
+	// A line touching a circle.
+	sps := NewCircle(geom.Coord{X: 0, Y: 0}, 5).ToSubPaths()
+	touch := mustParsePath(t, "M-10,5 L10,5")
+	pts := IntersectSubPaths(sps[0], touch[0])
+	if assert.Len(pts, 1) {
+		assert.InDelta(0, pts[0].X, 1e-3)
+		assert.InDelta(5, pts[0].Y, 1e-6)
+	}
+}
+
+func TestSelfIntersections(t *testing.T) {
+	assert := assert.New(t)
+
+	square := mustParsePath(t, "M0,0 h10 v10 h-10 z")
+	assert.Empty(square[0].SelfIntersections())
+
+	bowtie := mustParsePath(t, "M0,0 L10,10 L10,0 L0,10 Z")
+	pts := bowtie[0].SelfIntersections()
+	if assert.Len(pts, 1) {
+		assert.InDelta(5, pts[0].X, 1e-9)
+		assert.InDelta(5, pts[0].Y, 1e-9)
+	}
+
+	// A single cubic with a loop.
+	loop := mustParsePath(t, "M0,0 C20,20 -10,20 10,0")
+	pts = loop[0].SelfIntersections()
+	if assert.Len(pts, 1) {
+		assert.InDelta(5, pts[0].X, 1e-9)
+	}
+
+	// Smooth joins are not intersections.
+	circle := NewCircle(geom.Coord{X: 0, Y: 0}, 5).ToSubPaths()
+	assert.Empty(circle[0].SelfIntersections())
+}
+
+func TestSegmentTransform(t *testing.T) {
+	assert := assert.New(t)
+
+	s := subPathSegments(mustParsePath(t, "M0,0 A5,5 0 0 1 10,0")[0])[0]
+	for _, tr := range []Transform{
+		NewScale(2, 1),
+		NewRotate(30).Multiply(NewScale(3, 1)),
+		NewScale(-1, 2).Multiply(NewRotate(10)),
+	} {
+		ts := s.transform(tr)
+		for i := 0; i <= 8; i++ {
+			u := float64(i) / 8
+			p, q := tr.Apply(s.point(u)), ts.point(u)
+			assert.InDelta(p.X, q.X, 1e-9)
+			assert.InDelta(p.Y, q.Y, 1e-9)
+		}
+	}
+}
+
+func TestRootIntersections(t *testing.T) {
+	assert := assert.New(t)
+
+	r, err := Unmarshal([]byte(`<svg xmlns="http://www.w3.org/2000/svg">
+  <rect id="a" x="0" y="0" width="10" height="10"/>
+  <g transform="translate(20,0)">
+    <rect id="b" x="-15" y="5" width="10" height="10"/>
+  </g>
+  <path id="c" d="M30,0 L40,10 L40,0 L30,10 Z"/>
+  <circle id="d" cx="50" cy="50" r="5"/>
+</svg>`))
+	assert.NoError(err)
+
+	ir, err := r.Intersections()
+	assert.NoError(err)
+	assert.False(ir.Empty())
+	if assert.Len(ir.Crossings, 1) {
+		c := ir.Crossings[0]
+		assert.Equal(FindByID(r, "a"), c.A)
+		assert.Equal(FindByID(r, "b"), c.B)
+		assert.ElementsMatch([]geom.Coord{{X: 10, Y: 5}, {X: 5, Y: 10}}, roundCoords(c.Points))
+	}
+	if assert.Len(ir.SelfIntersections, 1) {
+		si := ir.SelfIntersections[0]
+		assert.Equal(FindByID(r, "c"), si.Node)
+		assert.Equal(0, si.SubPath)
+		assert.Equal([]geom.Coord{{X: 35, Y: 5}}, roundCoords(si.Points))
+	}
+}
+
+func roundCoords(pts []geom.Coord) []geom.Coord {
+	r := make([]geom.Coord, len(pts))
+	for i, p := range pts {
+		r[i] = geom.Coord{X: math.Round(p.X*1e6) / 1e6, Y: math.Round(p.Y*1e6) / 1e6}
+	}
+	return r
+}
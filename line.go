@@ -0,0 +1,73 @@
+// Copyright 2018 Joe Beda
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package svgdata
+
+import "github.com/jbeda/geom"
+
+// Line is a straight line segment from P1 to P2.
+type Line struct {
+	nodeImpl
+	P1, P2 geom.Coord
+}
+
+var _ Shape = (*Line)(nil)
+
+func init() {
+	RegisterNodeCreator("line", func() Node { return createLine() })
+}
+
+func createLine() *Line {
+	l := &Line{}
+	l.nodeImpl.name = "line"
+	l.nodeImpl.onMarshalAttrs = l.marshalAttrs
+	l.nodeImpl.onUnmarshalAttrs = l.unmarshalAttrs
+	return l
+}
+
+func NewLine(p1, p2 geom.Coord) *Line {
+	n := createLine()
+	n.P1 = p1
+	n.P2 = p2
+	return n
+}
+
+// ToSubPaths returns the line as an open subpath from P1 to P2.
+func (l *Line) ToSubPaths() []SubPath {
+	return BuildSubPaths([]PathCommand{
+		{Command: 'M', Params: []float64{l.P1.X, l.P1.Y}},
+		{Command: 'L', Params: []float64{l.P2.X, l.P2.Y}},
+	})
+}
+
+func (l *Line) marshalAttrs(am AttrMap) {
+	am["x1"] = floatToString(l.P1.X)
+	am["y1"] = floatToString(l.P1.Y)
+	am["x2"] = floatToString(l.P2.X)
+	am["y2"] = floatToString(l.P2.Y)
+}
+
+func (l *Line) unmarshalAttrs(am AttrMap) error {
+	var v [4]float64
+	for i, k := range []string{"x1", "y1", "x2", "y2"} {
+		f, err := am.ExtractValue(k)
+		if err != nil {
+			return err
+		}
+		v[i] = f
+	}
+	l.P1 = geom.Coord{X: v[0], Y: v[1]}
+	l.P2 = geom.Coord{X: v[2], Y: v[3]}
+	return nil
+}
@@ -0,0 +1,277 @@
+// Copyright 2018 Joe Beda
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package svgdata
+
+import (
+	"math"
+
+	"github.com/jbeda/geom"
+)
+
+// Gauss-Legendre nodes and weights on [-1, 1] used to integrate speed along a
+// curve.
+var glNodes = [5]float64{0, -0.5384693101056831, 0.5384693101056831, -0.9061798459386640, 0.9061798459386640}
+var glWeights = [5]float64{0.5688888888888889, 0.4786286704993665, 0.4786286704993665, 0.2369268850561891, 0.2369268850561891}
+
+// lengthEpsilon is the relative accuracy used when measuring curves.
+const lengthEpsilon = 1e-10
+
+// nearestSamples is the number of samples per segment used to seed a nearest
+// point search.
+const nearestSamples = 32
+
+// Length returns the arc length of the subpath.
+func (sp SubPath) Length() float64 {
+	l := 0.0
+	for _, s := range subPathSegments(sp) {
+		l += s.length()
+	}
+	return l
+}
+
+// Length returns the sum of the lengths of all of the subpaths.
+func (p *Path) Length() float64 {
+	return subPathsLength(p.SubPaths)
+}
+
+func subPathsLength(sps []SubPath) float64 {
+	l := 0.0
+	for _, sp := range sps {
+		l += sp.Length()
+	}
+	return l
+}
+
+// PointAtLength returns the point that is d along the subpath.  Values of d
+// outside of the subpath are clamped to the start or end.
+func (sp SubPath) PointAtLength(d float64) geom.Coord {
+	s, t, ok := locateLength(subPathSegments(sp), d)
+	if !ok {
+		return sp.Start()
+	}
+	return s.point(t)
+}
+
+// TangentAtLength returns the unit direction of travel at d along the
+// subpath.  A zero vector is returned for a subpath with no length.
+func (sp SubPath) TangentAtLength(d float64) geom.Coord {
+	s, t, ok := locateLength(subPathSegments(sp), d)
+	if !ok {
+		return geom.Coord{}
+	}
+	return s.tangent(t)
+}
+
+// NearestPoint returns the point on the subpath closest to c along with its
+// distance along the subpath.
+func (sp SubPath) NearestPoint(c geom.Coord) (geom.Coord, float64) {
+	best, bestAlong, bestDist := sp.Start(), 0.0, coordDist(c, sp.Start())
+	along := 0.0
+	for _, s := range subPathSegments(sp) {
+		t, p := s.nearest(c)
+		if d := coordDist(c, p); d < bestDist {
+			best, bestAlong, bestDist = p, along+s.lengthTo(t), d
+		}
+		along += s.length()
+	}
+	return best, bestAlong
+}
+
+// PointAtLength returns the point that is d along the path, measured across
+// all subpaths in order.
+func (p *Path) PointAtLength(d float64) geom.Coord {
+	sp, rest := p.subPathAtLength(d)
+	if sp == nil {
+		return geom.Coord{}
+	}
+	return sp.PointAtLength(rest)
+}
+
+// TangentAtLength returns the unit direction of travel at d along the path.
+func (p *Path) TangentAtLength(d float64) geom.Coord {
+	sp, rest := p.subPathAtLength(d)
+	if sp == nil {
+		return geom.Coord{}
+	}
+	return sp.TangentAtLength(rest)
+}
+
+// NearestPoint returns the point on the path closest to c along with its
+// distance along the path, measured across all subpaths in order.
+func (p *Path) NearestPoint(c geom.Coord) (geom.Coord, float64) {
+	var best geom.Coord
+	bestAlong, bestDist := 0.0, math.Inf(1)
+	along := 0.0
+	for _, sp := range p.SubPaths {
+		pt, a := sp.NearestPoint(c)
+		if d := coordDist(c, pt); d < bestDist {
+			best, bestAlong, bestDist = pt, along+a, d
+		}
+		along += sp.Length()
+	}
+	return best, bestAlong
+}
+
+// subPathAtLength finds the subpath that contains the point d along the path
+// and returns the remaining distance into that subpath.
+func (p *Path) subPathAtLength(d float64) (*SubPath, float64) {
+	for i := range p.SubPaths {
+		sp := &p.SubPaths[i]
+		l := sp.Length()
+		if d <= l || i == len(p.SubPaths)-1 {
+			return sp, d
+		}
+		d -= l
+	}
+	return nil, 0
+}
+
+// locateLength finds the segment and parameter that is d along segs.
+func locateLength(segs []segment, d float64) (segment, float64, bool) {
+	if len(segs) == 0 {
+		return segment{}, 0, false
+	}
+	for i, s := range segs {
+		l := s.length()
+		if d <= l || i == len(segs)-1 {
+			return s, s.paramAtLength(d), true
+		}
+		d -= l
+	}
+	return segment{}, 0, false
+}
+
+// length returns the arc length of the segment.
+func (s segment) length() float64 {
+	return s.lengthTo(1)
+}
+
+// lengthTo returns the arc length of the segment from 0 to t.
+func (s segment) lengthTo(t float64) float64 {
+	switch s.kind {
+	case 'L':
+		return coordDist(s.start, s.end) * t
+	case 'A':
+		if ea := s.ellipse(); ea.isCircular() {
+			return ea.rx * math.Abs(ea.delta) * t
+		}
+	}
+	whole := s.speedIntegral(0, t)
+	return s.adaptiveLength(0, t, whole, 0)
+}
+
+func (s segment) adaptiveLength(a, b, whole float64, depth int) float64 {
+	m := (a + b) / 2
+	left, right := s.speedIntegral(a, m), s.speedIntegral(m, b)
+	if depth >= 20 || math.Abs(left+right-whole) <= lengthEpsilon*math.Max(1, whole) {
+		return left + right
+	}
+	return s.adaptiveLength(a, m, left, depth+1) + s.adaptiveLength(m, b, right, depth+1)
+}
+
+// speedIntegral integrates |s'(t)| from a to b with Gauss-Legendre
+// quadrature.
+func (s segment) speedIntegral(a, b float64) float64 {
+	h := (b - a) / 2
+	c := (a + b) / 2
+	sum := 0.0
+	for i, x := range glNodes {
+		sum += glWeights[i] * coordLen(s.derivative(c+h*x))
+	}
+	return sum * h
+}
+
+// paramAtLength returns the parameter t where the length from the start of the
+// segment is d.
+func (s segment) paramAtLength(d float64) float64 {
+	l := s.length()
+	if d <= 0 || l == 0 {
+		return 0
+	}
+	if d >= l {
+		return 1
+	}
+	if s.kind == 'L' {
+		return d / l
+	}
+
+	// Newton's method falling back to bisection when it misbehaves.
+	lo, hi := 0.0, 1.0
+	t := d / l
+	for i := 0; i < 50; i++ {
+		f := s.lengthTo(t) - d
+		if math.Abs(f) <= lengthEpsilon*math.Max(1, l) {
+			break
+		}
+		if f > 0 {
+			hi = t
+		} else {
+			lo = t
+		}
+		nt := t - f/coordLen(s.derivative(t))
+		if math.IsNaN(nt) || nt <= lo || nt >= hi {
+			nt = (lo + hi) / 2
+		}
+		t = nt
+	}
+	return t
+}
+
+// tangent returns the unit direction of travel at t.  Where the derivative
+// vanishes, such as at a cusp or a control point that coincides with an end
+// point, the direction is estimated from nearby points.
+func (s segment) tangent(t float64) geom.Coord {
+	d := s.derivative(t)
+	if coordLen(d) > geomEpsilon {
+		return coordUnit(d)
+	}
+	const h = 1e-6
+	a, b := math.Max(0, t-h), math.Min(1, t+h)
+	return coordUnit(coordSub(s.point(b), s.point(a)))
+}
+
+// nearest returns the parameter and position of the point on the segment
+// closest to c.
+func (s segment) nearest(c geom.Coord) (float64, geom.Coord) {
+	if s.kind == 'L' {
+		_, t := pointSegmentDist(c, s.start, s.end)
+		return t, s.point(t)
+	}
+
+	// Sample to find the closest region and then narrow in with a golden
+	// section search.
+	bestT, bestD := 0.0, math.Inf(1)
+	for i := 0; i <= nearestSamples; i++ {
+		t := float64(i) / nearestSamples
+		if d := coordDist(c, s.point(t)); d < bestD {
+			bestT, bestD = t, d
+		}
+	}
+
+	const gr = 0.6180339887498949
+	lo := math.Max(0, bestT-1.0/nearestSamples)
+	hi := math.Min(1, bestT+1.0/nearestSamples)
+	for hi-lo > 1e-12 {
+		m1 := hi - gr*(hi-lo)
+		m2 := lo + gr*(hi-lo)
+		if coordDist(c, s.point(m1)) < coordDist(c, s.point(m2)) {
+			hi = m2
+		} else {
+			lo = m1
+		}
+	}
+	t := (lo + hi) / 2
+	return t, s.point(t)
+}
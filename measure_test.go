@@ -0,0 +1,127 @@
+// Copyright 2018 Joe Beda
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package svgdata
+
+import (
+	"math"
+	"testing"
+
+	"github.com/jbeda/geom"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLengthLinesAndArcs(t *testing.T) {
+	assert := assert.New(t)
+
+	sps, err := ParsePathString("M0,0 h3 v4 z")
+	assert.NoError(err)
+	assert.InDelta(12, sps[0].Length(), 1e-9)
+
+	// Half of a circle of radius 10
+	sps, err = ParsePathString("M10,0 A10,10 0 0 1 -10,0")
+	assert.NoError(err)
+	assert.InDelta(10*math.Pi, sps[0].Length(), 1e-9)
+
+	// Half of an ellipse with radii 20 and 10 measured against flattening
+	sps, err = ParsePathString("M20,0 A20,10 0 0 1 -20,0")
+	assert.NoError(err)
+	assert.InDelta(polylineLength(sps[0].Flatten(1e-5)), sps[0].Length(), 1e-3)
+}
+
+func TestLengthCurves(t *testing.T) {
+	assert := assert.New(t)
+
+	// A straight cubic has the length of its chord.
+	sps, err := ParsePathString("M0,0 C1,0 2,0 3,0")
+	assert.NoError(err)
+	assert.InDelta(3, sps[0].Length(), 1e-9)
+
+	sps, err = ParsePathString("M0,0 C0,50 100,50 100,0 S200,-50 200,0 Q250,50 300,0 T400,0")
+	assert.NoError(err)
+	assert.InDelta(polylineLength(sps[0].Flatten(1e-5)), sps[0].Length(), 1e-3)
+}
+
+func TestPointAndTangentAtLength(t *testing.T) {
+	assert := assert.New(t)
+
+	p := NewPath()
+	var err error
+	p.SubPaths, err = ParsePathString("M0,0 h10 M0,10 A10,10 0 0 0 10,0")
+	assert.NoError(err)
+
+	assert.Equal(geom.Coord{X: 4, Y: 0}, p.PointAtLength(4))
+	assert.Equal(geom.Coord{X: 1, Y: 0}, p.TangentAtLength(4))
+	assert.Equal(geom.Coord{X: 0, Y: 0}, p.PointAtLength(-1))
+
+	// Half way around the quarter circle.
+	quarter := 10 * math.Pi / 2
+	pt := p.PointAtLength(10 + quarter/2)
+	assert.InDelta(10*math.Cos(math.Pi/4), pt.X, 1e-9)
+	assert.InDelta(10*math.Sin(math.Pi/4), pt.Y, 1e-9)
+	tan := p.TangentAtLength(10 + quarter/2)
+	assert.InDelta(math.Sqrt(0.5), tan.X, 1e-9)
+	assert.InDelta(-math.Sqrt(0.5), tan.Y, 1e-9)
+
+	pt = p.PointAtLength(100)
+	assert.InDelta(10, pt.X, 1e-9)
+	assert.InDelta(0, pt.Y, 1e-9)
+
+	// Distances on a curve round trip through the nearest point.
+	sps, err := ParsePathString("M0,0 C0,50 100,50 100,0")
+	assert.NoError(err)
+	for _, d := range []float64{0, 10, 42.5, 80} {
+		pt, along := sps[0].NearestPoint(sps[0].PointAtLength(d))
+		assert.InDelta(d, along, 1e-6)
+		assert.InDelta(0, coordDist(pt, sps[0].PointAtLength(d)), 1e-6)
+	}
+
+	// A curve that starts with a zero length control handle still has a
+	// direction.
+	sps, err = ParsePathString("M0,0 C0,0 10,10 10,0")
+	assert.NoError(err)
+	tan = sps[0].TangentAtLength(0)
+	assert.InDelta(1, coordLen(tan), 1e-9)
+}
+
+func TestNearestPoint(t *testing.T) {
+	assert := assert.New(t)
+
+	p := NewPath()
+	var err error
+	p.SubPaths, err = ParsePathString("M0,0 h10 M0,10 h10")
+	assert.NoError(err)
+
+	pt, along := p.NearestPoint(geom.Coord{X: 3, Y: 8})
+	assert.Equal(geom.Coord{X: 3, Y: 10}, pt)
+	assert.InDelta(13, along, 1e-9)
+
+	sps, err := ParsePathString("M10,0 A10,10 0 0 1 -10,0")
+	assert.NoError(err)
+	pt, along = sps[0].NearestPoint(geom.Coord{X: 0, Y: 20})
+	assert.InDelta(0, pt.X, 1e-6)
+	assert.InDelta(10, pt.Y, 1e-6)
+	assert.InDelta(5*math.Pi, along, 1e-6)
+}
+
+func polylineLength(pl Polyline) float64 {
+	l := 0.0
+	for i := 1; i < len(pl.Points); i++ {
+		l += coordDist(pl.Points[i-1], pl.Points[i])
+	}
+	if pl.Closed && len(pl.Points) > 1 {
+		l += coordDist(pl.Points[len(pl.Points)-1], pl.Points[0])
+	}
+	return l
+}
@@ -0,0 +1,382 @@
+// Copyright 2018 Joe Beda
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package svgdata
+
+import (
+	"math"
+
+	"github.com/jbeda/geom"
+)
+
+// JoinStyle is how the gap at a corner is filled when offsetting or stroking.
+type JoinStyle int
+
+const (
+	JoinMiter JoinStyle = iota
+	JoinRound
+	JoinSquare
+	JoinBevel
+)
+
+// defaultMiterLimit matches the SVG default for stroke-miterlimit.
+const defaultMiterLimit = 4
+
+// OffsetOptions control how geometry is offset.
+type OffsetOptions struct {
+	Join JoinStyle
+
+	// MiterLimit is the largest ratio between the distance from a corner to the
+	// tip of its miter and the offset distance.  Corners that would exceed it
+	// are beveled.  Zero means the SVG default of 4.
+	MiterLimit float64
+
+	// Tolerance is the largest distance curves may deviate when flattened.
+	// Circular arcs are kept as arcs.
+	Tolerance float64
+}
+
+// OffsetSubPaths grows (d > 0) or shrinks (d < 0) the area filled by sps
+// using the given fill rule.  Open subpaths are closed as they are when
+// filled.  Lines and circular arcs are offset exactly while other curves are
+// flattened first.  Parts that shrink away are removed, and self intersections
+// are cleaned up so the result fills correctly with either fill rule.
+// Circular arcs in the input and round joins are returned as arcs.
+func OffsetSubPaths(sps []SubPath, d float64, fillRule string, opts OffsetOptions) []SubPath {
+	tol := math.Max(opts.Tolerance, minTolerance)
+	if opts.MiterLimit <= 0 {
+		opts.MiterLimit = defaultMiterLimit
+	}
+
+	// Normalize the input first so that every contour has the filled area on
+	// its left and nothing overlaps.  Circular arcs are recovered afterwards.
+	src := region{pls: FlattenSubPaths(sps, tol), evenOdd: fillRule == "evenodd"}
+	norm := fitArcs(clipRegions(Union, []region{src}, nil), subPathCircles(sps), tol)
+
+	var loops []Polyline
+	var circles []circle
+	for _, sp := range norm {
+		pts, cs := offsetLoop(offsetItems(sp, tol), d, opts, tol)
+		loops = append(loops, Polyline{Points: pts, Closed: true})
+		circles = append(circles, cs...)
+	}
+
+	pls := clipRegions(Union, []region{{pls: loops, positive: true}}, nil)
+	return fitArcs(pls, circles, tol)
+}
+
+// OffsetShape offsets the area filled by a shape, honoring its fill-rule.  The
+// result is a new path that carries over the attributes of s.
+func OffsetShape(s Shape, d float64, opts OffsetOptions) *Path {
+	return newPathFromNode(s, OffsetSubPaths(s.ToSubPaths(), d, NodeStyle(s).FillRule(), opts))
+}
+
+// offsetItem is a line or circular arc that makes up a contour being offset.
+type offsetItem struct {
+	start, end geom.Coord
+
+	arc    bool
+	center geom.Coord
+	radius float64
+	delta  float64 // Signed sweep in radians, positive is sweep-flag 1
+}
+
+// tangent returns the unit direction of travel at p, which must be on the
+// item.
+func (it offsetItem) tangent(p geom.Coord) geom.Coord {
+	if !it.arc {
+		return coordUnit(coordSub(it.end, it.start))
+	}
+	t := coordPerp(coordUnit(coordSub(p, it.center)))
+	if it.delta < 0 {
+		return coordScale(t, -1)
+	}
+	return t
+}
+
+// offsetItems breaks a subpath down into lines and circular arcs.  The
+// subpath is closed if it isn't already.
+func offsetItems(sp SubPath, tol float64) []offsetItem {
+	var items []offsetItem
+	addLine := func(a, b geom.Coord) {
+		if a != b {
+			items = append(items, offsetItem{start: a, end: b})
+		}
+	}
+
+	for _, s := range subPathSegments(sp) {
+		switch s.kind {
+		case 'L':
+			addLine(s.start, s.end)
+		case 'A':
+			if ea := s.ellipse(); ea.isCircular() {
+				items = append(items, offsetItem{
+					start: s.start, end: s.end,
+					arc: true, center: ea.center, radius: ea.rx, delta: ea.delta,
+				})
+				continue
+			}
+			fallthrough
+		default:
+			pts := s.flatten(tol, []geom.Coord{s.start})
+			for i := 1; i < len(pts); i++ {
+				addLine(pts[i-1], pts[i])
+			}
+		}
+	}
+	if len(items) > 0 {
+		addLine(items[len(items)-1].end, items[0].start)
+	}
+	return items
+}
+
+// circle is a circle that arcs in an offset result may lie on.
+type circle struct {
+	center geom.Coord
+	radius float64
+}
+
+// subPathCircles returns the circles of all circular arcs in sps.
+func subPathCircles(sps []SubPath) []circle {
+	var r []circle
+	for _, sp := range sps {
+		for _, s := range subPathSegments(sp) {
+			if s.kind != 'A' {
+				continue
+			}
+			if ea := s.ellipse(); ea.isCircular() {
+				r = append(r, circle{ea.center, ea.rx})
+			}
+		}
+	}
+	return r
+}
+
+// offsetLoop offsets a contour with the filled area on its left by d towards
+// its right and returns the flattened raw result along with the circles that
+// arcs in it lie on.  The raw result may intersect itself.
+func offsetLoop(items []offsetItem, d float64, opts OffsetOptions, tol float64) ([]geom.Coord, []circle) {
+	var pts []geom.Coord
+	var circles []circle
+
+	type offsetted struct {
+		start, end geom.Coord
+	}
+	offs := make([]offsetted, len(items))
+	for i, it := range items {
+		if !it.arc {
+			n := coordScale(coordPerp(it.tangent(it.start)), -d)
+			offs[i] = offsetted{coordAdd(it.start, n), coordAdd(it.end, n)}
+			continue
+		}
+		r := it.radius + d*math.Copysign(1, it.delta)
+		if r <= tol {
+			// The arc shrinks down to its center.
+			offs[i] = offsetted{it.center, it.center}
+			continue
+		}
+		circles = append(circles, circle{it.center, r})
+		offs[i] = offsetted{
+			coordAdd(it.center, coordScale(coordUnit(coordSub(it.start, it.center)), r)),
+			coordAdd(it.center, coordScale(coordUnit(coordSub(it.end, it.center)), r)),
+		}
+	}
+
+	for i, it := range items {
+		o := offs[i]
+		pts = append(pts, o.start)
+		if it.arc && o.start != o.end {
+			ea := ellipseArc{center: it.center, rx: coordDist(o.start, it.center)}
+			ea.ry = ea.rx
+			a := math.Atan2(o.start.Y-it.center.Y, o.start.X-it.center.X)
+			steps := ellipseArc{rx: ea.rx, ry: ea.rx, delta: it.delta}.steps(tol)
+			for s := 1; s < steps; s++ {
+				pts = append(pts, ea.point(a+it.delta*float64(s)/float64(steps)))
+			}
+		}
+		pts = append(pts, o.end)
+
+		next := items[(i+1)%len(items)]
+		nextStart := offs[(i+1)%len(items)].start
+		jp, jc := offsetJoin(it.end, it.tangent(it.end), next.tangent(next.start), o.end, nextStart, d, opts, tol)
+		pts = append(pts, jp...)
+		circles = append(circles, jc...)
+	}
+	return pts, circles
+}
+
+// offsetJoin returns the points that connect the end of one offset item, e, to
+// the start of the next, b.  v is the original corner and tin and tout the
+// directions of travel into and out of it.
+func offsetJoin(v, tin, tout, e, b geom.Coord, d float64, opts OffsetOptions, tol float64) ([]geom.Coord, []circle) {
+	if coordNear(e, b, tol/100) {
+		return nil, nil
+	}
+
+	turn := coordCross(tin, tout)
+	gap := turn*d > 0 || (math.Abs(turn) <= geomEpsilon && coordDot(tin, tout) < 0)
+	if !gap {
+		// Go through the corner.  The loops this creates are removed when the
+		// result is cleaned up.
+		return []geom.Coord{v}, nil
+	}
+
+	ad := math.Abs(d)
+	switch opts.Join {
+	case JoinRound:
+		mid := coordAdd(coordSub(e, v), coordSub(b, v))
+		if coordLen(mid) <= geomEpsilon*ad {
+			mid = tin
+		}
+		mid = coordAdd(v, coordScale(coordUnit(mid), ad))
+		a0 := math.Atan2(e.Y-v.Y, e.X-v.X)
+		a1 := math.Atan2(b.Y-v.Y, b.X-v.X)
+		delta := normalizeAngle(a1 - a0)
+		if coordCross(coordSub(e, v), coordSub(mid, v)) < 0 {
+			delta = delta - 2*math.Pi
+		}
+		ea := ellipseArc{center: v, rx: ad, ry: ad, delta: delta}
+		var pts []geom.Coord
+		steps := ea.steps(tol)
+		for s := 1; s < steps; s++ {
+			pts = append(pts, ea.point(a0+delta*float64(s)/float64(steps)))
+		}
+		return pts, []circle{{v, ad}}
+	case JoinSquare:
+		return []geom.Coord{coordAdd(e, coordScale(tin, ad)), coordSub(b, coordScale(tout, ad))}, nil
+	case JoinMiter:
+		if p, ok := lineIntersection(e, tin, b, tout); ok && coordDist(p, v) <= opts.MiterLimit*ad {
+			return []geom.Coord{p}, nil
+		}
+	}
+	return nil, nil
+}
+
+// normalizeAngle returns a in [0, 2π).
+func normalizeAngle(a float64) float64 {
+	a = math.Mod(a, 2*math.Pi)
+	if a < 0 {
+		a += 2 * math.Pi
+	}
+	return a
+}
+
+// lineIntersection returns the point where the infinite lines through p with
+// direction r and through q with direction s cross.
+func lineIntersection(p, r, q, s geom.Coord) (geom.Coord, bool) {
+	den := coordCross(r, s)
+	if math.Abs(den) <= geomEpsilon {
+		return geom.Coord{}, false
+	}
+	t := coordCross(coordSub(q, p), s) / den
+	return coordAdd(p, coordScale(r, t)), true
+}
+
+// fitArcs converts closed polylines to subpaths, turning runs of points that
+// lie on one of the circles into arcs.
+func fitArcs(pls []Polyline, circles []circle, tol float64) []SubPath {
+	var cmds []PathCommand
+	for _, pl := range pls {
+		cmds = append(cmds, fitArcLoop(pl.Points, circles, tol)...)
+	}
+	return BuildSubPaths(cmds)
+}
+
+// edgeCircle returns the index of a circle that the chord a-b approximates
+// along with the signed angle the chord covers.
+func edgeCircle(a, b geom.Coord, circles []circle, tol float64) (int, float64) {
+	for i, c := range circles {
+		if math.Abs(coordDist(a, c.center)-c.radius) > tol ||
+			math.Abs(coordDist(b, c.center)-c.radius) > tol {
+			continue
+		}
+		half := coordDist(a, b) / 2
+		if half >= c.radius || c.radius-math.Sqrt(c.radius*c.radius-half*half) > tol*1.01 {
+			continue
+		}
+		ra, rb := coordSub(a, c.center), coordSub(b, c.center)
+		return i, math.Atan2(coordCross(ra, rb), coordDot(ra, rb))
+	}
+	return -1, 0
+}
+
+func fitArcLoop(pts []geom.Coord, circles []circle, tol float64) []PathCommand {
+	n := len(pts)
+	ids := make([]int, n)
+	angles := make([]float64, n)
+	for i := range pts {
+		ids[i], angles[i] = edgeCircle(pts[i], pts[(i+1)%n], circles, tol)
+	}
+	sameRun := func(i, j int) bool {
+		return ids[i] >= 0 && ids[i] == ids[j] && (angles[i] > 0) == (angles[j] > 0)
+	}
+
+	// Start at the beginning of a run so that no run wraps around.
+	start := 0
+	for i := 0; i < n; i++ {
+		if !sameRun((i+n-1)%n, i) {
+			start = i
+			break
+		}
+	}
+
+	cmds := []PathCommand{{Command: 'M', Params: []float64{pts[start].X, pts[start].Y}}}
+	for k := 0; k < n; {
+		i := (start + k) % n
+		if ids[i] < 0 {
+			// The final line back to the start is covered by the close.
+			if k != n-1 {
+				p := pts[(i+1)%n]
+				cmds = append(cmds, PathCommand{Command: 'L', Params: []float64{p.X, p.Y}})
+			}
+			k++
+			continue
+		}
+
+		// Gather the run and split it in half if it covers more than half a
+		// circle so the arc flags are never ambiguous.
+		l := 1
+		for k+l < n && sameRun(i, (i+l)%n) {
+			l++
+		}
+		total := 0.0
+		for j := 0; j < l; j++ {
+			total += angles[(i+j)%n]
+		}
+		parts := []int{l}
+		if math.Abs(total) > math.Pi+geomEpsilon && l > 1 {
+			parts = []int{l / 2, l - l/2}
+		}
+		for _, pl := range parts {
+			r := circles[ids[i]].radius
+			a := 0.0
+			for j := 0; j < pl; j++ {
+				a += angles[(start+k+j)%n]
+			}
+			p := pts[(start+k+pl)%n]
+			cmds = append(cmds, PathCommand{Command: 'A', Params: []float64{
+				r, r, 0, boolToFlag(math.Abs(a) > math.Pi+geomEpsilon), boolToFlag(a > 0), p.X, p.Y,
+			}})
+			k += pl
+		}
+	}
+	return append(cmds, PathCommand{Command: 'Z'})
+}
+
+func boolToFlag(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
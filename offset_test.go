@@ -0,0 +1,147 @@
+// Copyright 2018 Joe Beda
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package svgdata
+
+import (
+	"math"
+	"testing"
+
+	"github.com/jbeda/geom"
+	"github.com/stretchr/testify/assert"
+)
+
+func countCommands(sps []SubPath, c byte) int {
+	n := 0
+	for _, sp := range sps {
+		for _, cmd := range sp.Commands {
+			if cmd.Command == c {
+				n++
+			}
+		}
+	}
+	return n
+}
+
+func TestOffsetSquare(t *testing.T) {
+	assert := assert.New(t)
+
+	sq := mustParsePath(t, "M0,0 h10 v10 h-10 z")
+	opts := OffsetOptions{Tolerance: 0.001}
+
+	r := OffsetSubPaths(sq, 1, "nonzero", opts)
+	assert.Len(r, 1)
+	assert.InDelta(144, subPathsArea(r), 1e-9)
+	assert.Equal(3, countCommands(r, 'L'))
+
+	r = OffsetSubPaths(sq, -1, "nonzero", opts)
+	assert.InDelta(64, subPathsArea(r), 1e-9)
+
+	opts.Join = JoinRound
+	r = OffsetSubPaths(sq, 1, "nonzero", opts)
+	assert.InDelta(140+math.Pi, subPathsArea(r), 0.01)
+	assert.Equal(4, countCommands(r, 'A'))
+
+	opts.Join = JoinBevel
+	r = OffsetSubPaths(sq, 1, "nonzero", opts)
+	assert.InDelta(142, subPathsArea(r), 1e-9)
+
+	opts.Join = JoinSquare
+	r = OffsetSubPaths(sq, 1, "nonzero", opts)
+	assert.InDelta(144, subPathsArea(r), 1e-9)
+
+	// Shrinking past the middle removes the shape.
+	r = OffsetSubPaths(sq, -6, "nonzero", opts)
+	assert.Len(r, 0)
+}
+
+func TestOffsetMiterLimit(t *testing.T) {
+	assert := assert.New(t)
+
+	// A sharp spike gets beveled once the miter is too long.
+	spike := mustParsePath(t, "M0,0 L100,5 L0,10 z")
+	r := OffsetSubPaths(spike, 1, "nonzero", OffsetOptions{Tolerance: 0.001})
+	tip := 0.0
+	for _, pl := range FlattenSubPaths(r, 0.001) {
+		for _, p := range pl.Points {
+			tip = math.Max(tip, p.X)
+		}
+	}
+	assert.True(tip < 100+4)
+
+	r = OffsetSubPaths(spike, 1, "nonzero", OffsetOptions{Tolerance: 0.001, MiterLimit: 100})
+	tip = 0.0
+	for _, pl := range FlattenSubPaths(r, 0.001) {
+		for _, p := range pl.Points {
+			tip = math.Max(tip, p.X)
+		}
+	}
+	assert.True(tip > 100+10)
+}
+
+func TestOffsetKeepsArcs(t *testing.T) {
+	assert := assert.New(t)
+
+	c := NewCircle(geom.Coord{X: 5, Y: 5}, 10)
+	p := OffsetShape(c, 2, OffsetOptions{Tolerance: 0.001})
+	assert.Equal(0, countCommands(p.SubPaths, 'L'))
+	assert.True(countCommands(p.SubPaths, 'A') >= 2)
+	assert.InDelta(144*math.Pi, subPathsArea(p.SubPaths), 0.1)
+	for _, pl := range p.Flatten(0.001) {
+		for _, pt := range pl.Points {
+			assert.InDelta(12, coordDist(pt, c.Center), 1e-6)
+		}
+	}
+
+	p = OffsetShape(c, -3, OffsetOptions{Tolerance: 0.001})
+	assert.InDelta(49*math.Pi, subPathsArea(p.SubPaths), 0.1)
+
+	// A slot made of two half circles and two lines stays a slot.
+	slot := mustParsePath(t, "M0,0 h10 A5,5 0 0 1 10,10 h-10 A5,5 0 0 1 0,0 z")
+	r := OffsetSubPaths(slot, 1, "nonzero", OffsetOptions{Tolerance: 0.001})
+	assert.Equal(2, countCommands(r, 'A'))
+	assert.Equal(2, countCommands(r, 'L'))
+	assert.InDelta(12*10+36*math.Pi, subPathsArea(r), 0.1)
+}
+
+func TestOffsetHoles(t *testing.T) {
+	assert := assert.New(t)
+
+	// A square with a hole wound the same way, relying on evenodd.
+	sps := mustParsePath(t, "M0,0 h10 v10 h-10 z M3,3 h4 v4 h-4 z")
+
+	r := OffsetSubPaths(sps, 1, "evenodd", OffsetOptions{Tolerance: 0.001})
+	assert.Len(r, 2)
+	assert.InDelta(144-4, subPathsArea(r), 1e-9)
+	assert.False(FillContains(r, geom.Coord{X: 5, Y: 5}, "nonzero"))
+
+	// Growing enough fills the hole in.
+	r = OffsetSubPaths(sps, 3, "evenodd", OffsetOptions{Tolerance: 0.001})
+	assert.Len(r, 1)
+	assert.InDelta(256, subPathsArea(r), 1e-9)
+}
+
+func TestOffsetConcave(t *testing.T) {
+	assert := assert.New(t)
+
+	// An L shape has a concave corner that overlaps itself when grown.
+	l := NewPolygon([]geom.Coord{{X: 0, Y: 0}, {X: 10, Y: 0}, {X: 10, Y: 4}, {X: 4, Y: 4}, {X: 4, Y: 10}, {X: 0, Y: 10}})
+	p := OffsetShape(l, 1, OffsetOptions{Tolerance: 0.001})
+	assert.Len(p.SubPaths, 1)
+	assert.Equal(5, countCommands(p.SubPaths, 'L'))
+	assert.InDelta(12*6+6*6, subPathsArea(p.SubPaths), 1e-9)
+
+	p = OffsetShape(l, -1, OffsetOptions{Tolerance: 0.001})
+	assert.InDelta(8*2+2*6, subPathsArea(p.SubPaths), 1e-9)
+}
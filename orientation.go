@@ -0,0 +1,207 @@
+// Copyright 2018 Joe Beda
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package svgdata
+
+import (
+	"math"
+	"sort"
+
+	"github.com/jbeda/geom"
+)
+
+// SignedArea returns the area enclosed by the subpath as if it were closed.
+// The area is positive when the subpath runs clockwise as displayed, where the
+// y axis points down.  Curves and arcs are measured exactly.
+func (sp SubPath) SignedArea() float64 {
+	a := 0.0
+	segs := subPathSegments(sp)
+	for _, s := range segs {
+		a += s.areaTerm()
+	}
+	if len(segs) > 0 {
+		// Close the subpath.
+		a += coordCross(segs[len(segs)-1].end, segs[0].start) / 2
+	}
+	return a
+}
+
+// IsClockwise returns true if the subpath runs clockwise as displayed.
+func (sp SubPath) IsClockwise() bool {
+	return sp.SignedArea() > 0
+}
+
+// areaTerm returns the contribution of the segment to the signed area of a
+// closed path, the integral of (x dy - y dx) / 2 along it.
+func (s segment) areaTerm() float64 {
+	switch s.kind {
+	case 'L':
+		return coordCross(s.start, s.end) / 2
+	case 'A':
+		// With p = c + e(θ) the integrand is c × e' + e × e' and e × e' is
+		// constant for an ellipse.
+		ea := s.ellipse()
+		e1 := coordSub(ea.point(ea.theta), ea.center)
+		e2 := coordSub(ea.point(ea.theta+ea.delta), ea.center)
+		return (coordCross(ea.center, coordSub(e2, e1)) + ea.rx*ea.ry*ea.delta) / 2
+	}
+
+	// The integrand is a polynomial of low enough degree that Gauss-Legendre
+	// quadrature is exact.
+	sum := 0.0
+	for i, x := range glNodes {
+		t := 0.5 + 0.5*x
+		sum += glWeights[i] * coordCross(s.point(t), s.derivative(t))
+	}
+	return sum / 4
+}
+
+// Reverse returns the subpath traversed in the opposite direction.  Curves
+// and arcs are kept.
+func (sp SubPath) Reverse() SubPath {
+	segs := subPathSegments(sp)
+	closed := sp.IsClosed()
+	if len(segs) == 0 {
+		return sp
+	}
+
+	var rsegs []segment
+	for i := len(segs) - 1; i >= 0; i-- {
+		rsegs = append(rsegs, segs[i].reverse())
+	}
+	return BuildSubPaths(segmentsToCommands(rsegs, closed))[0]
+}
+
+// Contour is a closed subpath placed in a hierarchy by containment.  Outer
+// contours are at depth 0, holes in them at depth 1, islands in those holes at
+// depth 2 and so on.
+type Contour struct {
+	SubPath  SubPath
+	Index    int // Index of the subpath in the original list
+	Area     float64
+	Depth    int
+	Parent   *Contour
+	Children []*Contour
+}
+
+// IsHole returns true if the contour is a hole in its parent.
+func (c *Contour) IsHole() bool {
+	return c.Depth%2 == 1
+}
+
+// ContourTree nests the closed subpaths in sps by containment and returns the
+// outermost contours.  Open subpaths are ignored.  Containment is decided on
+// the subpaths flattened to within tol.
+func ContourTree(sps []SubPath, tol float64) []*Contour {
+	var cs []*Contour
+	var pls []Polyline
+	for i, sp := range sps {
+		if !sp.IsClosed() {
+			continue
+		}
+		cs = append(cs, &Contour{SubPath: sp, Index: i, Area: sp.SignedArea()})
+		pls = append(pls, sp.Flatten(tol))
+	}
+
+	// Sorting by size means any container of a contour comes before it.
+	order := make([]int, len(cs))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		return math.Abs(cs[order[a]].Area) > math.Abs(cs[order[b]].Area)
+	})
+
+	var roots []*Contour
+	for oi, i := range order {
+		c := cs[i]
+		for pi := oi - 1; pi >= 0; pi-- {
+			p := order[pi]
+			if polylineInside(pls[i], pls[p], tol) {
+				c.Parent = cs[p]
+				break
+			}
+		}
+		if c.Parent == nil {
+			roots = append(roots, c)
+			continue
+		}
+		c.Depth = c.Parent.Depth + 1
+		c.Parent.Children = append(c.Parent.Children, c)
+	}
+
+	// Present the tree in the original order of the subpaths.
+	byIndex := func(l []*Contour) {
+		sort.Slice(l, func(a, b int) bool { return l[a].Index < l[b].Index })
+	}
+	byIndex(roots)
+	for _, c := range cs {
+		byIndex(c.Children)
+	}
+	return roots
+}
+
+// polylineInside returns true if inner lies within outer.  The first point of
+// inner that is clear of the edge of outer decides.
+func polylineInside(inner, outer Polyline, tol float64) bool {
+	for _, p := range inner.Points {
+		if polylineDistTo(outer, p) <= tol {
+			continue
+		}
+		return outer.windingNumber(p) != 0
+	}
+	return false
+}
+
+// polylineDistTo returns the distance from p to the closest edge of pl.
+func polylineDistTo(pl Polyline, p geom.Coord) float64 {
+	d := math.Inf(1)
+	n := len(pl.Points)
+	for i := 0; i < n; i++ {
+		if i == n-1 && !pl.Closed {
+			break
+		}
+		sd, _ := pointSegmentDist(p, pl.Points[i], pl.Points[(i+1)%n])
+		d = math.Min(d, sd)
+	}
+	if n == 1 {
+		d = coordDist(p, pl.Points[0])
+	}
+	return d
+}
+
+// NormalizeOrientation returns sps with every outer contour, and every island
+// within a hole, running clockwise as displayed and every hole running
+// counter-clockwise.  Open subpaths are left alone and the order of the
+// subpaths is kept.
+func NormalizeOrientation(sps []SubPath, tol float64) []SubPath {
+	r := append([]SubPath{}, sps...)
+	var visit func(cs []*Contour)
+	visit = func(cs []*Contour) {
+		for _, c := range cs {
+			if c.IsHole() == (c.Area > 0) {
+				r[c.Index] = c.SubPath.Reverse()
+			}
+			visit(c.Children)
+		}
+	}
+	visit(ContourTree(sps, tol))
+	return r
+}
+
+// NormalizeOrientation orients the subpaths of the path as described by the
+// NormalizeOrientation function.
+func (p *Path) NormalizeOrientation(tol float64) {
+	p.SubPaths = NormalizeOrientation(p.SubPaths, tol)
+}
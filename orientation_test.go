@@ -0,0 +1,109 @@
+// Copyright 2018 Joe Beda
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package svgdata
+
+import (
+	"math"
+	"testing"
+
+	"github.com/jbeda/geom"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSignedArea(t *testing.T) {
+	assert := assert.New(t)
+
+	sps := mustParsePath(t, "M0,0 h10 v10 h-10 z M0,0 v10 h10 v-10")
+	assert.InDelta(100, sps[0].SignedArea(), 1e-9)
+	assert.True(sps[0].IsClockwise())
+	assert.InDelta(-100, sps[1].SignedArea(), 1e-9)
+	assert.False(sps[1].IsClockwise())
+
+	c := NewCircle(geom.Coord{X: 3, Y: 4}, 5)
+	assert.InDelta(25*math.Pi, c.ToSubPaths()[0].SignedArea(), 1e-9)
+
+	// A rotated ellipse.
+	sps = mustParsePath(t, "M0,10 A10,5 90 0 1 0,-10 A10,5 90 0 1 0,10 z")
+	assert.InDelta(50*math.Pi, sps[0].SignedArea(), 1e-9)
+
+	// Curves are exact.
+	sps = mustParsePath(t, "M0,0 C0,50 100,50 100,0 Q50,-50 0,0 z")
+	assert.InDelta(polylineArea(sps[0].Flatten(1e-6).Points), sps[0].SignedArea(), 1e-4)
+}
+
+func TestReverse(t *testing.T) {
+	assert := assert.New(t)
+
+	sps := mustParsePath(t, "M0,0 C0,50 100,50 100,0 A50,50 0 0 1 0,0 z")
+	r := sps[0].Reverse()
+	assert.InDelta(-sps[0].SignedArea(), r.SignedArea(), 1e-9)
+	assert.Equal(sps[0].Start(), r.Start())
+	assert.True(r.IsClosed())
+	assert.Equal("M0 0A50 50 0 0 0 100 0C100 50 0 50 0 0Z", SavePathString([]SubPath{r}))
+
+	sps = mustParsePath(t, "M1,2 l3,0 q1,1 2,2")
+	r = sps[0].Reverse()
+	assert.False(r.IsClosed())
+	assert.Equal(geom.Coord{X: 6, Y: 4}, r.Start())
+	assert.Equal(geom.Coord{X: 1, Y: 2}, r.End())
+	assert.InDelta(sps[0].Length(), r.Length(), 1e-9)
+}
+
+func TestContourTree(t *testing.T) {
+	assert := assert.New(t)
+
+	sps := mustParsePath(t, `
+		M0,0 h100 v100 h-100 z
+		M10,10 h30 v30 h-30 z
+		M15,15 h10 v10 h-10 z
+		M60,60 v20 h20 v-20 z
+		M200,0 h10 v10 h-10 z
+		M0,0 L5,5`)
+
+	roots := ContourTree(sps, 0.01)
+	assert.Len(roots, 2)
+	assert.Equal(0, roots[0].Index)
+	assert.Equal(4, roots[1].Index)
+	assert.False(roots[0].IsHole())
+
+	holes := roots[0].Children
+	assert.Len(holes, 2)
+	assert.Equal(1, holes[0].Index)
+	assert.Equal(3, holes[1].Index)
+	assert.True(holes[0].IsHole())
+	assert.Equal(roots[0], holes[0].Parent)
+
+	assert.Len(holes[0].Children, 1)
+	island := holes[0].Children[0]
+	assert.Equal(2, island.Index)
+	assert.Equal(2, island.Depth)
+	assert.False(island.IsHole())
+
+	// Outers and islands clockwise, holes counter-clockwise.
+	p := NewPath()
+	p.SubPaths = sps
+	p.NormalizeOrientation(0.01)
+	assert.True(p.SubPaths[0].IsClockwise())
+	assert.False(p.SubPaths[1].IsClockwise())
+	assert.True(p.SubPaths[2].IsClockwise())
+	assert.False(p.SubPaths[3].IsClockwise())
+	assert.True(p.SubPaths[4].IsClockwise())
+	assert.Equal(sps[5], p.SubPaths[5])
+
+	// Once normalized, nonzero and evenodd fill the same way.
+	for _, pt := range []geom.Coord{{X: 5, Y: 5}, {X: 12, Y: 12}, {X: 20, Y: 20}, {X: 70, Y: 70}} {
+		assert.Equal(FillContains(sps[:5], pt, "evenodd"), FillContains(p.SubPaths[:5], pt, "nonzero"))
+	}
+}
@@ -20,6 +20,7 @@ import (
 	"strconv"
 	"strings"
 
+	"github.com/jbeda/geom"
 	"github.com/pkg/errors"
 )
 
@@ -41,6 +42,8 @@ type PathCommand struct {
 	endX, endY     float64   // The point that the command ends on
 }
 
+var _ Shape = (*Path)(nil)
+
 func init() {
 	RegisterNodeCreator("path", func() Node { return NewPath() })
 }
@@ -53,6 +56,40 @@ func NewPath() *Path {
 	return p
 }
 
+// ToSubPaths returns the SubPaths of the path.
+func (p *Path) ToSubPaths() []SubPath {
+	return p.SubPaths
+}
+
+// Start returns the point where the subpath starts.
+func (sp SubPath) Start() geom.Coord {
+	return geom.Coord{X: sp.startX, Y: sp.startY}
+}
+
+// End returns the point where the subpath ends.
+func (sp SubPath) End() geom.Coord {
+	return geom.Coord{X: sp.endX, Y: sp.endY}
+}
+
+// IsClosed returns true if the subpath ends with a close path command.
+func (sp SubPath) IsClosed() bool {
+	if len(sp.Commands) == 0 {
+		return false
+	}
+	c := sp.Commands[len(sp.Commands)-1].Command
+	return c == 'z' || c == 'Z'
+}
+
+// Start returns the current point before the command is applied.
+func (c PathCommand) Start() geom.Coord {
+	return geom.Coord{X: c.startX, Y: c.startY}
+}
+
+// End returns the current point after the command is applied.
+func (c PathCommand) End() geom.Coord {
+	return geom.Coord{X: c.endX, Y: c.endY}
+}
+
 func (p *Path) marshalAttrs(am AttrMap) {
 	am["d"] = SavePathString(p.SubPaths)
 }
@@ -127,12 +164,24 @@ func SavePathString(sps []SubPath) string {
 	return buf.String()
 }
 
+// BuildSubPaths groups a list of commands into SubPaths and fills in the start
+// and end points of each command.  This is the way to construct paths in code
+// without going through a path string.
+func BuildSubPaths(cmds []PathCommand) []SubPath {
+	pp := pathParser{}
+	return pp.build(cmds)
+}
+
 func (pp *pathParser) parse(d string) ([]SubPath, error) {
 	cmds, err := parsePathCommands(d)
 	if err != nil {
 		return nil, err
 	}
 
+	return pp.build(cmds), nil
+}
+
+func (pp *pathParser) build(cmds []PathCommand) []SubPath {
 	for _, c := range cmds {
 		// If we see a start path or don't have a current subpath then we start a
 		// new subpath
@@ -157,7 +206,7 @@ func (pp *pathParser) parse(d string) ([]SubPath, error) {
 		}
 	}
 
-	return pp.subPaths, nil
+	return pp.subPaths
 }
 
 // updatePositions updates any begin/end positions on the command and subpath
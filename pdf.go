@@ -0,0 +1,308 @@
+// Copyright 2018 Joe Beda
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package svgdata
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/jbeda/geom"
+)
+
+// PDFOptions control how a document is converted to PDF.
+type PDFOptions struct {
+	// Compress writes content streams with Flate compression.
+	Compress bool
+}
+
+// pdfPtPerPx is the size of a CSS pixel in PDF points.
+const pdfPtPerPx = ptPerInch / dpi
+
+// MarshalPDF converts the document to a single page PDF.  The page is the
+// physical size of the document's viewport and shapes are written as vector
+// paths with their fills, strokes, solid colors and opacity.  Arcs are
+// approximated with cubic Béziers.  Gradients, patterns, clipping, masks,
+// text and images are not written.
+func MarshalPDF(r *Root, opts PDFOptions) ([]byte, error) {
+	size, vt, err := viewportTransform(r)
+	if err != nil {
+		return nil, err
+	}
+
+	w := &pdfWriter{opts: opts, size: size, gstates: map[[2]float64]string{}}
+	// The catalog, page tree, page and resources come first so that the
+	// objects written while drawing can refer to the resources.
+	for i := 0; i < 4; i++ {
+		w.add(nil)
+	}
+	var content bytes.Buffer
+	fmt.Fprintf(&content, "%s 0 0 %s 0 %s cm\n",
+		pdfNumber(pdfPtPerPx), pdfNumber(-pdfPtPerPx), pdfNumber(size.Y*pdfPtPerPx))
+	if err := w.children(&content, r, vt, NodeStyle(r)); err != nil {
+		return nil, err
+	}
+	contentRef := w.stream("", content.Bytes())
+
+	w.set(1, "<< /Type /Catalog /Pages 2 0 R >>")
+	w.set(2, "<< /Type /Pages /Kids [3 0 R] /Count 1 >>")
+	w.set(3, fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %s %s] /Resources 4 0 R /Contents %d 0 R >>",
+		pdfNumber(size.X*pdfPtPerPx), pdfNumber(size.Y*pdfPtPerPx), contentRef))
+	res := "<< /ProcSet [/PDF]"
+	if len(w.extGState) > 0 {
+		res += " /ExtGState << " + strings.Join(w.extGState, " ") + " >>"
+	}
+	if len(w.xObject) > 0 {
+		res += " /XObject << " + strings.Join(w.xObject, " ") + " >>"
+	}
+	w.set(4, res+" >>")
+	return w.bytes(), nil
+}
+
+// pdfWriter collects the objects of a PDF file.
+type pdfWriter struct {
+	opts    PDFOptions
+	size    geom.Coord
+	objects [][]byte // Object n is objects[n-1]
+
+	gstates   map[[2]float64]string // Fill and stroke opacity to name
+	extGState []string              // Entries of the ExtGState resource
+	xObject   []string              // Entries of the XObject resource
+}
+
+// add appends an object and returns its number.
+func (w *pdfWriter) add(body []byte) int {
+	w.objects = append(w.objects, body)
+	return len(w.objects)
+}
+
+func (w *pdfWriter) set(n int, body string) {
+	w.objects[n-1] = []byte(body)
+}
+
+// stream adds a stream object with the extra dictionary entries in dict.
+func (w *pdfWriter) stream(dict string, data []byte) int {
+	if w.opts.Compress {
+		var buf bytes.Buffer
+		zw := zlib.NewWriter(&buf)
+		zw.Write(data)
+		zw.Close()
+		data = buf.Bytes()
+		dict += " /Filter /FlateDecode"
+	}
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "<<%s /Length %d >>\nstream\n", dict, len(data))
+	b.Write(data)
+	b.WriteString("\nendstream")
+	return w.add(b.Bytes())
+}
+
+// bytes returns the finished file with its cross-reference table.
+func (w *pdfWriter) bytes() []byte {
+	var b bytes.Buffer
+	// The comment with high bytes marks the file as binary.
+	b.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+	offsets := make([]int, len(w.objects))
+	for i, o := range w.objects {
+		offsets[i] = b.Len()
+		fmt.Fprintf(&b, "%d 0 obj\n%s\nendobj\n", i+1, o)
+	}
+	xref := b.Len()
+	fmt.Fprintf(&b, "xref\n0 %d\n0000000000 65535 f \n", len(w.objects)+1)
+	for _, o := range offsets {
+		fmt.Fprintf(&b, "%010d 00000 n \n", o)
+	}
+	fmt.Fprintf(&b, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(w.objects)+1, xref)
+	return b.Bytes()
+}
+
+// gstate returns the name of a graphics state with the given fill and stroke
+// opacity.
+func (w *pdfWriter) gstate(fill, stroke float64) string {
+	k := [2]float64{fill, stroke}
+	if name, ok := w.gstates[k]; ok {
+		return name
+	}
+	name := fmt.Sprintf("GS%d", len(w.gstates)+1)
+	w.gstates[k] = name
+	n := w.add([]byte(fmt.Sprintf("<< /Type /ExtGState /ca %s /CA %s >>", pdfNumber(fill), pdfNumber(stroke))))
+	w.extGState = append(w.extGState, fmt.Sprintf("/%s %d 0 R", name, n))
+	return name
+}
+
+// children writes the children of n to b.  Nodes with opacity are written as
+// transparency groups.
+func (w *pdfWriter) children(b *bytes.Buffer, n Node, ctm Transform, st Style) error {
+	return walkGroups(n, st, func(c Node, t Transform, cst Style, opacity float64) error {
+		cctm := ctm.Multiply(t)
+		target := b
+		if opacity < 1 {
+			target = &bytes.Buffer{}
+		}
+		if s, ok := c.(Shape); ok && cst.Get("visibility", "visible") == "visible" {
+			if err := w.shape(target, s, cctm, cst); err != nil {
+				return err
+			}
+		}
+		if err := w.children(target, c, cctm, cst); err != nil {
+			return err
+		}
+		if opacity < 1 && target.Len() > 0 {
+			ref := w.stream(fmt.Sprintf(" /Type /XObject /Subtype /Form /BBox [0 0 %s %s] /Group << /S /Transparency >> /Resources 4 0 R",
+				pdfNumber(w.size.X), pdfNumber(w.size.Y)), target.Bytes())
+			name := fmt.Sprintf("X%d", len(w.xObject)+1)
+			w.xObject = append(w.xObject, fmt.Sprintf("/%s %d 0 R", name, ref))
+			fmt.Fprintf(b, "q /%s gs /%s Do Q\n", w.gstate(opacity, opacity), name)
+		}
+		return nil
+	})
+}
+
+// shape writes the fill and stroke of s.  The path is written in the user
+// space of s so that strokes are transformed the same way as in SVG.
+func (w *pdfWriter) shape(b *bytes.Buffer, s Shape, ctm Transform, st Style) error {
+	fill, hasFill := parseColor(st.Get("fill", "black"))
+	stroke, hasStroke := parseColor(st.Get("stroke", "none"))
+	hasStroke = hasStroke && st.StrokeWidth() > 0
+	if !hasFill && !hasStroke {
+		return nil
+	}
+	path := pdfPath(s.ToSubPaths())
+	if path == "" {
+		return nil
+	}
+
+	fmt.Fprintf(b, "q %s cm\n", pdfTransform(ctm))
+	fillOpacity, strokeOpacity := 1.0, 1.0
+	if hasFill {
+		fillOpacity = styleOpacity(st, "fill-opacity")
+		fmt.Fprintf(b, "%s rg\n", pdfColor(fill.R, fill.G, fill.B))
+	}
+	if hasStroke {
+		strokeOpacity = styleOpacity(st, "stroke-opacity")
+		fmt.Fprintf(b, "%s RG %s w %d J %d j %s M\n", pdfColor(stroke.R, stroke.G, stroke.B),
+			pdfNumber(st.StrokeWidth()), pdfLineCaps[st.LineCap()], pdfLineJoins[st.LineJoin()],
+			pdfNumber(st.MiterLimit()))
+		dashes, offset := dashPattern(s, st)
+		if dashes != nil {
+			ds := make([]string, len(dashes))
+			for i, d := range dashes {
+				ds[i] = pdfNumber(d)
+			}
+			fmt.Fprintf(b, "[%s] %s d\n", strings.Join(ds, " "), pdfNumber(offset))
+		}
+	}
+	if fillOpacity < 1 || strokeOpacity < 1 {
+		fmt.Fprintf(b, "/%s gs\n", w.gstate(fillOpacity, strokeOpacity))
+	}
+	b.WriteString(path)
+
+	op := "S"
+	if hasFill {
+		op = "f"
+		if hasStroke {
+			op = "B"
+		}
+		if st.FillRule() == "evenodd" {
+			op += "*"
+		}
+	}
+	b.WriteString(op + "\nQ\n")
+	return nil
+}
+
+var pdfLineCaps = map[LineCap]int{CapButt: 0, CapRound: 1, CapSquare: 2}
+
+var pdfLineJoins = map[JoinStyle]int{JoinMiter: 0, JoinRound: 1, JoinBevel: 2}
+
+// pdfPath returns the path construction operators for sps.  Quadratic
+// curves and arcs are converted to cubics.
+func pdfPath(sps []SubPath) string {
+	var b strings.Builder
+	for _, sp := range sps {
+		segs, closed := closePathSegments(sp)
+		if len(segs) == 0 {
+			continue
+		}
+		fmt.Fprintf(&b, "%s m\n", pdfCoords(segs[0].start))
+		for _, s := range segs {
+			switch s.kind {
+			case 'L':
+				fmt.Fprintf(&b, "%s l\n", pdfCoords(s.end))
+			case 'Q':
+				c := s.quad().Elevate()
+				fmt.Fprintf(&b, "%s c\n", pdfCoords(c.P1, c.P2, c.P3))
+			case 'C':
+				fmt.Fprintf(&b, "%s c\n", pdfCoords(s.ctrl1, s.ctrl2, s.end))
+			case 'A':
+				cs := s.ellipse().cubics()
+				// Make the last point exact so that the path joins up.
+				cs[len(cs)-1].P3 = s.end
+				for _, c := range cs {
+					fmt.Fprintf(&b, "%s c\n", pdfCoords(c.P1, c.P2, c.P3))
+				}
+			}
+		}
+		if closed {
+			b.WriteString("h\n")
+		}
+	}
+	return b.String()
+}
+
+// closePathSegments returns the segments of sp and whether it is closed.
+// The line added by Z to close the subpath is left out since the close path
+// operators of PDF and PostScript draw it.
+func closePathSegments(sp SubPath) ([]segment, bool) {
+	segs := subPathSegments(sp)
+	closed := sp.IsClosed()
+	if n := len(segs); closed && n > 1 && segs[n-1].kind == 'L' && segs[n-1].end == segs[0].start {
+		segs = segs[:n-1]
+	}
+	return segs, closed
+}
+
+// pdfNumber formats f with up to 6 decimal places.
+func pdfNumber(f float64) string {
+	s := strconv.FormatFloat(f, 'f', 6, 64)
+	s = strings.TrimRight(strings.TrimRight(s, "0"), ".")
+	if s == "-0" {
+		return "0"
+	}
+	return s
+}
+
+func pdfCoords(cs ...geom.Coord) string {
+	var parts []string
+	for _, c := range cs {
+		parts = append(parts, pdfNumber(c.X), pdfNumber(c.Y))
+	}
+	return strings.Join(parts, " ")
+}
+
+func pdfTransform(t Transform) string {
+	return strings.Join([]string{
+		pdfNumber(t.A), pdfNumber(t.B), pdfNumber(t.C),
+		pdfNumber(t.D), pdfNumber(t.E), pdfNumber(t.F),
+	}, " ")
+}
+
+func pdfColor(r, g, b uint8) string {
+	return fmt.Sprintf("%s %s %s", pdfNumber(math.Round(float64(r)/255*1000)/1000),
+		pdfNumber(math.Round(float64(g)/255*1000)/1000), pdfNumber(math.Round(float64(b)/255*1000)/1000))
+}
@@ -0,0 +1,157 @@
+// Copyright 2018 Joe Beda
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package svgdata
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// pdfObjects checks the cross-reference table of a PDF and returns its
+// objects by number.
+func pdfObjects(t *testing.T, data []byte) map[int]string {
+	assert := assert.New(t)
+	assert.True(bytes.HasPrefix(data, []byte("%PDF-1.4\n")))
+
+	m := regexp.MustCompile(`startxref\n(\d+)\n%%EOF\n$`).FindSubmatch(data)
+	if !assert.NotNil(m) {
+		t.FailNow()
+	}
+	xref, _ := strconv.Atoi(string(m[1]))
+	lines := strings.Split(string(data[xref:]), "\n")
+	assert.Equal("xref", lines[0])
+	var count int
+	fmt.Sscanf(lines[1], "0 %d", &count)
+
+	objs := map[int]string{}
+	for i := 1; i < count; i++ {
+		off, _ := strconv.Atoi(lines[2+i][:10])
+		obj := string(data[off:])
+		prefix := fmt.Sprintf("%d 0 obj\n", i)
+		if assert.True(strings.HasPrefix(obj, prefix), "object %d", i) {
+			objs[i] = obj[len(prefix):strings.Index(obj, "\nendobj\n")]
+		}
+	}
+	return objs
+}
+
+// pdfStream returns the data in a stream object.
+func pdfStream(t *testing.T, obj string) string {
+	i := strings.Index(obj, "stream\n")
+	data := obj[i+len("stream\n") : len(obj)-len("\nendstream")]
+	if strings.Contains(obj[:i], "/FlateDecode") {
+		zr, err := zlib.NewReader(strings.NewReader(data))
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, err := io.ReadAll(zr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(b)
+	}
+	return data
+}
+
+func TestMarshalPDF(t *testing.T) {
+	assert := assert.New(t)
+
+	r := mustUnmarshal(t, `<svg xmlns="http://www.w3.org/2000/svg" width="210mm" height="297mm" viewBox="0 0 210 297">
+  <rect x="10" y="20" width="30" height="40" fill="#ff0000" stroke="blue" stroke-width="2" stroke-linejoin="round"/>
+  <path d="M0 0H10V10H0Z M2 2H8V8H2Z" fill-rule="evenodd" fill-opacity="0.5"/>
+  <path d="M50 50 Q60 40 70 50 A10 10 0 0 1 90 50" fill="none" stroke="black" stroke-dasharray="4 2"/>
+</svg>`)
+	data, err := MarshalPDF(r, PDFOptions{})
+	if !assert.NoError(err) {
+		return
+	}
+	objs := pdfObjects(t, data)
+	assert.Equal("<< /Type /Catalog /Pages 2 0 R >>", objs[1])
+	assert.Contains(objs[3], "/MediaBox [0 0 595.275591 841.889764] /Resources 4 0 R /Contents 6 0 R")
+
+	content := pdfStream(t, objs[6])
+	assert.Equal(`0.75 0 0 -0.75 0 841.889764 cm
+q 3.779528 0 0 3.779528 0 0 cm
+1 0 0 rg
+0 0 1 RG 2 w 0 J 1 j 4 M
+10 20 m
+40 20 l
+40 60 l
+10 60 l
+h
+B
+Q
+q 3.779528 0 0 3.779528 0 0 cm
+0 0 0 rg
+/GS1 gs
+0 0 m
+10 0 l
+10 10 l
+0 10 l
+h
+2 2 m
+8 2 l
+8 8 l
+2 8 l
+h
+f*
+Q
+q 3.779528 0 0 3.779528 0 0 cm
+0 0 0 RG 1 w 0 J 0 j 4 M
+[4 2] 0 d
+50 50 m
+56.666667 43.333333 63.333333 43.333333 70 50 c
+70 44.477153 74.477153 40 80 40 c
+85.522847 40 90 44.477153 90 50 c
+S
+Q
+`, content)
+	assert.Equal("<< /Type /ExtGState /ca 0.5 /CA 1 >>", objs[5])
+	assert.Contains(objs[4], "/ExtGState << /GS1 5 0 R >>")
+}
+
+func TestMarshalPDFGroupOpacity(t *testing.T) {
+	assert := assert.New(t)
+
+	r := mustUnmarshal(t, `<svg xmlns="http://www.w3.org/2000/svg" width="100" height="50">
+  <g opacity="0.25">
+    <circle cx="20" cy="20" r="10"/>
+  </g>
+  <circle cx="60" cy="20" r="10" display="none"/>
+</svg>`)
+	data, err := MarshalPDF(r, PDFOptions{Compress: true})
+	if !assert.NoError(err) {
+		return
+	}
+	objs := pdfObjects(t, data)
+	assert.Contains(objs[3], "/MediaBox [0 0 75 37.5]")
+	assert.Contains(objs[4], "/ExtGState << /GS1 6 0 R >>")
+	assert.Contains(objs[4], "/XObject << /X1 5 0 R >>")
+	assert.Contains(objs[5], "/Subtype /Form /BBox [0 0 100 50] /Group << /S /Transparency >>")
+	assert.Equal("<< /Type /ExtGState /ca 0.25 /CA 0.25 >>", objs[6])
+	assert.Equal("0.75 0 0 -0.75 0 37.5 cm\nq /GS1 gs /X1 Do Q\n", pdfStream(t, objs[7]))
+
+	group := pdfStream(t, objs[5])
+	assert.Equal(4, strings.Count(group, " c\n"))
+	assert.Contains(group, "\nf\nQ\n")
+}
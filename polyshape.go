@@ -14,17 +14,23 @@
 
 package svgdata
 
-import "github.com/jbeda/geom"
+import (
+	"bytes"
+
+	"github.com/jbeda/geom"
+	"github.com/pkg/errors"
+)
 
 // Polyshape is an SVG element is a shape specified with a list of straight
-// lines.
+// lines.  A polygon is closed while a polyline is left open.
 type Polyshape struct {
 	nodeImpl
 
-	// TODO: Handle Points
 	Points []geom.Coord
 }
 
+var _ Shape = (*Polyshape)(nil)
+
 func init() {
 	RegisterNodeCreator("polygon", CreatePolygon)
 	RegisterNodeCreator("polyline", CreatePolyline)
@@ -41,16 +47,78 @@ func CreatePolygon() Node {
 
 func CreatePolyline() Node {
 	p := &Polyshape{}
-	p.nodeImpl.name = "polygon"
+	p.nodeImpl.name = "polyline"
 	p.nodeImpl.onMarshalAttrs = p.marshalAttrs
 	p.nodeImpl.onUnmarshalAttrs = p.unmarshalAttrs
 
 	return p
 }
 
+// NewPolygon creates a closed polyshape with the given points.
+func NewPolygon(pts []geom.Coord) *Polyshape {
+	p := CreatePolygon().(*Polyshape)
+	p.Points = pts
+	return p
+}
+
+// NewPolyline creates an open polyshape with the given points.
+func NewPolyline(pts []geom.Coord) *Polyshape {
+	p := CreatePolyline().(*Polyshape)
+	p.Points = pts
+	return p
+}
+
+// IsClosed returns true if this is a polygon rather than a polyline.
+func (p *Polyshape) IsClosed() bool {
+	return p.name == "polygon"
+}
+
+// ToSubPaths returns the points as a single subpath.
+func (p *Polyshape) ToSubPaths() []SubPath {
+	if len(p.Points) == 0 {
+		return nil
+	}
+	pl := Polyline{Points: p.Points, Closed: p.IsClosed()}
+	return []SubPath{pl.ToSubPath()}
+}
+
 func (p *Polyshape) marshalAttrs(am AttrMap) {
+	var buf bytes.Buffer
+	for i, pt := range p.Points {
+		if i != 0 {
+			buf.WriteByte(' ')
+		}
+		buf.WriteString(floatToString(pt.X))
+		buf.WriteByte(',')
+		buf.WriteString(floatToString(pt.Y))
+	}
+	am["points"] = buf.String()
 }
 
 func (p *Polyshape) unmarshalAttrs(am AttrMap) error {
+	pts, err := parsePoints(am["points"])
+	if err != nil {
+		return err
+	}
+	delete(am, "points")
+
+	p.Points = pts
 	return nil
 }
+
+// parsePoints parses the points attribute of a polygon or polyline.
+func parsePoints(s string) ([]geom.Coord, error) {
+	nums, err := parseNumberList(s)
+	if err != nil {
+		return nil, err
+	}
+	if len(nums)%2 != 0 {
+		return nil, errors.Errorf("Odd number of values in points: %s", s)
+	}
+
+	var r []geom.Coord
+	for i := 0; i < len(nums); i += 2 {
+		r = append(r, geom.Coord{X: nums[i], Y: nums[i+1]})
+	}
+	return r, nil
+}
@@ -17,6 +17,7 @@ package svgdata
 import (
 	"testing"
 
+	"github.com/jbeda/geom"
 	"github.com/sanity-io/litter"
 	"github.com/stretchr/testify/assert"
 )
@@ -54,3 +55,23 @@ func TestLoadSavePolyline(t *testing.T) {
 
 	assert.Equal(l.Sdump(r0), l.Sdump(r1))
 }
+
+func TestPolyshapePoints(t *testing.T) {
+	assert := assert.New(t)
+
+	data0 := []byte(`<svg xmlns="http://www.w3.org/2000/svg"><polyline points="850,75  958,137.5 -1e2 3" ></polyline></svg>`)
+	r0, err := Unmarshal(data0)
+	assert.NoError(err)
+
+	p := (*r0.Children())[0].(*Polyshape)
+	assert.Equal("polyline", p.Name())
+	assert.False(p.IsClosed())
+	assert.Equal([]geom.Coord{{X: 850, Y: 75}, {X: 958, Y: 137.5}, {X: -100, Y: 3}}, p.Points)
+
+	data1, err := Marshal(r0, false)
+	assert.NoError(err)
+	assert.Contains(string(data1), `points="850,75 958,137.5 -100,3"`)
+
+	_, err = Unmarshal([]byte(`<svg xmlns="http://www.w3.org/2000/svg"><polygon points="1,2 3" ></polygon></svg>`))
+	assert.Error(err)
+}
@@ -14,14 +14,25 @@
 
 package svgdata
 
-import "github.com/jbeda/geom"
+import (
+	"math"
+
+	"github.com/jbeda/geom"
+)
 
 // Circle is an SVG element that has no specialized code or representation.
 type Rect struct {
 	nodeImpl
 	R geom.Rect
+
+	// Rx and Ry are the radii of the rounded corners.  They are clamped to
+	// half the width and height when drawing and the corners are square if
+	// either is zero.
+	Rx, Ry float64
 }
 
+var _ Shape = (*Rect)(nil)
+
 func init() {
 	RegisterNodeCreator("rect", func() Node { return createRect() })
 }
@@ -47,11 +58,56 @@ func NewRectXYWH(x, y, w, h float64) *Rect {
 	})
 }
 
+// ToSubPaths returns the rectangle as a closed subpath starting at the minimum
+// corner.  Rounded rectangles start at the end of the top left corner and
+// their corners are elliptical arcs.
+func (r *Rect) ToSubPaths() []SubPath {
+	x0, y0, x1, y1 := r.R.Min.X, r.R.Min.Y, r.R.Max.X, r.R.Max.Y
+	rx := math.Min(math.Abs(r.Rx), r.R.Width()/2)
+	ry := math.Min(math.Abs(r.Ry), r.R.Height()/2)
+	if rx <= 0 || ry <= 0 {
+		return BuildSubPaths([]PathCommand{
+			{Command: 'M', Params: []float64{x0, y0}},
+			{Command: 'L', Params: []float64{x1, y0}},
+			{Command: 'L', Params: []float64{x1, y1}},
+			{Command: 'L', Params: []float64{x0, y1}},
+			{Command: 'Z'},
+		})
+	}
+
+	cmds := []PathCommand{{Command: 'M', Params: []float64{x0 + rx, y0}}}
+	corner := func(x, y float64) {
+		cmds = append(cmds, PathCommand{Command: 'A', Params: []float64{rx, ry, 0, 0, 1, x, y}})
+	}
+	// Sides are left out where the corners meet.
+	side := func(x, y float64) {
+		if last := cmds[len(cmds)-1].Params; last[len(last)-2] != x || last[len(last)-1] != y {
+			cmds = append(cmds, PathCommand{Command: 'L', Params: []float64{x, y}})
+		}
+	}
+	side(x1-rx, y0)
+	corner(x1, y0+ry)
+	side(x1, y1-ry)
+	corner(x1-rx, y1)
+	side(x0+rx, y1)
+	corner(x0, y1-ry)
+	side(x0, y0+ry)
+	corner(x0+rx, y0)
+	return BuildSubPaths(append(cmds, PathCommand{Command: 'Z'}))
+}
+
 func (r *Rect) marshalAttrs(am AttrMap) {
 	am["x"] = floatToString(r.R.Min.X)
 	am["y"] = floatToString(r.R.Min.Y)
 	am["width"] = floatToString(r.R.Width())
 	am["height"] = floatToString(r.R.Height())
+	if r.Rx != 0 || r.Ry != 0 {
+		am["rx"] = floatToString(r.Rx)
+		// ry defaults to rx.
+		if r.Ry != r.Rx {
+			am["ry"] = floatToString(r.Ry)
+		}
+	}
 }
 
 func (r *Rect) unmarshalAttrs(am AttrMap) error {
@@ -78,5 +134,11 @@ func (r *Rect) unmarshalAttrs(am AttrMap) error {
 	r.R.Min = geom.Coord{x, y}
 	r.R.Max = geom.Coord{x + width, y + height}
 
+	rx, ry, err := am.extractRadii()
+	if err != nil {
+		return err
+	}
+	r.Rx, r.Ry = rx, ry
+
 	return nil
 }
@@ -0,0 +1,73 @@
+// Copyright 2018 Joe Beda
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package svgdata
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRectRoundedCorners(t *testing.T) {
+	assert := assert.New(t)
+
+	r := mustUnmarshal(t, `<svg xmlns="http://www.w3.org/2000/svg">
+  <rect id="both" width="20" height="10" rx="4" ry="2"/>
+  <rect id="rx" width="20" height="10" rx="3"/>
+  <rect id="ry" width="20" height="10" rx="auto" ry="3"/>
+  <rect id="clamped" width="20" height="10" rx="30" ry="30"/>
+  <rect id="square" width="20" height="10" rx="-1"/>
+</svg>`)
+	rect := func(id string) *Rect {
+		return FindByID(r, id).(*Rect)
+	}
+	assert.Equal([2]float64{4, 2}, [2]float64{rect("both").Rx, rect("both").Ry})
+	assert.Equal([2]float64{3, 3}, [2]float64{rect("rx").Rx, rect("rx").Ry})
+	assert.Equal([2]float64{3, 3}, [2]float64{rect("ry").Rx, rect("ry").Ry})
+	assert.Equal([2]float64{0, 0}, [2]float64{rect("square").Rx, rect("square").Ry})
+
+	// Each corner cuts away a square less a quarter ellipse.
+	corners := func(rx, ry float64) float64 {
+		return 200 - (4-math.Pi)*rx*ry
+	}
+	assert.InDelta(corners(4, 2), subPathsArea(rect("both").ToSubPaths()), 0.05)
+	assert.InDelta(corners(3, 3), subPathsArea(rect("rx").ToSubPaths()), 0.05)
+	assert.InDelta(corners(10, 5), subPathsArea(rect("clamped").ToSubPaths()), 0.05)
+	assert.InDelta(200, subPathsArea(rect("square").ToSubPaths()), 1e-9)
+
+	// When the corners meet there are no straight sides left.
+	for _, c := range rect("clamped").ToSubPaths()[0].Commands {
+		assert.Contains("MAZ", string(c.Command))
+	}
+
+	// ry is only written when it differs from rx.
+	data, err := Marshal(r, false)
+	if assert.NoError(err) {
+		assert.NotContains(string(data), `ry="3"`)
+		back := mustUnmarshal(t, string(data))
+		for _, id := range []string{"both", "rx", "ry", "clamped", "square"} {
+			b := FindByID(back, id).(*Rect)
+			assert.Equal([2]float64{rect(id).Rx, rect(id).Ry}, [2]float64{b.Rx, b.Ry}, id)
+		}
+	}
+
+	// Flattened rects keep their rounded outline but not the radii.
+	FlattenRoot(r, 0.001)
+	p := FindByID(r, "both").(*Path)
+	assert.InDelta(corners(4, 2), subPathsArea(p.SubPaths), 0.05)
+	assert.NotContains(p.Attrs(), "rx")
+	assert.NotContains(p.Attrs(), "ry")
+}
@@ -0,0 +1,438 @@
+// Copyright 2018 Joe Beda
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package svgdata
+
+import (
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/jbeda/geom"
+	"github.com/pkg/errors"
+)
+
+// renderTolerance is how far flattened curves may be from the true curves, in
+// pixels.
+const renderTolerance = 0.1
+
+// renderSamples is the number of sample rows in each row of pixels.  Within a
+// sample row coverage is computed exactly.
+const renderSamples = 16
+
+// RenderOptions control how a document is drawn.
+type RenderOptions struct {
+	// Width and Height are the size of the image in pixels.  If both are zero
+	// the size of the document is used.  If one is zero it is picked to keep
+	// the aspect ratio of the document.
+	Width, Height int
+
+	// Background fills the image before drawing.  If it is nil the
+	// background is transparent.
+	Background color.Color
+}
+
+// Render draws the document into a new image with anti-aliasing.  Fills with
+// either fill rule, strokes with their joins, caps and dashes, solid colors,
+// opacity, transforms and the mapping from the viewBox to the viewport are
+// supported.  Gradients, patterns, clipping, masks, text and images are not
+// drawn.
+func Render(r *Root, opts RenderOptions) (*image.RGBA, error) {
+	size, vt, err := viewportTransform(r)
+	if err != nil {
+		return nil, err
+	}
+	w, h := opts.Width, opts.Height
+	switch {
+	case w <= 0 && h <= 0:
+		w, h = int(math.Ceil(size.X)), int(math.Ceil(size.Y))
+	case w <= 0:
+		w = int(math.Ceil(float64(h) * size.X / size.Y))
+	case h <= 0:
+		h = int(math.Ceil(float64(w) * size.Y / size.X))
+	}
+	if w <= 0 || h <= 0 {
+		return nil, errors.Errorf("invalid image size %dx%d", w, h)
+	}
+
+	ctm := NewScale(float64(w)/size.X, float64(h)/size.Y).Multiply(vt)
+
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	if opts.Background != nil {
+		c := color.RGBAModel.Convert(opts.Background).(color.RGBA)
+		for i := 0; i < len(img.Pix); i += 4 {
+			img.Pix[i], img.Pix[i+1], img.Pix[i+2], img.Pix[i+3] = c.R, c.G, c.B, c.A
+		}
+	}
+	rd := &renderer{cov: make([]float32, w*h), w: w, h: h}
+	if err := rd.children(img, r, ctm, NodeStyle(r)); err != nil {
+		return nil, err
+	}
+	return img, nil
+}
+
+// RenderPNG draws the document and writes it to w as a PNG.
+func RenderPNG(w io.Writer, r *Root, opts RenderOptions) error {
+	img, err := Render(r, opts)
+	if err != nil {
+		return err
+	}
+	return errors.WithStack(png.Encode(w, img))
+}
+
+// viewportTransform returns the size of the viewport in pixels and the
+// transform from the user space of r to the viewport.
+func viewportTransform(r *Root) (geom.Coord, Transform, error) {
+	size, err := documentSize(r)
+	if err != nil {
+		return size, Transform{}, err
+	}
+	vb, ok := r.Attrs()["viewBox"]
+	if !ok {
+		return size, IdentityTransform, nil
+	}
+	t, err := viewBoxTransform(vb, r.Attrs()["preserveAspectRatio"], size)
+	return size, t, err
+}
+
+// documentSize returns the size of the viewport in pixels.  A missing width
+// or height comes from the viewBox, falling back to 300 by 150 as in web
+// browsers.
+func documentSize(r *Root) (geom.Coord, error) {
+	size := geom.Coord{X: 300, Y: 150}
+	if vb, ok := r.Attrs()["viewBox"]; ok {
+		nums, err := parseNumberList(vb)
+		if err != nil {
+			return size, err
+		}
+		if len(nums) != 4 || nums[2] <= 0 || nums[3] <= 0 {
+			return size, errors.Errorf("invalid viewBox: %s", vb)
+		}
+		size = geom.Coord{X: nums[2], Y: nums[3]}
+	}
+	for _, k := range []string{"width", "height"} {
+		v, ok := r.Attrs()[k]
+		if !ok || strings.HasSuffix(v, "%") {
+			continue
+		}
+		f, err := parseValue(strings.TrimSpace(v))
+		if err != nil {
+			return size, err
+		}
+		if f <= 0 {
+			return size, errors.Errorf("invalid document %s: %s", k, v)
+		}
+		if k == "width" {
+			size.X = f
+		} else {
+			size.Y = f
+		}
+	}
+	return size, nil
+}
+
+// viewBoxTransform maps the viewBox onto a viewport of the given size
+// following preserveAspectRatio.
+func viewBoxTransform(viewBox, par string, size geom.Coord) (Transform, error) {
+	nums, err := parseNumberList(viewBox)
+	if err != nil {
+		return Transform{}, err
+	}
+	if len(nums) != 4 || nums[2] <= 0 || nums[3] <= 0 {
+		return Transform{}, errors.Errorf("invalid viewBox: %s", viewBox)
+	}
+	x, y, w, h := nums[0], nums[1], nums[2], nums[3]
+	sx, sy := size.X/w, size.Y/h
+
+	fields := strings.Fields(par)
+	if len(fields) > 0 && fields[0] == "defer" {
+		fields = fields[1:]
+	}
+	align, slice := "xMidYMid", false
+	if len(fields) > 0 {
+		align = fields[0]
+	}
+	if len(fields) > 1 {
+		switch fields[1] {
+		case "meet":
+		case "slice":
+			slice = true
+		default:
+			return Transform{}, errors.Errorf("invalid preserveAspectRatio: %s", par)
+		}
+	}
+	if align == "none" {
+		return NewScale(sx, sy).Multiply(NewTranslate(-x, -y)), nil
+	}
+	if len(align) != 8 || align[0] != 'x' || align[4] != 'Y' {
+		return Transform{}, errors.Errorf("invalid preserveAspectRatio: %s", par)
+	}
+	pos := func(s string) (float64, error) {
+		switch s {
+		case "Min":
+			return 0, nil
+		case "Mid":
+			return 0.5, nil
+		case "Max":
+			return 1, nil
+		}
+		return 0, errors.Errorf("invalid preserveAspectRatio: %s", par)
+	}
+	ax, err := pos(align[1:4])
+	if err != nil {
+		return Transform{}, err
+	}
+	ay, err := pos(align[5:8])
+	if err != nil {
+		return Transform{}, err
+	}
+
+	s := math.Min(sx, sy)
+	if slice {
+		s = math.Max(sx, sy)
+	}
+	tx, ty := (size.X-w*s)*ax, (size.Y-h*s)*ay
+	return NewTranslate(tx, ty).Multiply(NewScale(s, s)).Multiply(NewTranslate(-x, -y)), nil
+}
+
+// renderer draws nodes into images.  cov is reused for the coverage of every
+// fill and stroke.
+type renderer struct {
+	cov  []float32
+	w, h int
+}
+
+// children draws the children of n.  Nodes with opacity are drawn into a
+// separate layer that is then blended in.
+func (rd *renderer) children(dst *image.RGBA, n Node, ctm Transform, st Style) error {
+	return walkGroups(n, st, func(c Node, t Transform, cst Style, opacity float64) error {
+		cctm := ctm.Multiply(t)
+		target := dst
+		if opacity < 1 {
+			target = image.NewRGBA(dst.Bounds())
+		}
+		if s, ok := c.(Shape); ok && cst.Get("visibility", "visible") == "visible" {
+			if err := rd.shape(target, s, cctm, cst); err != nil {
+				return err
+			}
+		}
+		if err := rd.children(target, c, cctm, cst); err != nil {
+			return err
+		}
+		if opacity < 1 {
+			blendLayer(dst, target, opacity)
+		}
+		return nil
+	})
+}
+
+// styleOpacity returns an opacity property clamped to [0, 1].
+func styleOpacity(st Style, k string) float64 {
+	v := strings.TrimSpace(st.Get(k, "1"))
+	scale := 1.0
+	if strings.HasSuffix(v, "%") {
+		v, scale = v[:len(v)-1], 0.01
+	}
+	o, err := strconv.ParseFloat(v, 64)
+	if err != nil {
+		return 1
+	}
+	return math.Max(0, math.Min(1, o*scale))
+}
+
+func (rd *renderer) shape(dst *image.RGBA, s Shape, ctm Transform, st Style) error {
+	tol := renderTolerance / math.Max(ctm.Scale(), geomEpsilon)
+
+	if c, ok := parseColor(st.Get("fill", "black")); ok {
+		var pls []Polyline
+		for _, pl := range FlattenShape(s, tol) {
+			pls = append(pls, pl.transform(ctm))
+		}
+		rd.fill(dst, pls, st.FillRule() == "evenodd", c, styleOpacity(st, "fill-opacity"))
+	}
+
+	c, ok := parseColor(st.Get("stroke", "none"))
+	if !ok || st.StrokeWidth() <= 0 {
+		return nil
+	}
+	sps, dashed := dashShape(s, st)
+	if !dashed {
+		sps = s.ToSubPaths()
+	}
+	sk := strokePieces(sps, StrokeOptionsFromStyle(st, tol))
+	if sk == nil {
+		return nil
+	}
+	// The pieces overlap, so orient them all the same way and fill them with
+	// the nonzero rule to cover their union.
+	var pls []Polyline
+	for _, r := range sk.pieces {
+		for _, pl := range r.pls {
+			pl = pl.transform(ctm)
+			if polylineArea(pl.Points) < 0 {
+				for i, j := 0, len(pl.Points)-1; i < j; i, j = i+1, j-1 {
+					pl.Points[i], pl.Points[j] = pl.Points[j], pl.Points[i]
+				}
+			}
+			pls = append(pls, pl)
+		}
+	}
+	rd.fill(dst, pls, false, c, styleOpacity(st, "stroke-opacity"))
+	return nil
+}
+
+// rasterEdge is a polygon edge in pixel coordinates with y0 < y1.
+type rasterEdge struct {
+	x0, y0, x1, y1 float64
+	dir            int
+}
+
+// fill blends c into dst wherever the polygons cover it.
+func (rd *renderer) fill(dst *image.RGBA, pls []Polyline, evenOdd bool, c color.RGBA, opacity float64) {
+	if opacity <= 0 {
+		return
+	}
+	var edges []rasterEdge
+	for _, pl := range pls {
+		n := len(pl.Points)
+		for i := 0; i < n; i++ {
+			a, b := pl.Points[i], pl.Points[(i+1)%n]
+			switch {
+			case a.Y < b.Y:
+				edges = append(edges, rasterEdge{a.X, a.Y, b.X, b.Y, 1})
+			case a.Y > b.Y:
+				edges = append(edges, rasterEdge{b.X, b.Y, a.X, a.Y, -1})
+			}
+		}
+	}
+	if len(edges) == 0 {
+		return
+	}
+	sort.Slice(edges, func(i, j int) bool { return edges[i].y0 < edges[j].y0 })
+
+	ymin, ymax := edges[0].y0, edges[0].y1
+	for _, e := range edges {
+		ymax = math.Max(ymax, e.y1)
+	}
+	row0 := int(math.Max(0, math.Floor(ymin)))
+	row1 := int(math.Min(float64(rd.h), math.Ceil(ymax)))
+
+	type crossing struct {
+		x   float64
+		dir int
+	}
+	var active []rasterEdge
+	var xs []crossing
+	next := 0
+	const weight = 1.0 / renderSamples
+	for y := row0; y < row1; y++ {
+		cov := rd.cov[y*rd.w : (y+1)*rd.w]
+		for s := 0; s < renderSamples; s++ {
+			sy := float64(y) + (float64(s)+0.5)/renderSamples
+			for next < len(edges) && edges[next].y0 <= sy {
+				active = append(active, edges[next])
+				next++
+			}
+			xs = xs[:0]
+			kept := active[:0]
+			for _, e := range active {
+				if e.y1 <= sy {
+					continue
+				}
+				kept = append(kept, e)
+				if e.y0 <= sy {
+					x := e.x0 + (sy-e.y0)/(e.y1-e.y0)*(e.x1-e.x0)
+					xs = append(xs, crossing{x, e.dir})
+				}
+			}
+			active = kept
+			sort.Slice(xs, func(i, j int) bool { return xs[i].x < xs[j].x })
+
+			wn := 0
+			for i := 0; i+1 < len(xs); i++ {
+				wn += xs[i].dir
+				inside := wn != 0
+				if evenOdd {
+					inside = wn%2 != 0
+				}
+				if inside {
+					addSpan(cov, xs[i].x, xs[i+1].x, weight)
+				}
+			}
+		}
+	}
+
+	// Blend the covered pixels and clear the coverage for the next fill.
+	for y := row0; y < row1; y++ {
+		cov := rd.cov[y*rd.w : (y+1)*rd.w]
+		for x, a := range cov {
+			if a <= 0 {
+				continue
+			}
+			cov[x] = 0
+			blendPixel(dst, x, y, c, math.Min(float64(a), 1)*opacity)
+		}
+	}
+}
+
+// addSpan adds weight times the part of each pixel covered by the span from
+// x0 to x1.
+func addSpan(cov []float32, x0, x1 float64, weight float32) {
+	x0 = math.Max(x0, 0)
+	x1 = math.Min(x1, float64(len(cov)))
+	if x1 <= x0 {
+		return
+	}
+	i0, i1 := int(x0), int(x1)
+	if i0 == i1 {
+		cov[i0] += float32(x1-x0) * weight
+		return
+	}
+	cov[i0] += float32(float64(i0+1)-x0) * weight
+	for i := i0 + 1; i < i1; i++ {
+		cov[i] += weight
+	}
+	if i1 < len(cov) {
+		cov[i1] += float32(x1-float64(i1)) * weight
+	}
+}
+
+// blendPixel draws c with alpha a over the pixel at x, y.
+func blendPixel(dst *image.RGBA, x, y int, c color.RGBA, a float64) {
+	i := dst.PixOffset(x, y)
+	p := dst.Pix[i : i+4 : i+4]
+	sa := a * float64(c.A) / 255
+	p[0] = uint8(float64(c.R)*sa + float64(p[0])*(1-sa) + 0.5)
+	p[1] = uint8(float64(c.G)*sa + float64(p[1])*(1-sa) + 0.5)
+	p[2] = uint8(float64(c.B)*sa + float64(p[2])*(1-sa) + 0.5)
+	p[3] = uint8(255*sa + float64(p[3])*(1-sa) + 0.5)
+}
+
+// blendLayer draws the premultiplied layer over dst with the given opacity.
+func blendLayer(dst, layer *image.RGBA, opacity float64) {
+	for i := 0; i < len(layer.Pix); i += 4 {
+		la := float64(layer.Pix[i+3]) / 255 * opacity
+		if la <= 0 {
+			continue
+		}
+		for j := 0; j < 4; j++ {
+			dst.Pix[i+j] = uint8(float64(layer.Pix[i+j])*opacity + float64(dst.Pix[i+j])*(1-la) + 0.5)
+		}
+	}
+}
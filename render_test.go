@@ -0,0 +1,178 @@
+// Copyright 2018 Joe Beda
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package svgdata
+
+import (
+	"bytes"
+	"flag"
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/jbeda/geom"
+	"github.com/stretchr/testify/assert"
+)
+
+var updateGolden = flag.Bool("update", false, "update golden files")
+
+// assertGolden compares img to the PNG in testdata/render, allowing each
+// channel to differ by a small amount.
+func assertGolden(t *testing.T, name string, img *image.RGBA) {
+	fn := filepath.Join("testdata", "render", name+".png")
+	if *updateGolden {
+		var buf bytes.Buffer
+		if err := png.Encode(&buf, img); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.MkdirAll(filepath.Dir(fn), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(fn, buf.Bytes(), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	f, err := os.Open(fn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	golden, err := png.Decode(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !assert.Equal(t, golden.Bounds(), img.Bounds(), name) {
+		return
+	}
+	b := img.Bounds()
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			e := color.RGBAModel.Convert(golden.At(x, y)).(color.RGBA)
+			a := img.RGBAAt(x, y)
+			for _, d := range []int{
+				int(e.R) - int(a.R), int(e.G) - int(a.G),
+				int(e.B) - int(a.B), int(e.A) - int(a.A),
+			} {
+				if d < -2 || d > 2 {
+					t.Errorf("%s: pixel %d,%d is %v, expected %v", name, x, y, a, e)
+					return
+				}
+			}
+		}
+	}
+}
+
+func TestRenderGolden(t *testing.T) {
+	tests := []struct {
+		name string
+		svg  string
+	}{
+		{"fills", `<svg xmlns="http://www.w3.org/2000/svg" width="64" height="64">
+  <rect x="4" y="4" width="40" height="30" fill="#f00"/>
+  <circle cx="40" cy="40" r="20" fill="blue" fill-opacity="0.5"/>
+  <path d="M8 60 L20 36 L32 60 Z" fill="green"/>
+</svg>`},
+		{"fillrule", `<svg xmlns="http://www.w3.org/2000/svg" width="64" height="32">
+  <path d="M2 2H30V30H2Z M8 8H24V24H8Z" fill="black"/>
+  <path d="M34 2H62V30H34Z M40 8H56V24H40Z" fill="black" fill-rule="evenodd"/>
+</svg>`},
+		{"strokes", `<svg xmlns="http://www.w3.org/2000/svg" width="64" height="64" fill="none">
+  <polyline points="8,24 20,8 32,24" stroke="black" stroke-width="6" stroke-linejoin="miter"/>
+  <polyline points="36,24 48,8 60,24" stroke="black" stroke-width="6" stroke-linejoin="round" stroke-linecap="round"/>
+  <path d="M8 40H56" stroke="red" stroke-width="4" stroke-linecap="square"/>
+  <path d="M8 54H56" stroke="blue" stroke-width="3" stroke-dasharray="6 3"/>
+</svg>`},
+		{"viewbox", `<svg xmlns="http://www.w3.org/2000/svg" width="64" height="32" viewBox="0 0 10 10">
+  <g transform="rotate(45 5 5)" opacity="0.5">
+    <rect x="2" y="2" width="6" height="6" fill="purple"/>
+    <rect x="4" y="4" width="6" height="6" fill="orange"/>
+  </g>
+</svg>`},
+	}
+	for _, test := range tests {
+		r := mustUnmarshal(t, test.svg)
+		img, err := Render(r, RenderOptions{Background: color.White})
+		if assert.NoError(t, err, test.name) {
+			assertGolden(t, test.name, img)
+		}
+	}
+}
+
+func TestRenderCoverage(t *testing.T) {
+	assert := assert.New(t)
+
+	r := mustUnmarshal(t, `<svg xmlns="http://www.w3.org/2000/svg" width="4" height="4">
+  <rect x="0.5" y="1" width="2" height="2" fill="#000"/>
+</svg>`)
+	img, err := Render(r, RenderOptions{})
+	if !assert.NoError(err) {
+		return
+	}
+	assert.Equal(image.Rect(0, 0, 4, 4), img.Bounds())
+	assert.Equal(color.RGBA{}, img.RGBAAt(1, 0))
+	assert.Equal(color.RGBA{A: 128}, img.RGBAAt(0, 1))
+	assert.Equal(color.RGBA{A: 255}, img.RGBAAt(1, 2))
+	assert.Equal(color.RGBA{A: 128}, img.RGBAAt(2, 2))
+	assert.Equal(color.RGBA{}, img.RGBAAt(3, 2))
+}
+
+func TestRenderSize(t *testing.T) {
+	assert := assert.New(t)
+
+	r := mustUnmarshal(t, `<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 20 10">
+  <rect width="20" height="10" fill="#fff"/>
+</svg>`)
+	img, err := Render(r, RenderOptions{Width: 40})
+	if assert.NoError(err) {
+		assert.Equal(image.Rect(0, 0, 40, 20), img.Bounds())
+		assert.Equal(color.RGBA{255, 255, 255, 255}, img.RGBAAt(39, 19))
+	}
+
+	var buf bytes.Buffer
+	if assert.NoError(RenderPNG(&buf, r, RenderOptions{})) {
+		img, err := png.Decode(&buf)
+		if assert.NoError(err) {
+			assert.Equal(image.Rect(0, 0, 20, 10), img.Bounds())
+		}
+	}
+}
+
+func TestViewBoxTransform(t *testing.T) {
+	assert := assert.New(t)
+
+	size := geom.Coord{X: 200, Y: 100}
+	tests := []struct {
+		par      string
+		expected Transform
+	}{
+		{"", Transform{A: 1, D: 1, E: 50}},
+		{"xMinYMin", Transform{A: 1, D: 1}},
+		{"xMaxYMax meet", Transform{A: 1, D: 1, E: 100}},
+		{"xMidYMid slice", Transform{A: 2, D: 2, F: -50}},
+		{"none", Transform{A: 2, D: 1}},
+	}
+	for _, test := range tests {
+		tr, err := viewBoxTransform("0 0 100 100", test.par, size)
+		if assert.NoError(err, test.par) {
+			assert.Equal(test.expected, tr, test.par)
+		}
+	}
+
+	_, err := viewBoxTransform("0 0 100 100", "xFooYMid", size)
+	assert.Error(err)
+}
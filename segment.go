@@ -0,0 +1,371 @@
+// Copyright 2018 Joe Beda
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package svgdata
+
+import (
+	"math"
+
+	"github.com/jbeda/geom"
+)
+
+// segment is a single drawing command normalized to absolute coordinates.
+// Relative commands are resolved, H and V become lines, S and T have their
+// implied control points made explicit and Z becomes a line back to the start
+// of the subpath.
+type segment struct {
+	kind         byte // One of 'L', 'Q', 'C' or 'A'
+	start, end   geom.Coord
+	ctrl1, ctrl2 geom.Coord // Control points for 'Q' (ctrl1 only) and 'C'
+
+	// Arc parameters, only valid for 'A'
+	rx, ry          float64
+	rotation        float64 // In degrees, as in the path string
+	largeArc, sweep bool
+}
+
+// subPathSegments converts the commands in a SubPath into segments.  Moves
+// are dropped and degenerate arcs are handled as described in the SVG
+// implementation notes.
+func subPathSegments(sp SubPath) []segment {
+	var r []segment
+	var lastCtrl geom.Coord
+	var lastKind byte
+
+	for _, c := range sp.Commands {
+		start, end := c.Start(), c.End()
+		abs := func(x, y float64) geom.Coord {
+			if c.Command >= 'a' {
+				return geom.Coord{X: start.X + x, Y: start.Y + y}
+			}
+			return geom.Coord{X: x, Y: y}
+		}
+
+		s := segment{kind: 'L', start: start, end: end}
+		switch c.Command {
+		case 'M', 'm':
+			lastKind = 0
+			continue
+		case 'Z', 'z':
+			if start == end {
+				lastKind = 0
+				continue
+			}
+		case 'C', 'c':
+			s.kind = 'C'
+			s.ctrl1 = abs(c.Params[0], c.Params[1])
+			s.ctrl2 = abs(c.Params[2], c.Params[3])
+		case 'S', 's':
+			s.kind = 'C'
+			s.ctrl1 = start
+			if lastKind == 'C' {
+				s.ctrl1 = coordSub(coordScale(start, 2), lastCtrl)
+			}
+			s.ctrl2 = abs(c.Params[0], c.Params[1])
+		case 'Q', 'q':
+			s.kind = 'Q'
+			s.ctrl1 = abs(c.Params[0], c.Params[1])
+		case 'T', 't':
+			s.kind = 'Q'
+			s.ctrl1 = start
+			if lastKind == 'Q' {
+				s.ctrl1 = coordSub(coordScale(start, 2), lastCtrl)
+			}
+		case 'A', 'a':
+			if start == end {
+				// An arc with identical endpoints is omitted entirely.
+				lastKind = 0
+				continue
+			}
+			if c.Params[0] != 0 && c.Params[1] != 0 {
+				s.kind = 'A'
+				s.rx, s.ry = math.Abs(c.Params[0]), math.Abs(c.Params[1])
+				s.rotation = c.Params[2]
+				s.largeArc = c.Params[3] != 0
+				s.sweep = c.Params[4] != 0
+			}
+		}
+
+		lastKind = s.kind
+		switch s.kind {
+		case 'C':
+			lastCtrl = s.ctrl2
+		case 'Q':
+			lastCtrl = s.ctrl1
+		}
+		r = append(r, s)
+	}
+	return r
+}
+
+// point returns the position on the segment at parameter t in [0, 1].
+func (s segment) point(t float64) geom.Coord {
+	switch s.kind {
+	case 'Q':
+		return s.quad().Point(t)
+	case 'C':
+		return s.cubic().Point(t)
+	case 'A':
+		ea := s.ellipse()
+		return ea.point(ea.theta + t*ea.delta)
+	}
+	return coordLerp(s.start, s.end, t)
+}
+
+// derivative returns the first derivative of the segment with respect to t.
+func (s segment) derivative(t float64) geom.Coord {
+	switch s.kind {
+	case 'Q':
+		return s.quad().Derivative(t)
+	case 'C':
+		return s.cubic().Derivative(t)
+	case 'A':
+		ea := s.ellipse()
+		return coordScale(ea.derivative(ea.theta+t*ea.delta), ea.delta)
+	}
+	return coordSub(s.end, s.start)
+}
+
+// ellipseArc is an elliptical arc in center parameterization.
+type ellipseArc struct {
+	center geom.Coord
+	rx, ry float64
+	phi    float64 // Rotation of the x axis of the ellipse in radians
+	theta  float64 // Start angle in radians
+	delta  float64 // Sweep in radians, positive is towards the positive y axis
+}
+
+// ellipse converts an 'A' segment from the endpoint parameterization used in
+// path strings to center parameterization.  Radii that are too small to span
+// the endpoints are scaled up as required by the SVG spec.
+func (s segment) ellipse() ellipseArc {
+	phi := s.rotation * math.Pi / 180
+	cosPhi, sinPhi := math.Cos(phi), math.Sin(phi)
+
+	dx2, dy2 := (s.start.X-s.end.X)/2, (s.start.Y-s.end.Y)/2
+	x1p := cosPhi*dx2 + sinPhi*dy2
+	y1p := -sinPhi*dx2 + cosPhi*dy2
+
+	rx, ry := s.rx, s.ry
+	if lambda := (x1p*x1p)/(rx*rx) + (y1p*y1p)/(ry*ry); lambda > 1 {
+		l := math.Sqrt(lambda)
+		rx, ry = rx*l, ry*l
+	}
+
+	num := rx*rx*ry*ry - rx*rx*y1p*y1p - ry*ry*x1p*x1p
+	den := rx*rx*y1p*y1p + ry*ry*x1p*x1p
+	coef := 0.0
+	if den != 0 && num > 0 {
+		coef = math.Sqrt(num / den)
+	}
+	if s.largeArc == s.sweep {
+		coef = -coef
+	}
+	cxp := coef * rx * y1p / ry
+	cyp := -coef * ry * x1p / rx
+
+	ea := ellipseArc{
+		center: geom.Coord{
+			X: cosPhi*cxp - sinPhi*cyp + (s.start.X+s.end.X)/2,
+			Y: sinPhi*cxp + cosPhi*cyp + (s.start.Y+s.end.Y)/2,
+		},
+		rx:  rx,
+		ry:  ry,
+		phi: phi,
+	}
+
+	ea.theta = math.Atan2((y1p-cyp)/ry, (x1p-cxp)/rx)
+	theta2 := math.Atan2((-y1p-cyp)/ry, (-x1p-cxp)/rx)
+	ea.delta = theta2 - ea.theta
+	if s.sweep && ea.delta < 0 {
+		ea.delta += 2 * math.Pi
+	} else if !s.sweep && ea.delta > 0 {
+		ea.delta -= 2 * math.Pi
+	}
+	return ea
+}
+
+// point returns the position on the ellipse at angle a.
+func (ea ellipseArc) point(a float64) geom.Coord {
+	cosPhi, sinPhi := math.Cos(ea.phi), math.Sin(ea.phi)
+	x, y := ea.rx*math.Cos(a), ea.ry*math.Sin(a)
+	return geom.Coord{
+		X: ea.center.X + cosPhi*x - sinPhi*y,
+		Y: ea.center.Y + sinPhi*x + cosPhi*y,
+	}
+}
+
+// derivative returns the derivative of the ellipse with respect to angle a.
+func (ea ellipseArc) derivative(a float64) geom.Coord {
+	cosPhi, sinPhi := math.Cos(ea.phi), math.Sin(ea.phi)
+	x, y := -ea.rx*math.Sin(a), ea.ry*math.Cos(a)
+	return geom.Coord{
+		X: cosPhi*x - sinPhi*y,
+		Y: sinPhi*x + cosPhi*y,
+	}
+}
+
+// isCircular returns true if the arc is part of a circle.
+func (ea ellipseArc) isCircular() bool {
+	return math.Abs(ea.rx-ea.ry) <= geomEpsilon*math.Max(1, ea.rx)
+}
+
+// cubics approximates the arc with cubic Béziers, each sweeping at most a
+// quarter turn.
+func (ea ellipseArc) cubics() []CubicBezier {
+	n := int(math.Ceil(math.Abs(ea.delta) / (math.Pi / 2)))
+	if n < 1 {
+		n = 1
+	}
+	d := ea.delta / float64(n)
+	k := 4.0 / 3 * math.Tan(d/4)
+	r := make([]CubicBezier, n)
+	for i := range r {
+		a0, a1 := ea.theta+float64(i)*d, ea.theta+float64(i+1)*d
+		p0, p3 := ea.point(a0), ea.point(a1)
+		r[i] = CubicBezier{
+			P0: p0,
+			P1: coordAdd(p0, coordScale(ea.derivative(a0), k)),
+			P2: coordSub(p3, coordScale(ea.derivative(a1), k)),
+			P3: p3,
+		}
+	}
+	return r
+}
+
+// reverse returns the segment traversed in the other direction.
+func (s segment) reverse() segment {
+	s.start, s.end = s.end, s.start
+	if s.kind == 'C' {
+		s.ctrl1, s.ctrl2 = s.ctrl2, s.ctrl1
+	}
+	s.sweep = !s.sweep
+	return s
+}
+
+// command returns the segment as an absolute path command.
+func (s segment) command() PathCommand {
+	switch s.kind {
+	case 'Q':
+		return PathCommand{Command: 'Q', Params: []float64{s.ctrl1.X, s.ctrl1.Y, s.end.X, s.end.Y}}
+	case 'C':
+		return PathCommand{Command: 'C', Params: []float64{
+			s.ctrl1.X, s.ctrl1.Y, s.ctrl2.X, s.ctrl2.Y, s.end.X, s.end.Y,
+		}}
+	case 'A':
+		return PathCommand{Command: 'A', Params: []float64{
+			s.rx, s.ry, s.rotation, boolToFlag(s.largeArc), boolToFlag(s.sweep), s.end.X, s.end.Y,
+		}}
+	}
+	return PathCommand{Command: 'L', Params: []float64{s.end.X, s.end.Y}}
+}
+
+// segmentsToCommands converts a list of connected segments back to commands
+// starting with a move to the start of the first one.
+func segmentsToCommands(segs []segment, closed bool) []PathCommand {
+	if len(segs) == 0 {
+		return nil
+	}
+	cmds := []PathCommand{{Command: 'M', Params: []float64{segs[0].start.X, segs[0].start.Y}}}
+	for i, s := range segs {
+		// A final line back to the start is covered by the close.
+		if closed && i == len(segs)-1 && s.kind == 'L' && s.end == segs[0].start {
+			break
+		}
+		cmds = append(cmds, s.command())
+	}
+	if closed {
+		cmds = append(cmds, PathCommand{Command: 'Z'})
+	}
+	return cmds
+}
+
+// split divides the segment at parameter t.
+func (s segment) split(t float64) (segment, segment) {
+	switch s.kind {
+	case 'Q':
+		a, b := s.quad().Split(t)
+		return quadSegment(a), quadSegment(b)
+	case 'C':
+		a, b := s.cubic().Split(t)
+		return cubicSegment(a), cubicSegment(b)
+	}
+
+	a, b := s, s
+	m := s.point(t)
+	a.end, b.start = m, m
+	if s.kind == 'A' {
+		// Use the radii after any scaling so both halves keep the same
+		// ellipse.
+		ea := s.ellipse()
+		a.rx, a.ry, b.rx, b.ry = ea.rx, ea.ry, ea.rx, ea.ry
+		a.largeArc = math.Abs(ea.delta*t) > math.Pi
+		b.largeArc = math.Abs(ea.delta*(1-t)) > math.Pi
+	}
+	return a, b
+}
+
+// sub returns the part of the segment between parameters t0 and t1.
+func (s segment) sub(t0, t1 float64) segment {
+	if s.kind == 'L' {
+		// Interpolate directly to avoid compounding rounding errors.
+		s.start, s.end = s.point(t0), s.point(t1)
+		return s
+	}
+	if t1 < 1 {
+		s, _ = s.split(t1)
+	}
+	if t0 > 0 {
+		_, s = s.split(t0 / t1)
+	}
+	return s
+}
+
+// transform returns the segment with t applied.  Arcs are mapped to the
+// ellipse that the transformed arc lies on.
+func (s segment) transform(t Transform) segment {
+	r := s
+	r.start, r.end = t.Apply(s.start), t.Apply(s.end)
+	r.ctrl1, r.ctrl2 = t.Apply(s.ctrl1), t.Apply(s.ctrl2)
+	if s.kind != 'A' {
+		return r
+	}
+
+	// The arc is the image of the unit circle under K = M * R(phi) *
+	// diag(rx, ry).  The radii of the new ellipse are the singular values of
+	// K and its rotation is that of the major axis of K * K^T.
+	ea := s.ellipse()
+	cosPhi, sinPhi := math.Cos(ea.phi), math.Sin(ea.phi)
+	k11 := (t.A*cosPhi + t.C*sinPhi) * ea.rx
+	k12 := (-t.A*sinPhi + t.C*cosPhi) * ea.ry
+	k21 := (t.B*cosPhi + t.D*sinPhi) * ea.rx
+	k22 := (-t.B*sinPhi + t.D*cosPhi) * ea.ry
+
+	p := k11*k11 + k12*k12
+	q := k11*k21 + k12*k22
+	u := k21*k21 + k22*k22
+	mid, disc := (p+u)/2, math.Hypot((p-u)/2, q)
+	rx, ry := math.Sqrt(mid+disc), math.Sqrt(math.Max(0, mid-disc))
+	if ry <= geomEpsilon*math.Max(1, rx) {
+		// Flattened to a line.
+		r.kind = 'L'
+		return r
+	}
+	r.rx, r.ry = rx, ry
+	r.rotation = math.Atan2(2*q, p-u) / 2 * 180 / math.Pi
+	if t.A*t.D-t.B*t.C < 0 {
+		r.sweep = !s.sweep
+	}
+	return r
+}
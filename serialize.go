@@ -19,6 +19,7 @@ import (
 	"encoding/xml"
 	"fmt"
 	"log"
+	"math"
 	"strconv"
 )
 
@@ -154,3 +155,13 @@ func copyAttrMap(am AttrMap) AttrMap {
 func floatToString(f float64) string {
 	return strconv.FormatFloat(f, 'g', -1, 64)
 }
+
+// roundedNumber formats a number in plain decimal notation, rounding away
+// floating point noise.
+func roundedNumber(f float64) string {
+	s := strconv.FormatFloat(math.Round(f*1e9)/1e9, 'f', -1, 64)
+	if s == "-0" {
+		return "0"
+	}
+	return s
+}
@@ -0,0 +1,95 @@
+// Copyright 2018 Joe Beda
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package svgdata
+
+// Shape is implemented by nodes that describe geometry.  Every shape can be
+// expressed as a set of SubPaths so that geometric operations only have to be
+// written once against paths.
+type Shape interface {
+	Node
+
+	// ToSubPaths returns the geometry of the shape as SubPaths in the local
+	// coordinate system of the node.
+	ToSubPaths() []SubPath
+}
+
+// Walk calls fn for n and each of its descendants in document order.  If fn
+// returns false the children of that node are not visited.
+func Walk(n Node, fn func(n Node) bool) {
+	if !fn(n) {
+		return
+	}
+	for _, c := range *n.Children() {
+		Walk(c, fn)
+	}
+}
+
+// newPathFromNode creates a Path that carries over the attributes and children
+// of n.  This is used when a shape is replaced by an equivalent path.
+func newPathFromNode(n Node, sps []SubPath) *Path {
+	p := NewPath()
+	p.attrs = copyAttrMap(n.Attrs())
+	// Corner radii are only meaningful on the shape the path replaces.
+	delete(p.attrs, "rx")
+	delete(p.attrs, "ry")
+	p.children = append(p.children, *n.Children()...)
+	p.text = n.GetText()
+	p.SubPaths = sps
+	return p
+}
+
+// replaceShapes walks the tree under n and replaces every Shape with the node
+// returned by fn.  If fn returns nil the shape is removed.
+func replaceShapes(n Node, fn func(s Shape) Node) {
+	children := n.Children()
+	var r []Node
+	for _, c := range *children {
+		if s, ok := c.(Shape); ok {
+			if nn := fn(s); nn != nil {
+				r = append(r, nn)
+			}
+			continue
+		}
+		replaceShapes(c, fn)
+		r = append(r, c)
+	}
+	*children = r
+}
+
+// FindNodes returns every node under n, including n, for which fn returns
+// true.
+func FindNodes(n Node, fn func(n Node) bool) []Node {
+	var r []Node
+	Walk(n, func(c Node) bool {
+		if fn(c) {
+			r = append(r, c)
+		}
+		return true
+	})
+	return r
+}
+
+// FindByID returns the node under n with the given id or nil if there isn't
+// one.
+func FindByID(n Node, id string) Node {
+	var r Node
+	Walk(n, func(c Node) bool {
+		if r == nil && c.Attrs()["id"] == id {
+			r = c
+		}
+		return r == nil
+	})
+	return r
+}
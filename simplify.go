@@ -0,0 +1,354 @@
+// Copyright 2018 Joe Beda
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package svgdata
+
+import (
+	"math"
+
+	"github.com/jbeda/geom"
+)
+
+// minFitSegments is the number of line segments a run must replace before it
+// is refit as an arc or curve.
+const minFitSegments = 3
+
+// SimplifyOptions control how paths are simplified.
+type SimplifyOptions struct {
+	// Tolerance is the largest distance the simplified path may deviate from
+	// the original.
+	Tolerance float64
+
+	// FitArcs refits runs of lines that follow a circle as arcs.
+	FitArcs bool
+
+	// FitCurves refits runs of lines that follow a smooth curve as cubic
+	// Béziers.  Arcs are preferred when both are enabled.
+	FitCurves bool
+}
+
+// SimplifySubPaths reduces the number of line segments in sps.  Runs of lines
+// are simplified with the Douglas-Peucker algorithm and, if enabled, refit as
+// arcs or cubic Béziers.  Curves and arcs already in the input are kept as is.
+func SimplifySubPaths(sps []SubPath, opts SimplifyOptions) []SubPath {
+	opts.Tolerance = math.Max(opts.Tolerance, minTolerance)
+
+	var cmds []PathCommand
+	for _, sp := range sps {
+		segs := subPathSegments(sp)
+		if len(segs) == 0 {
+			cmds = append(cmds, sp.Commands...)
+			continue
+		}
+
+		var out []segment
+		var run []geom.Coord
+		flush := func() {
+			if len(run) > 1 {
+				out = append(out, simplifyRun(run, opts)...)
+			}
+			run = nil
+		}
+		for _, s := range segs {
+			if s.kind != 'L' {
+				flush()
+				out = append(out, s)
+				continue
+			}
+			if len(run) == 0 {
+				run = append(run, s.start)
+			}
+			run = append(run, s.end)
+		}
+		flush()
+
+		cmds = append(cmds, segmentsToCommands(out, sp.IsClosed())...)
+	}
+	return BuildSubPaths(cmds)
+}
+
+// Simplify simplifies the path in place.
+func (p *Path) Simplify(opts SimplifyOptions) {
+	p.SubPaths = SimplifySubPaths(p.SubPaths, opts)
+}
+
+// simplifyRun returns segments that replace the connected lines through pts.
+func simplifyRun(pts []geom.Coord, opts SimplifyOptions) []segment {
+	if !opts.FitArcs && !opts.FitCurves {
+		return linesThrough(douglasPeucker(pts, opts.Tolerance))
+	}
+
+	var out []segment
+	lineStart := 0
+	for i := 0; i < len(pts)-1; {
+		fit, j, straight := bestFit(pts, i, opts)
+		if j-i < minFitSegments {
+			// Skip over any straight run as a curve is unlikely to start
+			// part way along it.
+			if straight > i {
+				i = straight
+			} else {
+				i++
+			}
+			continue
+		}
+		if lineStart < i {
+			out = append(out, linesThrough(douglasPeucker(pts[lineStart:i+1], opts.Tolerance))...)
+		}
+		out = append(out, fit)
+		i, lineStart = j, j
+	}
+	if lineStart < len(pts)-1 {
+		out = append(out, linesThrough(douglasPeucker(pts[lineStart:], opts.Tolerance))...)
+	}
+	return out
+}
+
+// bestFit finds the longest arc or curve starting at pts[i] and returns it
+// with the index of the point it ends on.  Stretches that are straight enough
+// to be a single line are not fit and the index of the last point that is
+// within tolerance of a line from pts[i] is returned as well.
+func bestFit(pts []geom.Coord, i int, opts SimplifyOptions) (segment, int, int) {
+	var best segment
+	bestJ, straight := i, i
+	for j := i + 1; j < len(pts); j++ {
+		if withinLine(pts[i:j+1], opts.Tolerance) {
+			straight = j
+			continue
+		}
+		if j-i < minFitSegments {
+			break
+		}
+
+		var s segment
+		ok := false
+		if opts.FitArcs {
+			s, ok = fitArc(pts[i:j+1], opts.Tolerance)
+		}
+		if !ok && opts.FitCurves {
+			s, ok = fitCubic(pts[i:j+1], opts.Tolerance)
+		}
+		if !ok {
+			break
+		}
+		best, bestJ = s, j
+	}
+	return best, bestJ, straight
+}
+
+func withinLine(pts []geom.Coord, tol float64) bool {
+	a, b := pts[0], pts[len(pts)-1]
+	for _, p := range pts[1 : len(pts)-1] {
+		if d, _ := pointSegmentDist(p, a, b); d > tol {
+			return false
+		}
+	}
+	return true
+}
+
+func linesThrough(pts []geom.Coord) []segment {
+	var r []segment
+	for i := 1; i < len(pts); i++ {
+		r = append(r, segment{kind: 'L', start: pts[i-1], end: pts[i]})
+	}
+	return r
+}
+
+// douglasPeucker returns the points of pts that must be kept so that the lines
+// between them are within tol of every point that was dropped.
+func douglasPeucker(pts []geom.Coord, tol float64) []geom.Coord {
+	if len(pts) < 3 {
+		return pts
+	}
+	keep := make([]bool, len(pts))
+	keep[0], keep[len(pts)-1] = true, true
+
+	var dp func(a, b int)
+	dp = func(a, b int) {
+		far, farD := -1, tol
+		for i := a + 1; i < b; i++ {
+			if d, _ := pointSegmentDist(pts[i], pts[a], pts[b]); d > farD {
+				far, farD = i, d
+			}
+		}
+		if far < 0 {
+			return
+		}
+		keep[far] = true
+		dp(a, far)
+		dp(far, b)
+	}
+	dp(0, len(pts)-1)
+
+	var r []geom.Coord
+	for i, p := range pts {
+		if keep[i] {
+			r = append(r, p)
+		}
+	}
+	return r
+}
+
+// fitArc fits a circular arc through the first, middle and last points and
+// checks that every point and every line between them is within tol of it.
+func fitArc(pts []geom.Coord, tol float64) (segment, bool) {
+	a, m, b := pts[0], pts[len(pts)/2], pts[len(pts)-1]
+	c, ok := circumcenter(a, m, b)
+	if !ok {
+		return segment{}, false
+	}
+	r := coordDist(a, c)
+
+	total := 0.0
+	for i := 1; i < len(pts); i++ {
+		p0, p1 := coordSub(pts[i-1], c), coordSub(pts[i], c)
+		step := math.Atan2(coordCross(p0, p1), coordDot(p0, p1))
+		if step == 0 || (total != 0 && (step > 0) != (total > 0)) {
+			return segment{}, false
+		}
+		total += step
+
+		// The point and the line from the previous point must both be close
+		// to the circle.
+		if math.Abs(coordLen(p1)-r) > tol {
+			return segment{}, false
+		}
+		if half := coordDist(pts[i-1], pts[i]) / 2; r-math.Sqrt(math.Max(0, r*r-half*half)) > tol {
+			return segment{}, false
+		}
+	}
+	if math.Abs(total) >= 2*math.Pi-geomEpsilon {
+		return segment{}, false
+	}
+	if math.Abs(total) < math.Pi && r*(1-math.Cos(total/2)) <= tol {
+		// Too flat to be worth an arc.
+		return segment{}, false
+	}
+
+	return segment{
+		kind: 'A', start: a, end: b,
+		rx: r, ry: r,
+		largeArc: math.Abs(total) > math.Pi,
+		sweep:    total > 0,
+	}, true
+}
+
+// circumcenter returns the center of the circle through a, b and c.
+func circumcenter(a, b, c geom.Coord) (geom.Coord, bool) {
+	ab, ac := coordSub(b, a), coordSub(c, a)
+	d := 2 * coordCross(ab, ac)
+	if math.Abs(d) <= geomEpsilon*coordDot(ab, ab) {
+		return geom.Coord{}, false
+	}
+	ab2, ac2 := coordDot(ab, ab), coordDot(ac, ac)
+	return geom.Coord{
+		X: a.X + (ac.Y*ab2-ab.Y*ac2)/d,
+		Y: a.Y + (ab.X*ac2-ac.X*ab2)/d,
+	}, true
+}
+
+// fitCubic fits a cubic Bézier to pts using least squares with tangents taken
+// from the ends of the run, as described by Philip Schneider in "An Algorithm
+// for Automatically Fitting Digitized Curves".
+func fitCubic(pts []geom.Coord, tol float64) (segment, bool) {
+	n := len(pts)
+	t1 := coordUnit(coordSub(pts[1], pts[0]))
+	t2 := coordUnit(coordSub(pts[n-2], pts[n-1]))
+
+	// Chord length parameterization.
+	u := make([]float64, n)
+	for i := 1; i < n; i++ {
+		u[i] = u[i-1] + coordDist(pts[i-1], pts[i])
+	}
+	if u[n-1] == 0 {
+		return segment{}, false
+	}
+	for i := range u {
+		u[i] /= u[n-1]
+	}
+
+	var s segment
+	for iter := 0; iter < 4; iter++ {
+		s = cubicLeastSquares(pts, u, t1, t2)
+		if cubicFitError(s, pts, u) <= tol {
+			return s, true
+		}
+		for i := range u {
+			u[i] = newtonReparameterize(s, pts[i], u[i])
+		}
+	}
+	return s, false
+}
+
+func cubicLeastSquares(pts []geom.Coord, u []float64, t1, t2 geom.Coord) segment {
+	p0, p3 := pts[0], pts[len(pts)-1]
+	var c00, c01, c11, x0, x1 float64
+	for i, p := range pts {
+		t := u[i]
+		mt := 1 - t
+		b0, b1, b2, b3 := mt*mt*mt, 3*mt*mt*t, 3*mt*t*t, t*t*t
+		a1, a2 := coordScale(t1, b1), coordScale(t2, b2)
+		c00 += coordDot(a1, a1)
+		c01 += coordDot(a1, a2)
+		c11 += coordDot(a2, a2)
+		tmp := coordSub(p, coordAdd(coordScale(p0, b0+b1), coordScale(p3, b2+b3)))
+		x0 += coordDot(a1, tmp)
+		x1 += coordDot(a2, tmp)
+	}
+
+	chord := coordDist(p0, p3)
+	alpha1, alpha2 := chord/3, chord/3
+	if det := c00*c11 - c01*c01; math.Abs(det) > geomEpsilon {
+		a1 := (x0*c11 - x1*c01) / det
+		a2 := (c00*x1 - c01*x0) / det
+		if a1 > geomEpsilon*chord && a2 > geomEpsilon*chord {
+			alpha1, alpha2 = a1, a2
+		}
+	}
+
+	return segment{
+		kind:  'C',
+		start: p0,
+		ctrl1: coordAdd(p0, coordScale(t1, alpha1)),
+		ctrl2: coordAdd(p3, coordScale(t2, alpha2)),
+		end:   p3,
+	}
+}
+
+// cubicFitError returns the largest distance between the curve and the points
+// or the midpoints of the lines between them.
+func cubicFitError(s segment, pts []geom.Coord, u []float64) float64 {
+	e := 0.0
+	for i, p := range pts {
+		e = math.Max(e, coordDist(s.point(u[i]), p))
+		if i > 0 {
+			mid := coordLerp(pts[i-1], p, 0.5)
+			e = math.Max(e, coordDist(s.point((u[i-1]+u[i])/2), mid))
+		}
+	}
+	return e
+}
+
+// newtonReparameterize improves the parameter t for the point on s closest to
+// p with a step of Newton's method.
+func newtonReparameterize(s segment, p geom.Coord, t float64) float64 {
+	d := coordSub(s.point(t), p)
+	d1 := s.derivative(t)
+	d2 := s.cubic().SecondDerivative(t)
+	den := coordDot(d1, d1) + coordDot(d, d2)
+	if den == 0 {
+		return t
+	}
+	return math.Max(0, math.Min(1, t-coordDot(d, d1)/den))
+}
@@ -0,0 +1,88 @@
+// Copyright 2018 Joe Beda
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package svgdata
+
+import (
+	"math"
+	"testing"
+
+	"github.com/jbeda/geom"
+	"github.com/stretchr/testify/assert"
+)
+
+// maxDeviation returns the largest distance from the flattened b to a.
+func maxDeviation(a, b []SubPath) float64 {
+	d := 0.0
+	pas := FlattenSubPaths(a, 1e-4)
+	for i, pb := range FlattenSubPaths(b, 1e-4) {
+		for _, p := range pb.Points {
+			d = math.Max(d, polylineDistTo(pas[i], p))
+		}
+	}
+	return d
+}
+
+func TestSimplifyLines(t *testing.T) {
+	assert := assert.New(t)
+
+	sps := mustParsePath(t, "M0,0 L1,0 L2,0.001 L3,0 L4,0 L4,1 L4,2 L4,3 L2,3 Z")
+	r := SimplifySubPaths(sps, SimplifyOptions{Tolerance: 0.01})
+	assert.Equal("M0 0L4 0L4 3L2 3Z", SavePathString(r))
+
+	r = SimplifySubPaths(sps, SimplifyOptions{Tolerance: 0.0006})
+	assert.Equal("M0 0L2 0.001L4 0L4 3L2 3Z", SavePathString(r))
+
+	// Curves are left alone and break up runs of lines.
+	sps = mustParsePath(t, "M0,0 L1,0 L2,0 Q3,1 4,0 L5,0 L6,0")
+	r = SimplifySubPaths(sps, SimplifyOptions{Tolerance: 0.01})
+	assert.Equal("M0 0L2 0Q3 1 4 0L6 0", SavePathString(r))
+}
+
+func TestSimplifyFitArcs(t *testing.T) {
+	assert := assert.New(t)
+
+	// A rounded slot flattened into many short lines.
+	orig := mustParsePath(t, "M0,0 h50 A10,10 0 0 1 50,20 h-50 A10,10 0 0 1 0,0 z")
+	flat := []SubPath{orig[0].Flatten(0.001).ToSubPath()}
+	assert.True(len(flat[0].Commands) > 100)
+
+	r := SimplifySubPaths(flat, SimplifyOptions{Tolerance: 0.01, FitArcs: true})
+	assert.Equal(2, countCommands(r, 'A'))
+	assert.True(len(r[0].Commands) <= 7)
+	assert.True(maxDeviation(flat, r) <= 0.01)
+	assert.True(maxDeviation(r, flat) <= 0.01)
+	assert.InDelta(flat[0].SignedArea(), r[0].SignedArea(), 0.5)
+}
+
+func TestSimplifyFitCurves(t *testing.T) {
+	assert := assert.New(t)
+
+	orig := mustParsePath(t, "M0,0 C20,40 60,-40 80,0 L100,0")
+	flat := []SubPath{orig[0].Flatten(0.001).ToSubPath()}
+
+	r := SimplifySubPaths(flat, SimplifyOptions{Tolerance: 0.05, FitCurves: true})
+	assert.True(countCommands(r, 'C') >= 1)
+	assert.Equal(1, countCommands(r, 'L'))
+	assert.True(len(r[0].Commands) < len(flat[0].Commands)/4)
+	assert.True(maxDeviation(flat, r) <= 0.05)
+}
+
+func TestDouglasPeucker(t *testing.T) {
+	assert := assert.New(t)
+
+	pts := []geom.Coord{{X: 0, Y: 0}, {X: 1, Y: 0.5}, {X: 2, Y: 0}, {X: 3, Y: 0}}
+	assert.Equal([]geom.Coord{{X: 0, Y: 0}, {X: 1, Y: 0.5}, {X: 2, Y: 0}, {X: 3, Y: 0}}, douglasPeucker(pts, 0.1))
+	assert.Equal([]geom.Coord{{X: 0, Y: 0}, {X: 3, Y: 0}}, douglasPeucker(pts, 1))
+}
@@ -0,0 +1,329 @@
+// Copyright 2018 Joe Beda
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package svgdata
+
+import (
+	"math"
+
+	"github.com/jbeda/geom"
+	"github.com/pkg/errors"
+)
+
+// LineCap is the shape drawn at the ends of open subpaths when stroking.
+type LineCap int
+
+const (
+	CapButt LineCap = iota
+	CapRound
+	CapSquare
+)
+
+// StrokeOptions describe the stroke to convert to an outline.
+type StrokeOptions struct {
+	Width float64
+	Cap   LineCap
+	Join  JoinStyle
+
+	// MiterLimit is the largest ratio between the length of a miter and the
+	// stroke width.  Zero means the SVG default of 4.
+	MiterLimit float64
+
+	// Tolerance is the largest distance curves may deviate when flattened.
+	// Circular arcs in the outline are kept as arcs.
+	Tolerance float64
+}
+
+// StrokeOptionsFromStyle returns the options that match the stroke properties
+// of st.
+func StrokeOptionsFromStyle(st Style, tol float64) StrokeOptions {
+	return StrokeOptions{
+		Width:      st.StrokeWidth(),
+		Cap:        st.LineCap(),
+		Join:       st.LineJoin(),
+		MiterLimit: st.MiterLimit(),
+		Tolerance:  tol,
+	}
+}
+
+// StrokeSubPaths returns the outline of the area covered by stroking sps.
+// Each segment, join and cap is outlined separately and the pieces are
+// unioned so that the result has no overlaps and fills correctly with either
+// fill rule.  Zero length subpaths draw a dot with round and square caps as
+// they do in SVG.
+func StrokeSubPaths(sps []SubPath, opts StrokeOptions) []SubPath {
+	sk := strokePieces(sps, opts)
+	if sk == nil {
+		return nil
+	}
+	pls := clipRegions(Union, sk.pieces, nil)
+	return fitArcs(pls, sk.circles, sk.tol)
+}
+
+// strokePieces outlines each segment, join and cap of the stroke separately.
+// nil is returned if the stroke has no width.
+func strokePieces(sps []SubPath, opts StrokeOptions) *stroker {
+	if opts.MiterLimit <= 0 {
+		opts.MiterLimit = defaultMiterLimit
+	}
+	h := opts.Width / 2
+	if h <= 0 {
+		return nil
+	}
+
+	sk := &stroker{h: h, opts: opts, tol: math.Max(opts.Tolerance, minTolerance)}
+	for _, sp := range sps {
+		sk.subPath(sp)
+	}
+	return sk
+}
+
+// StrokeShape converts the stroke of s into a filled path using the stroke
+// properties set directly on s.  The new path carries over the attributes of
+// s with its fill set to the stroke paint and the stroke properties removed.
+// Dashed strokes produce one outline per dash.  An invalid dash array is
+// ignored and the stroke is solid.
+func StrokeShape(s Shape, tol float64) *Path {
+	return strokeToPath(s, NodeStyle(s), IdentityTransform, tol)
+}
+
+// StrokeOutline converts the stroke of n, which must be a shape in r, into a
+// filled path.  Unlike StrokeShape, inherited stroke properties and
+// transforms are taken into account and the result is in the user space of
+// r.  The path is not added to the document.
+func (r *Root) StrokeOutline(n Node, tol float64) (*Path, error) {
+	s, ok := n.(Shape)
+	if !ok {
+		return nil, errors.Errorf("node %s is not a shape", n.Name())
+	}
+	ctxs, err := nodeContexts(r)
+	if err != nil {
+		return nil, err
+	}
+	ctx, ok := ctxs[n]
+	if !ok {
+		return nil, errors.Errorf("node %s is not part of the document", n.Name())
+	}
+	p := strokeToPath(s, ctx.style, ctx.ctm, tol)
+	delete(p.attrs, "transform")
+	delete(p.attrs, "id")
+	return p, nil
+}
+
+var strokeProperties = []string{
+	"stroke", "stroke-width", "stroke-linecap", "stroke-linejoin", "stroke-miterlimit",
+	"stroke-dasharray", "stroke-dashoffset", "stroke-opacity", "fill-rule",
+}
+
+func strokeToPath(s Shape, st Style, ctm Transform, tol float64) *Path {
+	opts := StrokeOptionsFromStyle(st, tol/math.Max(ctm.Scale(), geomEpsilon))
+	sps, dashed := dashShape(s, st)
+	if !dashed {
+		sps = s.ToSubPaths()
+	}
+	sps = StrokeSubPaths(sps, opts)
+	if !ctm.IsIdentity() {
+		var cmds []PathCommand
+		for _, sp := range sps {
+			segs := subPathSegments(sp)
+			for i := range segs {
+				segs[i] = segs[i].transform(ctm)
+			}
+			cmds = append(cmds, segmentsToCommands(segs, true)...)
+		}
+		sps = BuildSubPaths(cmds)
+	}
+
+	p := newPathFromNode(s, sps)
+	fill := st.Get("stroke", "none")
+	opacity, hasOpacity := st["stroke-opacity"]
+	for _, k := range strokeProperties {
+		p.attrs.DeleteStyle(k)
+	}
+	p.attrs.DeleteStyle("fill-opacity")
+	p.attrs.SetStyle("fill", fill)
+	p.attrs.SetStyle("stroke", "none")
+	if hasOpacity {
+		p.attrs.SetStyle("fill-opacity", opacity)
+	}
+	return p
+}
+
+// stroker collects the pieces that make up a stroke.
+type stroker struct {
+	h       float64 // Half the stroke width
+	opts    StrokeOptions
+	tol     float64
+	pieces  []region
+	circles []circle
+}
+
+func (sk *stroker) add(pts ...geom.Coord) {
+	if len(pts) > 2 {
+		sk.pieces = append(sk.pieces, region{pls: []Polyline{{Points: pts, Closed: true}}})
+	}
+}
+
+func (sk *stroker) subPath(sp SubPath) {
+	var segs []segment
+	for _, s := range subPathSegments(sp) {
+		if s.start != s.end || s.kind != 'L' {
+			segs = append(segs, s)
+		}
+	}
+	if len(segs) == 0 {
+		if len(sp.Commands) > 0 {
+			sk.dot(sp.Start())
+		}
+		return
+	}
+
+	for i, s := range segs {
+		sk.body(s)
+		if i > 0 {
+			sk.join(s.start, segs[i-1].tangent(1), s.tangent(0), sk.opts.Join)
+		}
+	}
+	first, last := segs[0], segs[len(segs)-1]
+	if sp.IsClosed() {
+		sk.join(first.start, last.tangent(1), first.tangent(0), sk.opts.Join)
+		return
+	}
+	sk.cap(first.start, coordScale(first.tangent(0), -1))
+	sk.cap(last.end, last.tangent(1))
+}
+
+// body adds the area swept by the segment.
+func (sk *stroker) body(s segment) {
+	h := sk.h
+	switch s.kind {
+	case 'L':
+		n := coordScale(coordPerp(coordUnit(coordSub(s.end, s.start))), h)
+		sk.add(coordAdd(s.start, n), coordAdd(s.end, n), coordSub(s.end, n), coordSub(s.start, n))
+		return
+	case 'A':
+		if ea := s.ellipse(); ea.isCircular() {
+			sk.arcBody(s, ea)
+			return
+		}
+	}
+
+	// Sweep the flattened curve.  The joins between the pieces are round as
+	// the curve is smooth there.
+	pts := s.flatten(sk.tol, []geom.Coord{s.start})
+	var prev geom.Coord
+	hasPrev := false
+	for i := 1; i < len(pts); i++ {
+		if pts[i] == pts[i-1] {
+			continue
+		}
+		dir := coordUnit(coordSub(pts[i], pts[i-1]))
+		if hasPrev {
+			sk.join(pts[i-1], prev, dir, JoinRound)
+		}
+		n := coordScale(coordPerp(dir), h)
+		sk.add(coordAdd(pts[i-1], n), coordAdd(pts[i], n), coordSub(pts[i], n), coordSub(pts[i-1], n))
+		prev, hasPrev = dir, true
+	}
+}
+
+// arcBody adds the ring sector swept by a circular arc.
+func (sk *stroker) arcBody(s segment, ea ellipseArc) {
+	h, r := sk.h, ea.rx
+	d := coordSub(s.start, ea.center)
+	a0 := math.Atan2(d.Y, d.X)
+	steps := ellipseArc{rx: r + h, ry: r + h, delta: ea.delta}.steps(sk.tol)
+	at := func(radius float64, i int) geom.Coord {
+		a := a0 + ea.delta*float64(i)/float64(steps)
+		return geom.Coord{X: ea.center.X + radius*math.Cos(a), Y: ea.center.Y + radius*math.Sin(a)}
+	}
+
+	var pts []geom.Coord
+	for i := 0; i <= steps; i++ {
+		pts = append(pts, at(r+h, i))
+	}
+	sk.circles = append(sk.circles, circle{ea.center, r + h})
+	if r-h > sk.tol {
+		for i := steps; i >= 0; i-- {
+			pts = append(pts, at(r-h, i))
+		}
+		sk.circles = append(sk.circles, circle{ea.center, r - h})
+	} else {
+		// The inside of the stroke reaches the center.
+		pts = append(pts, ea.center)
+	}
+	sk.add(pts...)
+}
+
+// join adds the wedge that fills the outside of the corner at v where the
+// direction changes from tin to tout.
+func (sk *stroker) join(v, tin, tout geom.Coord, js JoinStyle) {
+	// Offset towards the outside of the turn.
+	d := sk.h
+	if coordCross(tin, tout) < 0 {
+		d = -d
+	}
+	e := coordAdd(v, coordScale(coordPerp(tin), -d))
+	b := coordAdd(v, coordScale(coordPerp(tout), -d))
+	opts := OffsetOptions{Join: js, MiterLimit: sk.opts.MiterLimit}
+	pts, cs := offsetJoin(v, tin, tout, e, b, d, opts, sk.tol)
+	if len(pts) == 1 && pts[0] == v {
+		// No gap to fill.
+		return
+	}
+	sk.add(append([]geom.Coord{v, e}, append(pts, b)...)...)
+	sk.circles = append(sk.circles, cs...)
+}
+
+// cap adds the cap at the end point p of an open subpath where u points away
+// from the subpath.
+func (sk *stroker) cap(p, u geom.Coord) {
+	h := sk.h
+	n := coordScale(coordPerp(u), h)
+	switch sk.opts.Cap {
+	case CapSquare:
+		f := coordScale(u, h)
+		sk.add(coordAdd(p, n), coordAdd(coordAdd(p, n), f), coordAdd(coordSub(p, n), f), coordSub(p, n))
+	case CapRound:
+		steps := ellipseArc{rx: h, ry: h, delta: math.Pi}.steps(sk.tol)
+		pts := []geom.Coord{p}
+		for i := 0; i <= steps; i++ {
+			a := math.Pi * float64(i) / float64(steps)
+			pts = append(pts, coordAdd(p, coordAdd(coordScale(n, math.Cos(a)), coordScale(u, h*math.Sin(a)))))
+		}
+		sk.add(pts...)
+		sk.circles = append(sk.circles, circle{p, h})
+	}
+}
+
+// dot adds the mark drawn by a zero length subpath at p.
+func (sk *stroker) dot(p geom.Coord) {
+	h := sk.h
+	switch sk.opts.Cap {
+	case CapSquare:
+		sk.add(
+			geom.Coord{X: p.X - h, Y: p.Y - h}, geom.Coord{X: p.X + h, Y: p.Y - h},
+			geom.Coord{X: p.X + h, Y: p.Y + h}, geom.Coord{X: p.X - h, Y: p.Y + h},
+		)
+	case CapRound:
+		ea := ellipseArc{center: p, rx: h, ry: h, delta: 2 * math.Pi}
+		steps := ea.steps(sk.tol)
+		var pts []geom.Coord
+		for i := 0; i < steps; i++ {
+			pts = append(pts, ea.point(ea.delta*float64(i)/float64(steps)))
+		}
+		sk.add(pts...)
+		sk.circles = append(sk.circles, circle{p, h})
+	}
+}
@@ -0,0 +1,147 @@
+// Copyright 2018 Joe Beda
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package svgdata
+
+import (
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/jbeda/geom"
+	"github.com/stretchr/testify/assert"
+)
+
+func strokeArea(t *testing.T, d string, opts StrokeOptions) float64 {
+	a := 0.0
+	for _, sp := range StrokeSubPaths(mustParsePath(t, d), opts) {
+		a += sp.SignedArea()
+	}
+	return a
+}
+
+func TestStrokeCaps(t *testing.T) {
+	assert := assert.New(t)
+
+	opts := StrokeOptions{Width: 2, Tolerance: 0.001}
+	sps := StrokeSubPaths(mustParsePath(t, "M0,0 L10,0"), opts)
+	if assert.Len(sps, 1) {
+		assert.InDelta(20, sps[0].SignedArea(), 1e-9)
+		assert.True(sps[0].IsClosed())
+	}
+
+	opts.Cap = CapSquare
+	assert.InDelta(24, strokeArea(t, "M0,0 L10,0", opts), 1e-9)
+
+	opts.Cap = CapRound
+	sps = StrokeSubPaths(mustParsePath(t, "M0,0 L10,0"), opts)
+	assert.InDelta(20+math.Pi, subPathsArea(sps), 0.01)
+	assert.Equal(2, countCommands(sps, 'A'))
+
+	// Zero length subpaths only show with round or square caps.
+	assert.InDelta(math.Pi, subPathsArea(StrokeSubPaths(mustParsePath(t, "M5,5 Z"), opts)), 0.01)
+	opts.Cap = CapSquare
+	assert.InDelta(4, strokeArea(t, "M5,5 Z", opts), 1e-9)
+	opts.Cap = CapButt
+	assert.Empty(StrokeSubPaths(mustParsePath(t, "M5,5 Z"), opts))
+}
+
+func TestStrokeJoins(t *testing.T) {
+	assert := assert.New(t)
+
+	d := "M0,0 L10,0 L10,10"
+	assert.InDelta(40, strokeArea(t, d, StrokeOptions{Width: 2, Join: JoinMiter}), 1e-9)
+	assert.InDelta(39.5, strokeArea(t, d, StrokeOptions{Width: 2, Join: JoinBevel}), 1e-9)
+	assert.InDelta(39+math.Pi/4, strokeArea(t, d, StrokeOptions{Width: 2, Join: JoinRound, Tolerance: 1e-4}), 1e-3)
+
+	// A sharp corner is beveled unless the miter limit allows the spike.
+	sharp := "M0,0 L10,0 L0,1"
+	bounds := func(opts StrokeOptions) geom.Rect {
+		return pointsBounds(FlattenSubPaths(StrokeSubPaths(mustParsePath(t, sharp), opts), 0.01)[0].Points)
+	}
+	assert.True(bounds(StrokeOptions{Width: 1}).Max.X < 10.6)
+	assert.True(bounds(StrokeOptions{Width: 1, MiterLimit: 100}).Max.X > 15)
+}
+
+func TestStrokeClosed(t *testing.T) {
+	assert := assert.New(t)
+
+	sps := StrokeSubPaths(mustParsePath(t, "M0,0 h10 v10 h-10 z"), StrokeOptions{Width: 2})
+	if assert.Len(sps, 2) {
+		assert.InDelta(80, sps[0].SignedArea()+sps[1].SignedArea(), 1e-9)
+	}
+
+	// A circle becomes a ring of arcs.
+	circle := NewCircle(geom.Coord{X: 0, Y: 0}, 5).ToSubPaths()
+	sps = StrokeSubPaths(circle, StrokeOptions{Width: 2, Tolerance: 0.001})
+	if assert.Len(sps, 2) {
+		assert.Equal(0, countCommands(sps, 'L'))
+		assert.InDelta(20*math.Pi, math.Abs(sps[0].SignedArea()+sps[1].SignedArea()), 1e-6)
+	}
+}
+
+func TestStrokeCurve(t *testing.T) {
+	assert := assert.New(t)
+
+	// With butt caps and gentle curvature the area is the length times the
+	// width.
+	d := "M0,0 C0,10 10,10 10,0"
+	l := mustParsePath(t, d)[0].Length()
+	assert.InDelta(l, strokeArea(t, d, StrokeOptions{Width: 1, Tolerance: 1e-4}), 0.01)
+}
+
+func TestStrokeShape(t *testing.T) {
+	assert := assert.New(t)
+
+	r, err := Unmarshal([]byte(`<svg xmlns="http://www.w3.org/2000/svg">
+  <g stroke="red" stroke-width="2" transform="scale(2)">
+    <path id="a" d="M0,0 L10,0" fill="blue" style="stroke-linecap: square; stroke-opacity: 0.5"/>
+  </g>
+</svg>`))
+	assert.NoError(err)
+	a := FindByID(r, "a")
+
+	p, err := r.StrokeOutline(a, 0.001)
+	assert.NoError(err)
+	assert.InDelta(96, subPathsArea(p.SubPaths), 1e-6)
+	assert.Equal("red", p.Attrs()["fill"])
+	assert.Equal("0.5", p.Attrs()["fill-opacity"])
+	assert.Equal("none", p.Attrs()["stroke"])
+	assert.False(strings.Contains(p.Attrs()["style"], "stroke"))
+	assert.Empty(p.Attrs()["id"])
+
+	// Without the document only the node's own properties apply.
+	p = StrokeShape(a.(Shape), 0.001)
+	assert.InDelta(11, subPathsArea(p.SubPaths), 1e-6)
+	assert.Equal("none", p.Attrs()["fill"])
+	assert.Equal("a", p.Attrs()["id"])
+
+	_, err = r.StrokeOutline(NewCircle(geom.Coord{}, 1), 0.001)
+	assert.Error(err)
+
+	// Each dash is outlined separately.
+	r, err = Unmarshal([]byte(`<svg xmlns="http://www.w3.org/2000/svg">
+  <path id="a" d="M0 0H100" stroke="red" stroke-width="2" stroke-dasharray="10 10"/>
+  <path id="b" d="M0 0H100" stroke="red" stroke-width="2" stroke-dasharray="10 10" stroke-dashoffset="5"/>
+</svg>`))
+	assert.NoError(err)
+	p = StrokeShape(FindByID(r, "a").(Shape), 0.001)
+	assert.Len(p.SubPaths, 5)
+	assert.InDelta(100, subPathsArea(p.SubPaths), 1e-6)
+	assert.Empty(p.Attrs()["stroke-dasharray"])
+	p, err = r.StrokeOutline(FindByID(r, "b"), 0.001)
+	assert.NoError(err)
+	assert.Len(p.SubPaths, 6)
+	assert.InDelta(100, subPathsArea(p.SubPaths), 1e-6)
+}
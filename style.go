@@ -0,0 +1,323 @@
+// Copyright 2018 Joe Beda
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package svgdata
+
+import (
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// inheritedProperties are the presentation properties that pass from a node to
+// its children.
+var inheritedProperties = []string{
+	"fill",
+	"fill-opacity",
+	"fill-rule",
+	"stroke",
+	"stroke-dasharray",
+	"stroke-dashoffset",
+	"stroke-linecap",
+	"stroke-linejoin",
+	"stroke-miterlimit",
+	"stroke-opacity",
+	"stroke-width",
+	"visibility",
+}
+
+// nonRenderedElements are elements whose children are never drawn directly.
+var nonRenderedElements = map[string]bool{
+	"clipPath": true,
+	"defs":     true,
+	"marker":   true,
+	"mask":     true,
+	"pattern":  true,
+	"style":    true,
+	"symbol":   true,
+}
+
+// groupingElements are the elements other than shapes that are drawn by
+// drawing their children.
+var groupingElements = map[string]bool{"g": true, "a": true}
+
+// Style is the set of presentation properties in effect for a node once
+// inheritance has been applied.
+type Style map[string]string
+
+// inherit returns the style for a child node n of a node with style s.
+func (s Style) inherit(n Node) Style {
+	r := Style{}
+	for _, k := range inheritedProperties {
+		if v, ok := s[k]; ok {
+			r[k] = v
+		}
+	}
+	am := n.Attrs()
+	for _, k := range inheritedProperties {
+		if v, ok := am.GetStyle(k); ok && v != "inherit" {
+			r[k] = v
+		}
+	}
+	if v, ok := am.GetStyle("opacity"); ok {
+		r["opacity"] = v
+	}
+	return r
+}
+
+// Get returns the value of a property or def if it isn't set.
+func (s Style) Get(k, def string) string {
+	if v, ok := s[k]; ok {
+		return v
+	}
+	return def
+}
+
+// FillRule returns the fill rule in effect, either "nonzero" or "evenodd".
+func (s Style) FillRule() string {
+	if s.Get("fill-rule", "nonzero") == "evenodd" {
+		return "evenodd"
+	}
+	return "nonzero"
+}
+
+// HasFill returns true if the fill is painted.
+func (s Style) HasFill() bool {
+	return !isNonePaint(s.Get("fill", "black"))
+}
+
+// HasStroke returns true if the stroke is painted.
+func (s Style) HasStroke() bool {
+	return !isNonePaint(s.Get("stroke", "none")) && s.StrokeWidth() > 0
+}
+
+// StrokeWidth returns the stroke width in user units.
+func (s Style) StrokeWidth() float64 {
+	w, err := parseValue(s.Get("stroke-width", "1"))
+	if err != nil {
+		return 1
+	}
+	return w
+}
+
+// LineCap returns the value of stroke-linecap.
+func (s Style) LineCap() LineCap {
+	switch strings.TrimSpace(s.Get("stroke-linecap", "butt")) {
+	case "round":
+		return CapRound
+	case "square":
+		return CapSquare
+	}
+	return CapButt
+}
+
+// LineJoin returns the value of stroke-linejoin.  The SVG 2 values
+// miter-clip and arcs are treated as miter.
+func (s Style) LineJoin() JoinStyle {
+	switch strings.TrimSpace(s.Get("stroke-linejoin", "miter")) {
+	case "round":
+		return JoinRound
+	case "bevel":
+		return JoinBevel
+	}
+	return JoinMiter
+}
+
+// MiterLimit returns the value of stroke-miterlimit.
+func (s Style) MiterLimit() float64 {
+	v, err := strconv.ParseFloat(strings.TrimSpace(s.Get("stroke-miterlimit", "4")), 64)
+	if err != nil || v < 1 {
+		return defaultMiterLimit
+	}
+	return v
+}
+
+// DashArray returns the lengths in stroke-dasharray in user units or nil if
+// the stroke is solid.  Values that can't be parsed, such as percentages,
+// are ignored and the stroke is solid.
+func (s Style) DashArray() []float64 {
+	v := strings.TrimSpace(s.Get("stroke-dasharray", "none"))
+	if v == "none" || v == "" {
+		return nil
+	}
+	var r []float64
+	for _, f := range strings.FieldsFunc(v, func(c rune) bool { return c == ',' || unicode.IsSpace(c) }) {
+		d, err := parseValue(f)
+		if err != nil {
+			return nil
+		}
+		r = append(r, d)
+	}
+	return r
+}
+
+// DashOffset returns stroke-dashoffset in user units.  Values that can't be
+// parsed are ignored and the offset is zero.
+func (s Style) DashOffset() float64 {
+	d, err := parseValue(strings.TrimSpace(s.Get("stroke-dashoffset", "0")))
+	if err != nil {
+		return 0
+	}
+	return d
+}
+
+func isNonePaint(v string) bool {
+	v = strings.TrimSpace(v)
+	return v == "none" || v == "transparent"
+}
+
+// NodeStyle returns the style of a single node without anything inherited
+// from its parents.
+func NodeStyle(n Node) Style {
+	return Style{}.inherit(n)
+}
+
+// paintFunc is called for each rendered node with the transform from the
+// node's coordinates to the root's user space and the node's style.
+type paintFunc func(n Node, ctm Transform, st Style) error
+
+// walkPaint visits the rendered nodes under n in paint order, tracking the
+// current transform and inherited style.  Nodes that are hidden with display
+// none are skipped along with their children.
+func walkPaint(n Node, ctm Transform, st Style, fn paintFunc) error {
+	for _, c := range *n.Children() {
+		if nonRenderedElements[c.Name()] {
+			continue
+		}
+		if v, _ := c.Attrs().GetStyle("display"); v == "none" {
+			continue
+		}
+
+		t, err := NodeTransform(c)
+		if err != nil {
+			return err
+		}
+		cctm := ctm.Multiply(t)
+		cst := st.inherit(c)
+
+		if err := fn(c, cctm, cst); err != nil {
+			return err
+		}
+		if err := walkPaint(c, cctm, cst, fn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// groupFunc is called for each rendered child by walkGroups with the child's
+// own transform, its style and its opacity, which is above zero.
+type groupFunc func(n Node, t Transform, st Style, opacity float64) error
+
+// walkGroups visits the rendered children of n like walkPaint but doesn't
+// descend into them.  fn draws each child and its children itself, which lets
+// a group with opacity be drawn as a whole so that overlapping parts don't
+// show through each other.  Children that are fully transparent are skipped.
+func walkGroups(n Node, st Style, fn groupFunc) error {
+	for _, c := range *n.Children() {
+		if nonRenderedElements[c.Name()] {
+			continue
+		}
+		if v, _ := c.Attrs().GetStyle("display"); v == "none" {
+			continue
+		}
+		cst := st.inherit(c)
+		opacity := styleOpacity(cst, "opacity")
+		if opacity <= 0 {
+			continue
+		}
+		t, err := NodeTransform(c)
+		if err != nil {
+			return err
+		}
+		if err := fn(c, t, cst, opacity); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// layerFunc is called for each rendered node like paintFunc along with the
+// child of the root that contains it.
+type layerFunc func(layer, n Node, ctm Transform, st Style) error
+
+// walkLayers visits the rendered nodes under r in paint order like walkPaint,
+// also passing the child of r that each one is under since drawing programs
+// use top level groups as layers.  Top level children hidden with display
+// none are still visited so callers can decide what to do with them.
+func walkLayers(r *Root, fn layerFunc) error {
+	rst := NodeStyle(r)
+	for _, c := range *r.Children() {
+		if nonRenderedElements[c.Name()] {
+			continue
+		}
+		t, err := NodeTransform(c)
+		if err != nil {
+			return err
+		}
+		layer := c
+		visit := func(n Node, ctm Transform, st Style) error {
+			return fn(layer, n, ctm, st)
+		}
+		st := rst.inherit(c)
+		if err := visit(c, t, st); err != nil {
+			return err
+		}
+		if err := walkPaint(c, t, st, visit); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// layerID returns the id of a top level group, or "" if layer is a shape
+// that isn't in a group or has no id.
+func layerID(layer Node) string {
+	if _, ok := layer.(Shape); ok {
+		return ""
+	}
+	return layer.Attrs()["id"]
+}
+
+// nodeContext is the transform to the root's user space and the inherited
+// style of a node.
+type nodeContext struct {
+	ctm   Transform
+	style Style
+}
+
+// nodeContexts computes the context of every node under r, including r
+// itself.
+func nodeContexts(r Node) (map[Node]nodeContext, error) {
+	ctxs := map[Node]nodeContext{r: {ctm: IdentityTransform, style: NodeStyle(r)}}
+	var visit func(n Node) error
+	visit = func(n Node) error {
+		pctx := ctxs[n]
+		for _, c := range *n.Children() {
+			t, err := NodeTransform(c)
+			if err != nil {
+				return err
+			}
+			ctxs[c] = nodeContext{ctm: pctx.ctm.Multiply(t), style: pctx.style.inherit(c)}
+			if err := visit(c); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if err := visit(r); err != nil {
+		return nil, err
+	}
+	return ctxs, nil
+}
@@ -0,0 +1,216 @@
+// Copyright 2018 Joe Beda
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package svgdata
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strings"
+
+	"github.com/jbeda/geom"
+	"github.com/pkg/errors"
+)
+
+// Transform is an affine transform laid out the same way as the SVG matrix
+// transform function:
+//
+//	| A C E |
+//	| B D F |
+//	| 0 0 1 |
+type Transform struct {
+	A, B, C, D, E, F float64
+}
+
+// IdentityTransform leaves every point where it is.
+var IdentityTransform = Transform{A: 1, D: 1}
+
+func NewTranslate(x, y float64) Transform {
+	return Transform{A: 1, D: 1, E: x, F: y}
+}
+
+func NewScale(x, y float64) Transform {
+	return Transform{A: x, D: y}
+}
+
+// NewRotate creates a rotation by deg degrees around the origin.
+func NewRotate(deg float64) Transform {
+	s, c := math.Sincos(deg * math.Pi / 180)
+	return Transform{A: c, B: s, C: -s, D: c}
+}
+
+// Apply transforms a point.
+func (t Transform) Apply(p geom.Coord) geom.Coord {
+	return geom.Coord{
+		X: t.A*p.X + t.C*p.Y + t.E,
+		Y: t.B*p.X + t.D*p.Y + t.F,
+	}
+}
+
+// ApplyVector transforms a direction, ignoring any translation.
+func (t Transform) ApplyVector(v geom.Coord) geom.Coord {
+	return geom.Coord{
+		X: t.A*v.X + t.C*v.Y,
+		Y: t.B*v.X + t.D*v.Y,
+	}
+}
+
+// Multiply returns the transform that applies o and then t.
+func (t Transform) Multiply(o Transform) Transform {
+	return Transform{
+		A: t.A*o.A + t.C*o.B,
+		B: t.B*o.A + t.D*o.B,
+		C: t.A*o.C + t.C*o.D,
+		D: t.B*o.C + t.D*o.D,
+		E: t.A*o.E + t.C*o.F + t.E,
+		F: t.B*o.E + t.D*o.F + t.F,
+	}
+}
+
+// Invert returns the inverse transform.  false is returned if the transform
+// is not invertible.
+func (t Transform) Invert() (Transform, bool) {
+	det := t.A*t.D - t.B*t.C
+	if det == 0 {
+		return Transform{}, false
+	}
+	return Transform{
+		A: t.D / det,
+		B: -t.B / det,
+		C: -t.C / det,
+		D: t.A / det,
+		E: (t.C*t.F - t.D*t.E) / det,
+		F: (t.B*t.E - t.A*t.F) / det,
+	}, true
+}
+
+// IsIdentity returns true if the transform leaves every point where it is.
+func (t Transform) IsIdentity() bool {
+	return t == IdentityTransform
+}
+
+// Scale returns the geometric mean of the scale factors of the transform.
+// This is how lengths such as stroke widths are scaled.
+func (t Transform) Scale() float64 {
+	return math.Sqrt(math.Abs(t.A*t.D - t.B*t.C))
+}
+
+// String formats the transform as an SVG matrix function.
+func (t Transform) String() string {
+	return fmt.Sprintf("matrix(%s %s %s %s %s %s)",
+		floatToString(t.A), floatToString(t.B), floatToString(t.C),
+		floatToString(t.D), floatToString(t.E), floatToString(t.F))
+}
+
+var transformRE *regexp.Regexp = regexp.MustCompile(`^\s*,?\s*([a-zA-Z]+)\s*\(([^)]*)\)`)
+
+// ParseTransform parses the value of a transform attribute.
+func ParseTransform(s string) (Transform, error) {
+	r := IdentityTransform
+	for strings.TrimLeft(s, " \t\r\n,") != "" {
+		caps := transformRE.FindStringSubmatch(s)
+		if caps == nil {
+			return Transform{}, errors.Errorf("Unparsable transform: %s", s)
+		}
+		s = s[len(caps[0]):]
+
+		args, err := parseNumberList(caps[2])
+		if err != nil {
+			return Transform{}, err
+		}
+
+		t, err := makeTransform(caps[1], args)
+		if err != nil {
+			return Transform{}, err
+		}
+		r = r.Multiply(t)
+	}
+	return r, nil
+}
+
+func makeTransform(name string, args []float64) (Transform, error) {
+	argErr := errors.Errorf("Wrong number of arguments to %s: %d", name, len(args))
+	switch name {
+	case "matrix":
+		if len(args) != 6 {
+			return Transform{}, argErr
+		}
+		return Transform{args[0], args[1], args[2], args[3], args[4], args[5]}, nil
+	case "translate":
+		switch len(args) {
+		case 1:
+			return NewTranslate(args[0], 0), nil
+		case 2:
+			return NewTranslate(args[0], args[1]), nil
+		}
+	case "scale":
+		switch len(args) {
+		case 1:
+			return NewScale(args[0], args[0]), nil
+		case 2:
+			return NewScale(args[0], args[1]), nil
+		}
+	case "rotate":
+		switch len(args) {
+		case 1:
+			return NewRotate(args[0]), nil
+		case 3:
+			return NewTranslate(args[1], args[2]).
+				Multiply(NewRotate(args[0])).
+				Multiply(NewTranslate(-args[1], -args[2])), nil
+		}
+	case "skewX":
+		if len(args) == 1 {
+			return Transform{A: 1, C: math.Tan(args[0] * math.Pi / 180), D: 1}, nil
+		}
+	case "skewY":
+		if len(args) == 1 {
+			return Transform{A: 1, B: math.Tan(args[0] * math.Pi / 180), D: 1}, nil
+		}
+	default:
+		return Transform{}, errors.Errorf("Unknown transform: %s", name)
+	}
+	return Transform{}, argErr
+}
+
+// parseNumberList parses a list of numbers separated by whitespace or commas.
+func parseNumberList(s string) ([]float64, error) {
+	pts, err := pathTokenize(s)
+	if err != nil {
+		return nil, err
+	}
+
+	var r []float64
+	for _, t := range pts {
+		switch tt := t.(type) {
+		case numberToken:
+			r = append(r, tt.number)
+		case eodToken:
+		default:
+			return nil, errors.Errorf("Unexpected value in number list: %s", s)
+		}
+	}
+	return r, nil
+}
+
+// NodeTransform returns the transform specified on a node.  The identity
+// transform is returned if there isn't one.
+func NodeTransform(n Node) (Transform, error) {
+	s, ok := n.Attrs()["transform"]
+	if !ok {
+		return IdentityTransform, nil
+	}
+	return ParseTransform(s)
+}
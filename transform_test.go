@@ -0,0 +1,56 @@
+// Copyright 2018 Joe Beda
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package svgdata
+
+import (
+	"testing"
+
+	"github.com/jbeda/geom"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseTransform(t *testing.T) {
+	assert := assert.New(t)
+
+	tr, err := ParseTransform("")
+	assert.NoError(err)
+	assert.True(tr.IsIdentity())
+
+	tr, err = ParseTransform("translate(10) scale(2, 3)")
+	assert.NoError(err)
+	assert.Equal(geom.Coord{X: 12, Y: 3}, tr.Apply(geom.Coord{X: 1, Y: 1}))
+
+	tr, err = ParseTransform("rotate(90 10,10)")
+	assert.NoError(err)
+	p := tr.Apply(geom.Coord{X: 20, Y: 10})
+	assert.InDelta(10, p.X, 1e-9)
+	assert.InDelta(20, p.Y, 1e-9)
+
+	tr, err = ParseTransform("matrix(1,2,3,4,5,6)")
+	assert.NoError(err)
+	assert.Equal(Transform{1, 2, 3, 4, 5, 6}, tr)
+	inv, ok := tr.Invert()
+	assert.True(ok)
+	p = inv.Apply(tr.Apply(geom.Coord{X: 7, Y: -3}))
+	assert.InDelta(7, p.X, 1e-9)
+	assert.InDelta(-3, p.Y, 1e-9)
+
+	_, err = ParseTransform("rotate(1,2)")
+	assert.Error(err)
+	_, err = ParseTransform("spin(1)")
+	assert.Error(err)
+	_, err = ParseTransform("translate(1")
+	assert.Error(err)
+}
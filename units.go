@@ -18,7 +18,9 @@ import (
 	"fmt"
 	"regexp"
 	"strconv"
+	"strings"
 
+	"github.com/jbeda/geom"
 	"github.com/pkg/errors"
 )
 
@@ -62,3 +64,47 @@ func parseValue(s string) (float64, error) {
 		return 0, fmt.Errorf("Unknown unit: %s", u)
 	}
 }
+
+// documentViewport returns the area of user space shown by r and the size of
+// one user unit in pixels.  Without a viewBox user units are pixels and the
+// area is given by the width and height, if they are set.
+func documentViewport(r *Root) (geom.Rect, float64, error) {
+	size := func(k string) (float64, error) {
+		v, ok := r.Attrs()[k]
+		if !ok || strings.HasSuffix(v, "%") {
+			return 0, nil
+		}
+		return parseValue(strings.TrimSpace(v))
+	}
+	w, err := size("width")
+	if err != nil {
+		return geom.Rect{}, 0, err
+	}
+	h, err := size("height")
+	if err != nil {
+		return geom.Rect{}, 0, err
+	}
+
+	vb, ok := r.Attrs()["viewBox"]
+	if !ok {
+		return geom.Rect{Max: geom.Coord{X: w, Y: h}}, 1, nil
+	}
+	nums, err := parseNumberList(vb)
+	if err != nil {
+		return geom.Rect{}, 0, err
+	}
+	if len(nums) != 4 || nums[2] <= 0 || nums[3] <= 0 {
+		return geom.Rect{}, 0, errors.Errorf("invalid viewBox: %s", vb)
+	}
+	rect := geom.Rect{
+		Min: geom.Coord{X: nums[0], Y: nums[1]},
+		Max: geom.Coord{X: nums[0] + nums[2], Y: nums[1] + nums[3]},
+	}
+	switch {
+	case w > 0:
+		return rect, w / nums[2], nil
+	case h > 0:
+		return rect, h / nums[3], nil
+	}
+	return rect, 1, nil
+}
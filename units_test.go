@@ -17,6 +17,7 @@ package svgdata
 import (
 	"testing"
 
+	"github.com/jbeda/geom"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -65,3 +66,32 @@ func TestParseValue(t *testing.T) {
 	p, err = parseValue("1 in")
 	assert.Error(err)
 }
+
+func TestDocumentViewport(t *testing.T) {
+	assert := assert.New(t)
+
+	r := CreateRoot()
+	r.Attrs()["width"] = "100mm"
+	r.Attrs()["height"] = "50mm"
+	r.Attrs()["viewBox"] = "10 20 200 100"
+	vb, px, err := documentViewport(r)
+	assert.NoError(err)
+	assert.Equal(geom.Rect{Min: geom.Coord{X: 10, Y: 20}, Max: geom.Coord{X: 210, Y: 120}}, vb)
+	assert.InDelta(96/25.4/2, px, 1e-12)
+
+	delete(r.Attrs(), "viewBox")
+	vb, px, err = documentViewport(r)
+	assert.NoError(err)
+	assert.InDelta(100*96/25.4, vb.Max.X, 1e-9)
+	assert.Equal(1.0, px)
+
+	r.Attrs()["width"] = "100%"
+	r.Attrs()["viewBox"] = "0 0 10 10"
+	_, px, err = documentViewport(r)
+	assert.NoError(err)
+	assert.InDelta(50*96/25.4/10, px, 1e-12)
+
+	r.Attrs()["viewBox"] = "0 0 10"
+	_, _, err = documentViewport(r)
+	assert.Error(err)
+}
@@ -38,3 +38,9 @@ func NewStyle() Node {
 	u.name = "style"
 	return u
 }
+
+func NewDefs() Node {
+	u := createUnknown()
+	u.name = "defs"
+	return u
+}
@@ -0,0 +1,736 @@
+// Copyright 2018 Joe Beda
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package svgdata
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// AndroidNs is the XML namespace of the attributes in Android resources.
+const AndroidNs = "http://schemas.android.com/apk/res/android"
+
+// MarshalVectorDrawable converts the document to an Android VectorDrawable.
+// Groups and transforms become <group> elements, shapes become <path>
+// elements with their fill and stroke and clip-path references become
+// <clip-path> elements.  Transforms with skew, which groups can't express,
+// are applied to the path data instead.  Dashed strokes are split into
+// dashes.
+//
+// The second result describes each part of the document that couldn't be
+// converted exactly, such as text, gradients and masks.  Those parts are left
+// out or approximated as described.
+func MarshalVectorDrawable(r *Root) ([]byte, []string, error) {
+	size, vt, err := viewportTransform(r)
+	if err != nil {
+		return nil, nil, err
+	}
+	viewport := size
+	if vb, ok := r.Attrs()["viewBox"]; ok {
+		nums, err := parseNumberList(vb)
+		if err != nil {
+			return nil, nil, err
+		}
+		viewport.X, viewport.Y = nums[2], nums[3]
+	}
+
+	w := &vdWriter{root: r}
+	w.b.WriteString(`<vector xmlns:android="` + AndroidNs + `"`)
+	w.attr(1, "width", roundedNumber(size.X)+"dp")
+	w.attr(1, "height", roundedNumber(size.Y)+"dp")
+	w.attr(1, "viewportWidth", roundedNumber(viewport.X))
+	w.attr(1, "viewportHeight", roundedNumber(viewport.Y))
+	st := NodeStyle(r)
+	if o := styleOpacity(st, "opacity"); o < 1 {
+		w.attr(1, "alpha", roundedNumber(o))
+	}
+	w.b.WriteString(">\n")
+
+	// The viewport is stretched to the size of the drawable, so the rest of
+	// the mapping from the viewBox goes in a group.
+	inner := NewScale(viewport.X/size.X, viewport.Y/size.Y).Multiply(vt)
+	if err := w.content(r, inner, IdentityTransform, 1, st, 1); err != nil {
+		return nil, nil, err
+	}
+	w.b.WriteString("</vector>\n")
+	return w.b.Bytes(), w.unsupported, nil
+}
+
+// vdWriter writes VectorDrawable XML and collects descriptions of what
+// couldn't be converted.
+type vdWriter struct {
+	root        *Root
+	b           bytes.Buffer
+	unsupported []string
+}
+
+func (w *vdWriter) report(format string, args ...interface{}) {
+	w.unsupported = append(w.unsupported, fmt.Sprintf(format, args...))
+}
+
+// attr writes an android attribute on its own line as Android Studio does.
+func (w *vdWriter) attr(depth int, name, value string) {
+	w.b.WriteString("\n" + strings.Repeat("    ", depth+1) + "android:" + name + `="`)
+	xml.EscapeText(&w.b, []byte(value))
+	w.b.WriteString(`"`)
+}
+
+// content writes n as seen through transform t.  If t can be a group it is
+// written as one, otherwise it is applied to bake, which is applied to all
+// path data below.  alpha is the opacity inherited from groups.
+func (w *vdWriter) content(n Node, t, bake Transform, alpha float64, st Style, depth int) error {
+	clip, clipped, err := w.clipPath(n, st)
+	if err != nil {
+		return err
+	}
+
+	if _, _, _, _, _, ok := vdDecompose(t); !ok || !bake.IsIdentity() {
+		if !ok && bake.IsIdentity() {
+			w.report("transform on <%s> with skew applied to path data", n.Name())
+		}
+		bake, t = bake.Multiply(t), IdentityTransform
+	}
+	group := clipped || !t.IsIdentity()
+	if group {
+		w.b.WriteString(strings.Repeat("    ", depth) + "<group")
+		if id := n.Attrs()["id"]; id != "" {
+			w.attr(depth, "name", id)
+		}
+		w.groupTransform(t, depth)
+		w.b.WriteString(">\n")
+		depth++
+		if clipped {
+			w.b.WriteString(strings.Repeat("    ", depth) + "<clip-path")
+			w.attr(depth, "pathData", SavePathString(transformSubPaths(clip, bake)))
+			w.b.WriteString("/>\n")
+		}
+	}
+
+	if s, ok := n.(Shape); ok {
+		if err := w.shape(s, bake, alpha, st, depth); err != nil {
+			return err
+		}
+	}
+	if err := w.children(n, bake, alpha, st, depth); err != nil {
+		return err
+	}
+
+	if group {
+		depth--
+		w.b.WriteString(strings.Repeat("    ", depth) + "</group>\n")
+	}
+	return nil
+}
+
+func (w *vdWriter) children(n Node, bake Transform, alpha float64, st Style, depth int) error {
+	return walkGroups(n, st, func(c Node, t Transform, cst Style, opacity float64) error {
+		_, shape := c.(Shape)
+		if !shape && !groupingElements[c.Name()] {
+			w.report("<%s> element not supported", c.Name())
+			return nil
+		}
+		for _, k := range []string{"mask", "filter"} {
+			if v, ok := c.Attrs().GetStyle(k); ok && v != "none" {
+				w.report("%s on <%s> not supported", k, c.Name())
+			}
+		}
+
+		// VectorDrawable groups have no opacity, so it is applied to each
+		// path instead.  That is only exact when there is one path.
+		if opacity < 1 && !shape {
+			paths := 0
+			err := walkPaint(c, IdentityTransform, cst, func(n Node, ctm Transform, st Style) error {
+				if _, ok := n.(Shape); ok {
+					paths++
+				}
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+			if paths > 1 {
+				w.report("opacity on <%s> applied to each path", c.Name())
+			}
+		}
+		return w.content(c, t, bake, alpha*opacity, cst, depth)
+	})
+}
+
+// groupTransform writes the attributes of a group for a transform that
+// vdDecompose accepts.
+func (w *vdWriter) groupTransform(t Transform, depth int) {
+	tx, ty, rot, sx, sy, _ := vdDecompose(t)
+	for _, a := range []struct {
+		name     string
+		v, unset float64
+	}{
+		{"translateX", tx, 0},
+		{"translateY", ty, 0},
+		{"rotation", rot, 0},
+		{"scaleX", sx, 1},
+		{"scaleY", sy, 1},
+	} {
+		if s := roundedNumber(a.v); s != roundedNumber(a.unset) {
+			w.attr(depth, a.name, s)
+		}
+	}
+}
+
+// vdDecompose splits t into the translation, rotation in degrees and scale
+// of a group, which are applied in the order scale, rotate then translate.
+// false is returned if t has skew.
+func vdDecompose(t Transform) (tx, ty, rot, sx, sy float64, ok bool) {
+	sx = math.Hypot(t.A, t.B)
+	if sx == 0 {
+		return 0, 0, 0, 0, 0, false
+	}
+	sy = (t.A*t.D - t.B*t.C) / sx
+	cos, sin := t.A/sx, t.B/sx
+	eps := geomEpsilon * math.Max(1, math.Abs(sy))
+	if math.Abs(t.C+sy*sin) > eps || math.Abs(t.D-sy*cos) > eps {
+		return 0, 0, 0, 0, 0, false
+	}
+	return t.E, t.F, math.Atan2(t.B, t.A) * 180 / math.Pi, sx, sy, true
+}
+
+// clipPath returns the outline of the clip path referenced by n in the user
+// space of n.  false is returned if n isn't clipped.
+func (w *vdWriter) clipPath(n Node, st Style) ([]SubPath, bool, error) {
+	v, ok := n.Attrs().GetStyle("clip-path")
+	if !ok || v == "none" {
+		return nil, false, nil
+	}
+	var cp Node
+	if strings.HasPrefix(v, "url(#") && strings.HasSuffix(v, ")") {
+		cp = FindByID(w.root, v[5:len(v)-1])
+	}
+	if cp == nil || cp.Name() != "clipPath" {
+		w.report("clip-path %s on <%s> not supported", v, n.Name())
+		return nil, false, nil
+	}
+	if cp.Attrs()["clipPathUnits"] == "objectBoundingBox" {
+		w.report("clip-path %s with objectBoundingBox units not supported", v)
+		return nil, false, nil
+	}
+
+	t, err := NodeTransform(cp)
+	if err != nil {
+		return nil, false, err
+	}
+	var sps []SubPath
+	for _, c := range *cp.Children() {
+		s, ok := c.(Shape)
+		if !ok {
+			w.report("<%s> in clip-path %s not supported", c.Name(), v)
+			continue
+		}
+		if NodeStyle(c).Get("clip-rule", st.Get("clip-rule", "nonzero")) == "evenodd" {
+			w.report("clip-rule evenodd in clip-path %s not supported", v)
+		}
+		ct, err := NodeTransform(c)
+		if err != nil {
+			return nil, false, err
+		}
+		sps = append(sps, transformSubPaths(s.ToSubPaths(), t.Multiply(ct))...)
+	}
+	return sps, true, nil
+}
+
+// paint returns the color of a fill or stroke, reporting paints that can't
+// be converted.
+func (w *vdWriter) paint(n Node, k, v string) (string, bool) {
+	if isNonePaint(v) {
+		return "", false
+	}
+	c, ok := parseColor(v)
+	if !ok {
+		w.report("%s %s on <%s> not supported", k, strings.TrimSpace(v), n.Name())
+		return "", false
+	}
+	return fmt.Sprintf("#%02X%02X%02X", c.R, c.G, c.B), true
+}
+
+func (w *vdWriter) shape(s Shape, bake Transform, alpha float64, st Style, depth int) error {
+	if st.Get("visibility", "visible") != "visible" {
+		return nil
+	}
+	for _, k := range []string{"marker-start", "marker-mid", "marker-end"} {
+		if v, ok := s.Attrs().GetStyle(k); ok && v != "none" {
+			w.report("%s on <%s> not supported", k, s.Name())
+		}
+	}
+	if v, _ := s.Attrs().GetStyle("vector-effect"); v == "non-scaling-stroke" {
+		w.report("non-scaling-stroke on <%s> not supported", s.Name())
+	}
+
+	fill, hasFill := w.paint(s, "fill", st.Get("fill", "black"))
+	stroke, hasStroke := w.paint(s, "stroke", st.Get("stroke", "none"))
+	hasStroke = hasStroke && st.StrokeWidth() > 0
+
+	sps := transformSubPaths(s.ToSubPaths(), bake)
+	strokeSps := sps
+	dashes, dashed := dashShape(s, st)
+	if dashed && hasStroke {
+		strokeSps = transformSubPaths(dashes, bake)
+	}
+
+	var fillAttrs, strokeAttrs [][2]string
+	if hasFill {
+		fillAttrs = append(fillAttrs, [2]string{"fillColor", fill})
+		if a := alpha * styleOpacity(st, "fill-opacity"); a < 1 {
+			fillAttrs = append(fillAttrs, [2]string{"fillAlpha", roundedNumber(a)})
+		}
+		if st.FillRule() == "evenodd" {
+			fillAttrs = append(fillAttrs, [2]string{"fillType", "evenOdd"})
+		}
+	}
+	if hasStroke {
+		strokeAttrs = append(strokeAttrs,
+			[2]string{"strokeColor", stroke},
+			[2]string{"strokeWidth", roundedNumber(st.StrokeWidth() * bake.Scale())})
+		if a := alpha * styleOpacity(st, "stroke-opacity"); a < 1 {
+			strokeAttrs = append(strokeAttrs, [2]string{"strokeAlpha", roundedNumber(a)})
+		}
+		if c := st.LineCap(); c != CapButt {
+			strokeAttrs = append(strokeAttrs, [2]string{"strokeLineCap", [...]string{"butt", "round", "square"}[pdfLineCaps[c]]})
+		}
+		if j := st.LineJoin(); j != JoinMiter {
+			strokeAttrs = append(strokeAttrs, [2]string{"strokeLineJoin", [...]string{"miter", "round", "bevel"}[pdfLineJoins[j]]})
+		}
+		if m := st.MiterLimit(); m != defaultMiterLimit {
+			strokeAttrs = append(strokeAttrs, [2]string{"strokeMiterLimit", roundedNumber(m)})
+		}
+	}
+	if !hasFill && !hasStroke {
+		return nil
+	}
+
+	name := s.Attrs()["id"]
+	if dashed && hasStroke && hasFill {
+		w.path(depth, name, sps, fillAttrs)
+		w.path(depth, "", strokeSps, strokeAttrs)
+		return nil
+	}
+	w.path(depth, name, strokeSps, append(fillAttrs, strokeAttrs...))
+	return nil
+}
+
+func (w *vdWriter) path(depth int, name string, sps []SubPath, attrs [][2]string) {
+	d := SavePathString(sps)
+	if d == "" {
+		return
+	}
+	w.b.WriteString(strings.Repeat("    ", depth) + "<path")
+	if name != "" {
+		w.attr(depth, "name", name)
+	}
+	w.attr(depth, "pathData", d)
+	for _, a := range attrs {
+		w.attr(depth, a[0], a[1])
+	}
+	w.b.WriteString("/>\n")
+}
+
+// transformSubPaths returns sps transformed by t.
+func transformSubPaths(sps []SubPath, t Transform) []SubPath {
+	if t.IsIdentity() {
+		return sps
+	}
+	var cmds []PathCommand
+	for _, sp := range sps {
+		segs := subPathSegments(sp)
+		if len(segs) == 0 {
+			p := t.Apply(sp.Start())
+			cmds = append(cmds, PathCommand{Command: 'M', Params: []float64{p.X, p.Y}})
+			continue
+		}
+		for i := range segs {
+			segs[i] = segs[i].transform(t)
+		}
+		cmds = append(cmds, segmentsToCommands(segs, sp.IsClosed())...)
+	}
+	return BuildSubPaths(cmds)
+}
+
+// vdElement is an element of a VectorDrawable.
+type vdElement struct {
+	XMLName  xml.Name
+	Attrs    []xml.Attr  `xml:",any,attr"`
+	Children []vdElement `xml:",any"`
+}
+
+// UnmarshalVectorDrawable converts an Android VectorDrawable to a document.
+// Groups become <g> elements with their transforms, paths become <path>
+// elements with their fill and stroke and <clip-path> elements become
+// clipPath definitions applied to the rest of their group.  The viewport is
+// stretched to the width and height as it is on Android.
+//
+// The second result describes each attribute and element that couldn't be
+// converted, such as tints, trimmed paths, gradients and color resources.
+// Those are left out.
+func UnmarshalVectorDrawable(data []byte) (*Root, []string, error) {
+	var v vdElement
+	if err := xml.Unmarshal(data, &v); err != nil {
+		return nil, nil, errors.Wrap(err, "invalid VectorDrawable")
+	}
+	if v.XMLName.Local != "vector" {
+		return nil, nil, errors.Errorf("expected <vector>, got <%s>", v.XMLName.Local)
+	}
+
+	r := CreateRoot()
+	r.attrs = AttrMap{"xmlns": SvgNs}
+	rd := &vdReader{root: r}
+	attrs := rd.attrs(v)
+	for _, k := range []string{"width", "height"} {
+		d, err := vdDimension(attrs[k])
+		if err != nil {
+			return nil, nil, err
+		}
+		r.Attrs()[k] = d
+	}
+	vw, err := rd.number(v, attrs, "viewportWidth", 0)
+	if err != nil {
+		return nil, nil, err
+	}
+	vh, err := rd.number(v, attrs, "viewportHeight", 0)
+	if err != nil {
+		return nil, nil, err
+	}
+	if vw <= 0 || vh <= 0 {
+		return nil, nil, errors.New("VectorDrawable viewport must be positive")
+	}
+	r.Attrs()["viewBox"] = "0 0 " + roundedNumber(vw) + " " + roundedNumber(vh)
+	r.Attrs()["preserveAspectRatio"] = "none"
+	if a, err := rd.number(v, attrs, "alpha", 1); err != nil {
+		return nil, nil, err
+	} else if a < 1 {
+		r.Attrs()["opacity"] = roundedNumber(a)
+	}
+	if name := attrs["name"]; name != "" {
+		r.Attrs()["id"] = name
+	}
+	rd.unknownAttrs(v, attrs, "width", "height", "viewportWidth", "viewportHeight", "alpha", "name")
+
+	if err := rd.children(r, v); err != nil {
+		return nil, nil, err
+	}
+	return r, rd.unsupported, nil
+}
+
+// vdReader converts VectorDrawable elements and collects descriptions of
+// what couldn't be converted.
+type vdReader struct {
+	root        *Root
+	defs        Node
+	clips       int
+	unsupported []string
+}
+
+func (rd *vdReader) report(format string, args ...interface{}) {
+	rd.unsupported = append(rd.unsupported, fmt.Sprintf(format, args...))
+}
+
+// attrs returns the android attributes of e by local name.  Attributes in
+// other namespaces, such as tools, only matter to development tools and are
+// ignored.
+func (rd *vdReader) attrs(e vdElement) map[string]string {
+	r := map[string]string{}
+	for _, a := range e.Attrs {
+		if a.Name.Space == AndroidNs {
+			r[a.Name.Local] = strings.TrimSpace(a.Value)
+		}
+	}
+	return r
+}
+
+// unknownAttrs reports the attributes of e that aren't in known.
+func (rd *vdReader) unknownAttrs(e vdElement, attrs map[string]string, known ...string) {
+	for _, a := range e.Attrs {
+		if a.Name.Space != AndroidNs {
+			continue
+		}
+		found := false
+		for _, k := range known {
+			found = found || a.Name.Local == k
+		}
+		if !found {
+			rd.report("android:%s on <%s> not supported", a.Name.Local, e.XMLName.Local)
+		}
+	}
+}
+
+// number parses a numeric attribute, returning def if it isn't set.
+func (rd *vdReader) number(e vdElement, attrs map[string]string, k string, def float64) (float64, error) {
+	v, ok := attrs[k]
+	if !ok {
+		return def, nil
+	}
+	f, err := strconv.ParseFloat(v, 64)
+	if err != nil {
+		return 0, errors.Errorf("invalid android:%s on <%s>: %s", k, e.XMLName.Local, v)
+	}
+	return f, nil
+}
+
+// vdDimension converts an Android dimension to an SVG length.  Density
+// independent pixels are treated as CSS pixels.
+func vdDimension(v string) (string, error) {
+	v = strings.TrimSpace(v)
+	i := strings.IndexFunc(v, func(c rune) bool { return (c < '0' || c > '9') && c != '.' && c != '-' && c != '+' })
+	if i < 0 {
+		i = len(v)
+	}
+	f, err := strconv.ParseFloat(v[:i], 64)
+	if err != nil || f <= 0 {
+		return "", errors.Errorf("invalid VectorDrawable size: %s", v)
+	}
+	switch unit := v[i:]; unit {
+	case "dp", "dip", "px", "sp", "":
+		return roundedNumber(f), nil
+	case "in", "mm", "pt":
+		return roundedNumber(f) + unit, nil
+	}
+	return "", errors.Errorf("invalid VectorDrawable size: %s", v)
+}
+
+// children converts the children of e and adds them to parent.  A
+// <clip-path> applies to the elements after it, so they are moved into a
+// clipped group.
+func (rd *vdReader) children(parent Node, e vdElement) error {
+	for _, c := range e.Children {
+		switch c.XMLName.Local {
+		case "group":
+			g, err := rd.group(c)
+			if err != nil {
+				return err
+			}
+			parent.AddChild(g)
+		case "path":
+			p, err := rd.path(c)
+			if err != nil {
+				return err
+			}
+			parent.AddChild(p)
+		case "clip-path":
+			id, err := rd.clipPath(c)
+			if err != nil {
+				return err
+			}
+			g := NewGroup()
+			g.Attrs()["clip-path"] = "url(#" + id + ")"
+			parent.AddChild(g)
+			parent = g
+		default:
+			rd.report("<%s> not supported", c.XMLName.Local)
+		}
+	}
+	return nil
+}
+
+func (rd *vdReader) group(e vdElement) (Node, error) {
+	attrs := rd.attrs(e)
+	rd.unknownAttrs(e, attrs, "name", "rotation", "pivotX", "pivotY", "scaleX", "scaleY", "translateX", "translateY")
+	g := NewGroup()
+	if name := attrs["name"]; name != "" {
+		g.Attrs()["id"] = name
+	}
+
+	nums := map[string]float64{}
+	for _, k := range []string{"rotation", "pivotX", "pivotY", "scaleX", "scaleY", "translateX", "translateY"} {
+		def := 0.0
+		if strings.HasPrefix(k, "scale") {
+			def = 1
+		}
+		f, err := rd.number(e, attrs, k, def)
+		if err != nil {
+			return nil, err
+		}
+		nums[k] = f
+	}
+	// The group scales, then rotates, then translates, all about the pivot.
+	px, py := nums["pivotX"], nums["pivotY"]
+	var parts []string
+	if tx, ty := nums["translateX"]+px, nums["translateY"]+py; tx != 0 || ty != 0 {
+		parts = append(parts, "translate("+roundedNumber(tx)+" "+roundedNumber(ty)+")")
+	}
+	if r := nums["rotation"]; r != 0 {
+		parts = append(parts, "rotate("+roundedNumber(r)+")")
+	}
+	if sx, sy := nums["scaleX"], nums["scaleY"]; sx != 1 || sy != 1 {
+		parts = append(parts, "scale("+roundedNumber(sx)+" "+roundedNumber(sy)+")")
+	}
+	if px != 0 || py != 0 {
+		parts = append(parts, "translate("+roundedNumber(-px)+" "+roundedNumber(-py)+")")
+	}
+	if len(parts) > 0 {
+		g.Attrs()["transform"] = strings.Join(parts, " ")
+	}
+
+	return g, rd.children(g, e)
+}
+
+// clipPath adds a clipPath for e to the definitions and returns its id.
+func (rd *vdReader) clipPath(e vdElement) (string, error) {
+	attrs := rd.attrs(e)
+	rd.unknownAttrs(e, attrs, "name", "pathData")
+	sps, err := ParsePathString(attrs["pathData"])
+	if err != nil {
+		return "", errors.Wrap(err, "invalid android:pathData on <clip-path>")
+	}
+
+	if rd.defs == nil {
+		rd.defs = NewDefs()
+		*rd.root.Children() = append([]Node{rd.defs}, *rd.root.Children()...)
+	}
+	rd.clips++
+	id := fmt.Sprintf("clip%d", rd.clips)
+	if name := attrs["name"]; name != "" {
+		id = name
+	}
+	cp := createUnknown()
+	cp.name = "clipPath"
+	cp.Attrs()["id"] = id
+	p := NewPath()
+	p.SubPaths = sps
+	cp.AddChild(p)
+	rd.defs.AddChild(cp)
+	return id, nil
+}
+
+// vdPathDefaults are the path attributes that are only reported when they
+// are set to something other than their defaults.
+var vdPathDefaults = map[string]string{
+	"trimPathStart":  "0",
+	"trimPathEnd":    "1",
+	"trimPathOffset": "0",
+}
+
+func (rd *vdReader) path(e vdElement) (Node, error) {
+	attrs := rd.attrs(e)
+	for k, def := range vdPathDefaults {
+		if v, ok := attrs[k]; ok {
+			if f, err := strconv.ParseFloat(v, 64); err != nil || roundedNumber(f) != def {
+				rd.report("android:%s on <path> not supported", k)
+			}
+		}
+	}
+	known := []string{"name", "pathData", "fillColor", "fillAlpha", "fillType", "strokeColor", "strokeWidth",
+		"strokeAlpha", "strokeLineCap", "strokeLineJoin", "strokeMiterLimit"}
+	for k := range vdPathDefaults {
+		known = append(known, k)
+	}
+	rd.unknownAttrs(e, attrs, known...)
+	for _, c := range e.Children {
+		// Gradients are given in <aapt:attr> children.
+		rd.report("<%s> in <path> not supported", c.XMLName.Local)
+	}
+
+	p := NewPath()
+	sps, err := ParsePathString(attrs["pathData"])
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid android:pathData on <path>")
+	}
+	p.SubPaths = sps
+	if name := attrs["name"]; name != "" {
+		p.Attrs()["id"] = name
+	}
+
+	// paint sets the color and opacity of a fill or stroke.
+	paint := func(k, colorAttr, alphaAttr string) (bool, error) {
+		c, ok := attrs[colorAttr]
+		if !ok {
+			p.Attrs()[k] = "none"
+			return false, nil
+		}
+		color, alpha, ok := vdColor(c)
+		if !ok {
+			rd.report("android:%s %s on <path> not supported", colorAttr, c)
+			p.Attrs()[k] = "none"
+			return false, nil
+		}
+		a, err := rd.number(e, attrs, alphaAttr, 1)
+		if err != nil {
+			return false, err
+		}
+		p.Attrs()[k] = color
+		if a *= alpha; a < 1 {
+			p.Attrs()[k+"-opacity"] = roundedNumber(a)
+		}
+		return true, nil
+	}
+	if _, err := paint("fill", "fillColor", "fillAlpha"); err != nil {
+		return nil, err
+	}
+	if attrs["fillType"] == "evenOdd" {
+		p.Attrs()["fill-rule"] = "evenodd"
+	}
+	stroked, err := paint("stroke", "strokeColor", "strokeAlpha")
+	if err != nil {
+		return nil, err
+	}
+	if stroked {
+		w, err := rd.number(e, attrs, "strokeWidth", 0)
+		if err != nil {
+			return nil, err
+		}
+		p.Attrs()["stroke-width"] = roundedNumber(w)
+		if c := attrs["strokeLineCap"]; c == "round" || c == "square" {
+			p.Attrs()["stroke-linecap"] = c
+		}
+		if j := attrs["strokeLineJoin"]; j == "round" || j == "bevel" {
+			p.Attrs()["stroke-linejoin"] = j
+		}
+		if m, ok := attrs["strokeMiterLimit"]; ok {
+			p.Attrs()["stroke-miterlimit"] = m
+		}
+	}
+	return p, nil
+}
+
+// vdColor parses an Android color in one of the forms #RGB, #ARGB, #RRGGBB or
+// #AARRGGBB, returning it as an SVG color and its alpha.  Color resources
+// and theme attributes can't be resolved and return false.
+func vdColor(v string) (string, float64, bool) {
+	if !strings.HasPrefix(v, "#") {
+		return "", 0, false
+	}
+	hex := v[1:]
+	if len(hex) == 3 || len(hex) == 4 {
+		var b strings.Builder
+		for _, c := range hex {
+			b.WriteRune(c)
+			b.WriteRune(c)
+		}
+		hex = b.String()
+	}
+	if len(hex) != 6 && len(hex) != 8 {
+		return "", 0, false
+	}
+	n, err := strconv.ParseUint(hex, 16, 32)
+	if err != nil {
+		return "", 0, false
+	}
+	alpha := 1.0
+	if len(hex) == 8 {
+		alpha = float64(n>>24) / 255
+	}
+	return fmt.Sprintf("#%06x", n&0xffffff), alpha, true
+}
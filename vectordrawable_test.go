@@ -0,0 +1,229 @@
+// Copyright 2018 Joe Beda
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package svgdata
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMarshalVectorDrawable(t *testing.T) {
+	assert := assert.New(t)
+
+	r := mustUnmarshal(t, `<svg xmlns="http://www.w3.org/2000/svg" width="48" height="48" viewBox="-12 -12 24 24">
+  <defs><clipPath id="half"><rect x="-12" y="-12" width="12" height="24"/></clipPath></defs>
+  <g id="icon" transform="rotate(90) scale(2 -1)" opacity="0.5">
+    <path id="p" d="M-5 -5H5V5H-5Z" fill="#f00" stroke="blue" stroke-width="2" stroke-linecap="round" fill-rule="evenodd"/>
+  </g>
+  <path d="M0 0H4" fill="none" stroke="black" stroke-dasharray="1" clip-path="url(#half)"/>
+  <path d="M0 0H4V4Z" transform="skewX(45)" fill="url(#grad)" stroke="#00ff00"/>
+  <text x="1" y="20">Hi</text>
+</svg>`)
+	data, unsupported, err := MarshalVectorDrawable(r)
+	if !assert.NoError(err) {
+		return
+	}
+	assert.Equal(`<vector xmlns:android="http://schemas.android.com/apk/res/android"
+        android:width="48dp"
+        android:height="48dp"
+        android:viewportWidth="24"
+        android:viewportHeight="24">
+    <group
+        android:translateX="12"
+        android:translateY="12">
+        <group
+            android:name="icon"
+            android:rotation="90"
+            android:scaleX="2"
+            android:scaleY="-1">
+            <path
+                android:name="p"
+                android:pathData="M-5 -5H5V5H-5Z"
+                android:fillColor="#FF0000"
+                android:fillAlpha="0.5"
+                android:fillType="evenOdd"
+                android:strokeColor="#0000FF"
+                android:strokeWidth="2"
+                android:strokeAlpha="0.5"
+                android:strokeLineCap="round"/>
+        </group>
+        <group>
+            <clip-path
+                android:pathData="M-12 -12L0 -12L0 12L-12 12Z"/>
+            <path
+                android:pathData="M0 0L1 0M2 0L3 0"
+                android:strokeColor="#000000"
+                android:strokeWidth="1"/>
+        </group>
+        <path
+            android:pathData="M0 0L4 0L8 4Z"
+            android:strokeColor="#00FF00"
+            android:strokeWidth="1"/>
+    </group>
+</vector>
+`, string(data))
+	assert.Equal([]string{
+		"transform on <path> with skew applied to path data",
+		"fill url(#grad) on <path> not supported",
+		"<text> element not supported",
+	}, unsupported)
+}
+
+func TestMarshalVectorDrawableBake(t *testing.T) {
+	assert := assert.New(t)
+
+	// Everything under a skewed group is baked, including nested transforms
+	// and stroke widths.
+	r := mustUnmarshal(t, `<svg xmlns="http://www.w3.org/2000/svg" width="10" height="10">
+  <g transform="matrix(1 0 1 1 0 0)" opacity="0.5">
+    <path d="M0 0H1" transform="scale(2)" stroke="red" stroke-width="3"/>
+    <path d="M0 2H1" fill="none"/>
+  </g>
+</svg>`)
+	data, unsupported, err := MarshalVectorDrawable(r)
+	if !assert.NoError(err) {
+		return
+	}
+	assert.Contains(string(data), `android:pathData="M0 0L2 0"`)
+	assert.Contains(string(data), `android:strokeWidth="6"`)
+	assert.Contains(string(data), `android:fillAlpha="0.5"`)
+	assert.Equal([]string{
+		"opacity on <g> applied to each path",
+		"transform on <g> with skew applied to path data",
+	}, unsupported)
+}
+
+func TestMarshalVectorDrawableGroupOpacity(t *testing.T) {
+	assert := assert.New(t)
+
+	// Opacity is exact for a single path however deeply it is nested, and
+	// reported when it is spread over several.
+	r := mustUnmarshal(t, `<svg xmlns="http://www.w3.org/2000/svg" width="10" height="10">
+  <g id="one" opacity="0.5">
+    <g><path d="M0 0H1" stroke="red"/></g>
+  </g>
+  <a opacity="0.5">
+    <g>
+      <path d="M0 0H1" stroke="red"/>
+      <path d="M0 1H1" stroke="red"/>
+      <path d="M0 2H1" stroke="red" display="none"/>
+    </g>
+  </a>
+</svg>`)
+	_, unsupported, err := MarshalVectorDrawable(r)
+	assert.NoError(err)
+	assert.Equal([]string{"opacity on <a> applied to each path"}, unsupported)
+}
+
+func TestUnmarshalVectorDrawable(t *testing.T) {
+	assert := assert.New(t)
+
+	r, unsupported, err := UnmarshalVectorDrawable([]byte(`<vector xmlns:android="http://schemas.android.com/apk/res/android"
+    xmlns:aapt="http://schemas.android.com/aapt"
+    xmlns:tools="http://schemas.android.com/tools"
+    android:width="24dp" android:height="12dp"
+    android:viewportWidth="48" android:viewportHeight="24"
+    android:alpha="0.8" android:tint="?attr/colorControlNormal"
+    tools:ignore="VectorRaster">
+  <group android:name="g" android:pivotX="12" android:pivotY="12" android:rotation="45" android:scaleX="2">
+    <path android:name="a" android:pathData="M0 0h10v10z" android:fillColor="#80FF0000"
+        android:fillAlpha="0.5" android:fillType="evenOdd" android:trimPathStart="0"/>
+  </group>
+  <clip-path android:pathData="M0 0H24V24H0Z"/>
+  <path android:pathData="M1 1L5 5" android:strokeColor="#00f" android:strokeWidth="2"
+      android:strokeLineCap="round" android:strokeLineJoin="miter" android:trimPathEnd="0.5"/>
+  <path android:pathData="M2 2L6 6" android:fillColor="@color/accent">
+    <aapt:attr name="android:strokeColor"><gradient android:type="linear"/></aapt:attr>
+  </path>
+  <text/>
+</vector>`))
+	if !assert.NoError(err) {
+		return
+	}
+	assert.Equal([]string{
+		"android:tint on <vector> not supported",
+		"android:trimPathEnd on <path> not supported",
+		"<attr> in <path> not supported",
+		"android:fillColor @color/accent on <path> not supported",
+		"<text> not supported",
+	}, unsupported)
+
+	assert.Equal("24", r.Attrs()["width"])
+	assert.Equal("12", r.Attrs()["height"])
+	assert.Equal("0 0 48 24", r.Attrs()["viewBox"])
+	assert.Equal("none", r.Attrs()["preserveAspectRatio"])
+	assert.Equal("0.8", r.Attrs()["opacity"])
+
+	children := *r.Children()
+	if !assert.Len(children, 3) {
+		return
+	}
+	assert.Equal("defs", children[0].Name())
+	clip := (*children[0].Children())[0]
+	assert.Equal(AttrMap{"id": "clip1"}, clip.Attrs())
+
+	g := children[1]
+	assert.Equal(AttrMap{"id": "g", "transform": "translate(12 12) rotate(45) scale(2 1) translate(-12 -12)"}, g.Attrs())
+	a := (*g.Children())[0].(*Path)
+	assert.Equal("M0 0h10v10z", SavePathString(a.SubPaths))
+	assert.Equal(AttrMap{"id": "a", "fill": "#ff0000", "fill-opacity": "0.250980392", "fill-rule": "evenodd", "stroke": "none"}, a.Attrs())
+
+	clipped := children[2]
+	assert.Equal(AttrMap{"clip-path": "url(#clip1)"}, clipped.Attrs())
+	if assert.Len(*clipped.Children(), 2) {
+		assert.Equal(AttrMap{"fill": "none", "stroke": "#0000ff", "stroke-width": "2", "stroke-linecap": "round"},
+			(*clipped.Children())[0].Attrs())
+		assert.Equal(AttrMap{"fill": "none", "stroke": "none"}, (*clipped.Children())[1].Attrs())
+	}
+
+	_, _, err = UnmarshalVectorDrawable([]byte(`<svg/>`))
+	assert.Error(err)
+	_, _, err = UnmarshalVectorDrawable([]byte(`<vector xmlns:android="http://schemas.android.com/apk/res/android" android:width="24dp" android:height="24dp"/>`))
+	assert.Error(err)
+}
+
+func TestVectorDrawableRoundTrip(t *testing.T) {
+	assert := assert.New(t)
+
+	r := mustUnmarshal(t, `<svg xmlns="http://www.w3.org/2000/svg" width="24" height="24">
+  <g transform="translate(4 2) rotate(30) scale(1.5)">
+    <circle cx="5" cy="5" r="3" fill="#123456" fill-opacity="0.5"/>
+  </g>
+</svg>`)
+	data, unsupported, err := MarshalVectorDrawable(r)
+	if !assert.NoError(err) || !assert.Empty(unsupported) {
+		return
+	}
+	r2, unsupported, err := UnmarshalVectorDrawable(data)
+	if !assert.NoError(err) || !assert.Empty(unsupported) {
+		return
+	}
+	g := (*r2.Children())[0]
+	tr, err := NodeTransform(g)
+	if assert.NoError(err) {
+		expected, _ := ParseTransform("translate(4 2) rotate(30) scale(1.5)")
+		assert.InDelta(expected.A, tr.A, 1e-9)
+		assert.InDelta(expected.B, tr.B, 1e-9)
+		assert.InDelta(expected.C, tr.C, 1e-9)
+		assert.InDelta(expected.D, tr.D, 1e-9)
+		assert.InDelta(expected.E, tr.E, 1e-9)
+		assert.InDelta(expected.F, tr.F, 1e-9)
+	}
+	p := (*g.Children())[0].(*Path)
+	assert.InDelta(9*3.14159, subPathsArea(p.SubPaths), 0.05)
+	assert.Equal("#123456", p.Attrs()["fill"])
+	assert.Equal("0.5", p.Attrs()["fill-opacity"])
+}
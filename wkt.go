@@ -0,0 +1,247 @@
+// Copyright 2018 Joe Beda
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package svgdata
+
+import (
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/jbeda/geom"
+	"github.com/pkg/errors"
+)
+
+// MarshalWKT converts a shape to Well-Known Text.  Curves are flattened and
+// the transform of s is applied.  Closed shapes become POLYGON or
+// MULTIPOLYGON, with subpaths inside others becoming holes, and open shapes
+// become LINESTRING or MULTILINESTRING.
+func MarshalWKT(s Shape, opts GeoOptions) (string, error) {
+	t, err := NodeTransform(s)
+	if err != nil {
+		return "", err
+	}
+	g, err := shapeGeometry(s, opts.transform().Multiply(t), opts.tolerance())
+	if err != nil {
+		return "", err
+	}
+
+	var b strings.Builder
+	b.WriteString(strings.ToUpper(g.kind))
+	b.WriteByte(' ')
+	switch g.kind {
+	case geoLineString:
+		wktCoords(&b, g.lines[0])
+	case geoMultiLineString:
+		wktList(&b, g.lines)
+	case geoPolygon:
+		wktList(&b, g.polygons[0])
+	case geoMultiPolygon:
+		b.WriteByte('(')
+		for i, p := range g.polygons {
+			if i > 0 {
+				b.WriteString(", ")
+			}
+			wktList(&b, p)
+		}
+		b.WriteByte(')')
+	}
+	return b.String(), nil
+}
+
+func wktCoords(b *strings.Builder, pts []geom.Coord) {
+	b.WriteByte('(')
+	for i, p := range pts {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		b.WriteString(strconv.FormatFloat(p.X, 'f', -1, 64))
+		b.WriteByte(' ')
+		b.WriteString(strconv.FormatFloat(p.Y, 'f', -1, 64))
+	}
+	b.WriteByte(')')
+}
+
+func wktList(b *strings.Builder, lists [][]geom.Coord) {
+	b.WriteByte('(')
+	for i, pts := range lists {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		wktCoords(b, pts)
+	}
+	b.WriteByte(')')
+}
+
+// wktKinds maps WKT geometry tags to geoGeometry kinds.
+var wktKinds = map[string]string{
+	"LINESTRING":      geoLineString,
+	"MULTILINESTRING": geoMultiLineString,
+	"POLYGON":         geoPolygon,
+	"MULTIPOLYGON":    geoMultiPolygon,
+}
+
+// UnmarshalWKT converts Well-Known Text to a shape in the same way as
+// UnmarshalGeoJSON.  Z, M and ZM coordinates are accepted but only x and y
+// are used.
+func UnmarshalWKT(s string, opts GeoOptions) (Node, error) {
+	t, err := opts.inverse()
+	if err != nil {
+		return nil, err
+	}
+	p := &wktParser{s: s}
+	tag := strings.ToUpper(p.word())
+	kind, ok := wktKinds[tag]
+	if !ok {
+		return nil, errors.Errorf("unsupported WKT type: %s", tag)
+	}
+	if dim := strings.ToUpper(p.word()); dim != "" && dim != "Z" && dim != "M" && dim != "ZM" {
+		if dim != "EMPTY" {
+			return nil, errors.Errorf("invalid WKT: unexpected %s", dim)
+		}
+		p.empty = true
+	}
+
+	g := geoGeometry{kind: kind}
+	switch kind {
+	case geoLineString:
+		l, err := p.coords()
+		if err != nil {
+			return nil, err
+		}
+		g.lines = [][]geom.Coord{l}
+	case geoMultiLineString, geoPolygon:
+		ls, err := p.lists()
+		if err != nil {
+			return nil, err
+		}
+		if kind == geoPolygon {
+			g.polygons = [][][]geom.Coord{ls}
+		} else {
+			g.lines = ls
+		}
+	case geoMultiPolygon:
+		err := p.list(func() error {
+			ls, err := p.lists()
+			g.polygons = append(g.polygons, ls)
+			return err
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	if p.skipSpace(); p.pos < len(p.s) {
+		return nil, errors.Errorf("invalid WKT: unexpected %q", p.s[p.pos:])
+	}
+	return g.node(t), nil
+}
+
+// wktParser reads Well-Known Text.
+type wktParser struct {
+	s     string
+	pos   int
+	empty bool // The geometry was EMPTY
+}
+
+func (p *wktParser) skipSpace() {
+	for p.pos < len(p.s) && unicode.IsSpace(rune(p.s[p.pos])) {
+		p.pos++
+	}
+}
+
+// word reads a keyword or returns "" if there isn't one.
+func (p *wktParser) word() string {
+	p.skipSpace()
+	start := p.pos
+	for p.pos < len(p.s) && unicode.IsLetter(rune(p.s[p.pos])) {
+		p.pos++
+	}
+	return p.s[start:p.pos]
+}
+
+// list reads a parenthesized, comma separated list calling item for each
+// entry.  EMPTY is read as a list with no entries.
+func (p *wktParser) list(item func() error) error {
+	if p.empty {
+		p.empty = false
+		return nil
+	}
+	p.skipSpace()
+	if p.pos < len(p.s) && p.s[p.pos] != '(' {
+		if w := p.word(); strings.ToUpper(w) == "EMPTY" {
+			return nil
+		}
+	}
+	if p.pos >= len(p.s) || p.s[p.pos] != '(' {
+		return errors.Errorf("invalid WKT: expected ( at %d", p.pos)
+	}
+	p.pos++
+	for {
+		if err := item(); err != nil {
+			return err
+		}
+		p.skipSpace()
+		if p.pos >= len(p.s) {
+			return errors.New("invalid WKT: unexpected end")
+		}
+		c := p.s[p.pos]
+		p.pos++
+		if c == ')' {
+			return nil
+		}
+		if c != ',' {
+			return errors.Errorf("invalid WKT: unexpected %q at %d", c, p.pos-1)
+		}
+	}
+}
+
+// coords reads a list of coordinates.
+func (p *wktParser) coords() ([]geom.Coord, error) {
+	var r []geom.Coord
+	err := p.list(func() error {
+		var nums []float64
+		for {
+			p.skipSpace()
+			start := p.pos
+			for p.pos < len(p.s) && strings.IndexByte("+-.0123456789eE", p.s[p.pos]) >= 0 {
+				p.pos++
+			}
+			if start == p.pos {
+				break
+			}
+			f, err := strconv.ParseFloat(p.s[start:p.pos], 64)
+			if err != nil {
+				return errors.Wrap(err, "invalid WKT coordinate")
+			}
+			nums = append(nums, f)
+		}
+		if len(nums) < 2 {
+			return errors.Errorf("invalid WKT: expected coordinate at %d", p.pos)
+		}
+		r = append(r, geom.Coord{X: nums[0], Y: nums[1]})
+		return nil
+	})
+	return r, err
+}
+
+// lists reads a list of coordinate lists.
+func (p *wktParser) lists() ([][]geom.Coord, error) {
+	var r [][]geom.Coord
+	err := p.list(func() error {
+		l, err := p.coords()
+		r = append(r, l)
+		return err
+	})
+	return r, err
+}
@@ -0,0 +1,99 @@
+// Copyright 2018 Joe Beda
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package svgdata
+
+import (
+	"testing"
+
+	"github.com/jbeda/geom"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMarshalWKT(t *testing.T) {
+	assert := assert.New(t)
+
+	r := mustUnmarshal(t, `<svg xmlns="http://www.w3.org/2000/svg">
+  <rect width="10" height="5"/>
+  <polyline points="0,0 1.5,2" transform="translate(1 1)"/>
+  <path d="M0 0H10V10H0Z M2 2H8V8H2Z M20 0H30V10H20Z"/>
+  <path d="M0 0L1 1M2 2L3 3"/>
+</svg>`)
+	tests := []string{
+		"POLYGON ((0 0, 10 0, 10 5, 0 5, 0 0))",
+		"LINESTRING (1 1, 2.5 3)",
+		"MULTIPOLYGON (((0 0, 10 0, 10 10, 0 10, 0 0), (2 2, 2 8, 8 8, 8 2, 2 2)), ((20 0, 30 0, 30 10, 20 10, 20 0)))",
+		"MULTILINESTRING ((0 0, 1 1), (2 2, 3 3))",
+	}
+	for i, expected := range tests {
+		s, err := MarshalWKT((*r.Children())[i].(Shape), GeoOptions{})
+		if assert.NoError(err) {
+			assert.Equal(expected, s)
+		}
+	}
+
+	s, err := MarshalWKT((*r.Children())[0].(Shape), GeoOptions{Transform: NewScale(2, -2)})
+	if assert.NoError(err) {
+		assert.Equal("POLYGON ((0 0, 0 -10, 20 -10, 20 0, 0 0))", s)
+	}
+}
+
+func TestUnmarshalWKT(t *testing.T) {
+	assert := assert.New(t)
+
+	n, err := UnmarshalWKT("POLYGON ((0 0, 10 0, 10 10, 0 10, 0 0), (2 2, 8 2, 8 8, 2 8, 2 2))", GeoOptions{})
+	if assert.NoError(err) {
+		p := n.(*Path)
+		assert.Len(p.SubPaths, 2)
+		assert.Equal("evenodd", p.Attrs()["fill-rule"])
+		assert.True(ShapeContains(p, geom.Coord{X: 1, Y: 1}))
+		assert.False(ShapeContains(p, geom.Coord{X: 5, Y: 5}))
+	}
+
+	n, err = UnmarshalWKT("  LineString Z(0 0 1,5 -5 2)", GeoOptions{Transform: NewScale(1, -1)})
+	if assert.NoError(err) {
+		assert.Equal([]geom.Coord{{X: 0, Y: 0}, {X: 5, Y: 5}}, n.(*Polyshape).Points)
+	}
+
+	n, err = UnmarshalWKT("multipolygon (((0 0, 1 0, 1 1, 0 0)), ((5 5, 6 5, 6 6, 5 5)))", GeoOptions{})
+	if assert.NoError(err) {
+		assert.Len(n.(*Path).SubPaths, 2)
+		assert.InDelta(1, subPathsArea(n.(*Path).SubPaths), 1e-9)
+	}
+
+	n, err = UnmarshalWKT("MULTILINESTRING ((0 0, 1 1), EMPTY)", GeoOptions{})
+	if assert.NoError(err) {
+		assert.Len(n.(*Path).SubPaths, 1)
+	}
+
+	n, err = UnmarshalWKT("POLYGON EMPTY", GeoOptions{})
+	if assert.NoError(err) {
+		assert.Len(n.(*Path).SubPaths, 0)
+	}
+
+	for _, s := range []string{
+		"POINT (1 2)",
+		"LINESTRING (0 0, 1)",
+		"LINESTRING (0 0, 1 1",
+		"LINESTRING (0 0, 1 1) x",
+		"LINESTRING FOO (0 0, 1 1)",
+		"POLYGON (0 0, 1 1)",
+	} {
+		_, err := UnmarshalWKT(s, GeoOptions{})
+		assert.Error(err, s)
+	}
+
+	_, err = UnmarshalWKT("LINESTRING (0 0, 1 1)", GeoOptions{Transform: NewScale(0, 1)})
+	assert.Error(err)
+}
@@ -0,0 +1,55 @@
+// Copyright 2018 Joe Beda
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package svgdata
+
+import (
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// XAMLPathData formats subpaths as XAML path markup, as used in the Data
+// attribute of a Path or in a PathGeometry's Figures.  The markup uses the
+// same commands as SVG path data, with the fill rule given by a leading F0
+// for evenodd or F1 for nonzero.
+func XAMLPathData(sps []SubPath, fillRule string) string {
+	prefix := "F1 "
+	if fillRule == "evenodd" {
+		prefix = "F0 "
+	}
+	return prefix + SavePathString(sps)
+}
+
+// ParseXAMLPathData parses XAML path markup, returning the subpaths and the
+// fill rule.  XAML uses evenodd when there is no F0 or F1 prefix.
+func ParseXAMLPathData(s string) ([]SubPath, string, error) {
+	s = strings.TrimSpace(s)
+	fillRule := "evenodd"
+	if len(s) >= 2 && (s[0] == 'F' || s[0] == 'f') {
+		switch s[1] {
+		case '0':
+		case '1':
+			fillRule = "nonzero"
+		default:
+			return nil, "", errors.Errorf("invalid XAML fill rule: %s", s[:2])
+		}
+		s = s[2:]
+	}
+	sps, err := ParsePathString(s)
+	if err != nil {
+		return nil, "", err
+	}
+	return sps, fillRule, nil
+}
@@ -0,0 +1,49 @@
+// Copyright 2018 Joe Beda
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package svgdata
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestXAMLPathData(t *testing.T) {
+	assert := assert.New(t)
+
+	sps := mustParsePath(t, "M0 0L10 0A5 5 0 0 1 10 10Z")
+	assert.Equal("F1 M0 0L10 0A5 5 0 0 1 10 10Z", XAMLPathData(sps, "nonzero"))
+	assert.Equal("F0 M0 0L10 0A5 5 0 0 1 10 10Z", XAMLPathData(sps, "evenodd"))
+
+	tests := []struct {
+		data, fillRule, path string
+	}{
+		{"F1 M 0,0 L 10,0 10,10 Z", "nonzero", "M0 0L10 0L10 10Z"},
+		{" f0M0 0h5", "evenodd", "M0 0h5"},
+		{"M0 0 C1 1 2 2 3 3", "evenodd", "M0 0C1 1 2 2 3 3"},
+	}
+	for _, test := range tests {
+		sps, fillRule, err := ParseXAMLPathData(test.data)
+		if assert.NoError(err, test.data) {
+			assert.Equal(test.fillRule, fillRule, test.data)
+			assert.Equal(test.path, SavePathString(sps), test.data)
+		}
+	}
+
+	_, _, err := ParseXAMLPathData("F2 M0 0")
+	assert.Error(err)
+	_, _, err = ParseXAMLPathData("F1 M0 0 L")
+	assert.Error(err)
+}